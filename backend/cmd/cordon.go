@@ -0,0 +1,176 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// handleNodeCordon handles /cordon-node.
+func (c *HeadlampConfig) handleNodeCordon(w http.ResponseWriter, r *http.Request) {
+	c.handleNodeSchedulable(w, r, "handleNodeCordon", true)
+}
+
+// handleNodeUncordon handles /uncordon-node.
+func (c *HeadlampConfig) handleNodeUncordon(w http.ResponseWriter, r *http.Request) {
+	c.handleNodeSchedulable(w, r, "handleNodeUncordon", false)
+}
+
+// handleNodeSchedulable marks the node in the request as unschedulable or schedulable.
+func (c *HeadlampConfig) handleNodeSchedulable(
+	w http.ResponseWriter,
+	r *http.Request,
+	operation string,
+	unschedulable bool,
+) {
+	ctx := r.Context()
+	_, span := telemetry.CreateSpan(ctx, r, "node-management", operation)
+	c.TelemetryHandler.RecordRequestCount(ctx, r)
+	c.TelemetryHandler.RecordEvent(span, operation+" request started")
+
+	defer span.End()
+
+	var payload struct {
+		Cluster  string `json:"cluster"`
+		NodeName string `json:"nodeName"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		c.handleError(w, ctx, span, err, "decoding payload", http.StatusBadRequest)
+
+		return
+	}
+
+	if payload.NodeName == "" {
+		c.handleError(w, ctx, span, errors.New("nodeName not found"), "missing nodeName", http.StatusBadRequest)
+		return
+	}
+
+	if payload.Cluster == "" {
+		c.handleError(w, ctx, span, errors.New("clusterName not found"), "missing clusterName", http.StatusBadRequest)
+
+		return
+	}
+
+	clientset := c.requestClientset(w, r, span, payload.Cluster)
+	if clientset == nil {
+		return
+	}
+
+	if err := setNodeUnschedulable(ctx, clientset, payload.NodeName, unschedulable); err != nil {
+		c.handleError(w, ctx, span, err, "patching node", kubernetesErrorStatus(err))
+
+		return
+	}
+
+	c.TelemetryHandler.RecordEvent(span, "node schedulability updated",
+		attribute.String("nodeName", payload.NodeName),
+		attribute.Bool("unschedulable", unschedulable))
+
+	responsePayload := struct {
+		Message       string `json:"message"`
+		Cluster       string `json:"cluster"`
+		NodeName      string `json:"nodeName"`
+		Unschedulable bool   `json:"unschedulable"`
+	}{
+		Message:       "Node uncordoned successfully",
+		Cluster:       payload.Cluster,
+		NodeName:      payload.NodeName,
+		Unschedulable: unschedulable,
+	}
+
+	if unschedulable {
+		responsePayload.Message = "Node cordoned successfully"
+	}
+
+	if err := json.NewEncoder(w).Encode(responsePayload); err != nil {
+		c.handleError(w, ctx, span, err, "writing response", http.StatusInternalServerError)
+	}
+}
+
+// requestClientset returns a clientset for the cluster using the token of the
+// request, or writes an error response and returns nil.
+func (c *HeadlampConfig) requestClientset(
+	w http.ResponseWriter,
+	r *http.Request,
+	span trace.Span,
+	cluster string,
+) kubernetes.Interface {
+	ctx := r.Context()
+
+	ctxtProxy, err := c.KubeConfigStore.GetContext(cluster)
+	if err != nil {
+		c.handleError(w, ctx, span, err, "Cluster not found", http.StatusNotFound)
+
+		return nil
+	}
+
+	token := c.requestTokenForContext(r, cluster, ctxtProxy)
+
+	clientset, err := ctxtProxy.ClientSetWithToken(token)
+	if err != nil {
+		c.handleError(w, ctx, span, err, "getting client", http.StatusInternalServerError)
+
+		return nil
+	}
+
+	return clientset
+}
+
+// setNodeUnschedulable cordons or uncordons a node. It patches only
+// spec.unschedulable, so it does not conflict with concurrent node status
+// updates the way a read-modify-write Update would.
+func setNodeUnschedulable(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	nodeName string,
+	unschedulable bool,
+) error {
+	patch := fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable)
+
+	_, err := clientset.CoreV1().Nodes().Patch(ctx, nodeName, types.StrategicMergePatchType,
+		[]byte(patch), v1.PatchOptions{})
+
+	return err
+}
+
+// kubernetesErrorStatus maps an error from the Kubernetes API to the HTTP
+// status to respond with.
+func kubernetesErrorStatus(err error) int {
+	switch {
+	case apierrors.IsNotFound(err):
+		return http.StatusNotFound
+	case apierrors.IsForbidden(err):
+		return http.StatusForbidden
+	case apierrors.IsUnauthorized(err):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestSetNodeUnschedulable(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-node",
+		},
+	}
+
+	fakeClient := fake.NewClientset(node)
+	ctx := context.Background()
+
+	require.NoError(t, setNodeUnschedulable(ctx, fakeClient, "test-node", true))
+
+	got, err := fakeClient.CoreV1().Nodes().Get(ctx, "test-node", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, got.Spec.Unschedulable)
+
+	require.NoError(t, setNodeUnschedulable(ctx, fakeClient, "test-node", false))
+
+	got, err = fakeClient.CoreV1().Nodes().Get(ctx, "test-node", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.False(t, got.Spec.Unschedulable)
+
+	for _, action := range fakeClient.Actions() {
+		assert.NotEqual(t, "update", action.GetVerb(), "nodes should be patched, not updated")
+	}
+}
+
+// newCordonTestConfig returns a config with a single in-cluster context named
+// "test-cluster" pointing at the given API server.
+func newCordonTestConfig(t *testing.T, server string) *HeadlampConfig {
+	t.Helper()
+
+	kubeConfigStore := kubeconfig.NewContextStore()
+	err := kubeConfigStore.AddContext(&kubeconfig.Context{
+		Name:        "test-cluster",
+		KubeContext: &api.Context{Cluster: "test-cluster", AuthInfo: "test-cluster"},
+		Cluster: &api.Cluster{
+			Server:                server,
+			InsecureSkipTLSVerify: true,
+		},
+		AuthInfo: &api.AuthInfo{},
+		Source:   kubeconfig.InCluster,
+	})
+	require.NoError(t, err)
+
+	return &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			HeadlampCFG: &headlampconfig.HeadlampCFG{
+				UseInCluster:    true,
+				KubeConfigStore: kubeConfigStore,
+			},
+			Cache:            cache.New[interface{}](),
+			TelemetryConfig:  GetDefaultTestTelemetryConfig(),
+			TelemetryHandler: &telemetry.RequestHandler{},
+		},
+	}
+}
+
+func TestHandleNodeCordonAndUncordon(t *testing.T) { //nolint:funlen
+	const testToken = "test-cluster-token"
+
+	type apiRequest struct {
+		method        string
+		contentType   string
+		authorization string
+		body          string
+	}
+
+	requests := make(chan apiRequest, 1)
+	kubeAPI := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/nodes/node-a" {
+			http.NotFound(w, r)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		requests <- apiRequest{
+			method:        r.Method,
+			contentType:   r.Header.Get("Content-Type"),
+			authorization: r.Header.Get("Authorization"),
+			body:          string(body),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}})
+	}))
+	t.Cleanup(kubeAPI.Close)
+
+	cfg := newCordonTestConfig(t, kubeAPI.URL)
+
+	tests := []struct {
+		name          string
+		handler       http.HandlerFunc
+		unschedulable bool
+	}{
+		{name: "cordon", handler: cfg.handleNodeCordon, unschedulable: true},
+		{name: "uncordon", handler: cfg.handleNodeUncordon, unschedulable: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := makeJSONReq(http.MethodPost, "/"+tt.name+"-node", map[string]string{
+				"cluster":  "test-cluster",
+				"nodeName": "node-a",
+			})
+			require.NoError(t, err)
+			req.AddCookie(&http.Cookie{Name: "headlamp-auth-test-cluster.0", Value: testToken})
+
+			rr := httptest.NewRecorder()
+			tt.handler(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+			got := <-requests
+			assert.Equal(t, http.MethodPatch, got.method)
+			assert.Equal(t, "application/strategic-merge-patch+json", got.contentType)
+			assert.Equal(t, "Bearer "+testToken, got.authorization)
+
+			var patch struct {
+				Spec struct {
+					Unschedulable bool `json:"unschedulable"`
+				} `json:"spec"`
+			}
+
+			require.NoError(t, json.Unmarshal([]byte(got.body), &patch))
+			assert.Equal(t, tt.unschedulable, patch.Spec.Unschedulable)
+
+			var response struct {
+				NodeName      string `json:"nodeName"`
+				Unschedulable bool   `json:"unschedulable"`
+			}
+
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+			assert.Equal(t, "node-a", response.NodeName)
+			assert.Equal(t, tt.unschedulable, response.Unschedulable)
+		})
+	}
+}
+
+func TestHandleNodeCordonErrors(t *testing.T) {
+	kubeAPI := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(kubeAPI.Close)
+
+	cfg := newCordonTestConfig(t, kubeAPI.URL)
+
+	tests := []struct {
+		name    string
+		payload map[string]string
+		want    int
+	}{
+		{name: "missing nodeName", payload: map[string]string{"cluster": "test-cluster"}, want: http.StatusBadRequest},
+		{name: "missing cluster", payload: map[string]string{"nodeName": "node-a"}, want: http.StatusBadRequest},
+		{
+			name:    "unknown cluster",
+			payload: map[string]string{"cluster": "other", "nodeName": "node-a"},
+			want:    http.StatusNotFound,
+		},
+		{
+			name:    "unknown node",
+			payload: map[string]string{"cluster": "test-cluster", "nodeName": "node-a"},
+			want:    http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := makeJSONReq(http.MethodPost, "/cordon-node", tt.payload)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			cfg.handleNodeCordon(rr, req)
+			assert.Equal(t, tt.want, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,711 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	cfg "github.com/kubernetes-sigs/headlamp/backend/pkg/config"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	drainStatusInProgress = "in progress"
+	drainStatusSuccess    = "success"
+)
+
+// drainEvictionRetryInterval is how long to wait before retrying an eviction
+// the API server refused with 429, e.g. because a PodDisruptionBudget does not
+// allow the disruption yet.
+//
+//nolint:gochecknoglobals // allow test override
+var drainEvictionRetryInterval = 5 * time.Second
+
+// drainPodDeletionPollInterval is how often to check whether an evicted pod is gone.
+//
+//nolint:gochecknoglobals // allow test override
+var drainPodDeletionPollInterval = time.Second
+
+// drainBlockedPod is a pod whose eviction was still refused when the drain gave up.
+type drainBlockedPod struct {
+	Pod string `json:"pod"`
+	// PodDisruptionBudget is the name of the budget blocking the eviction, if one matches the pod.
+	PodDisruptionBudget string `json:"podDisruptionBudget,omitempty"`
+}
+
+// drainFailedPod is a pod that could not be evicted for a reason other than a disruption budget.
+type drainFailedPod struct {
+	Pod   string `json:"pod"`
+	Error string `json:"error"`
+}
+
+// nodeDrainResult summarizes a drain job for handleNodeDrainStatus.
+type nodeDrainResult struct {
+	// Status is "in progress", "success" or an "error: ..." message.
+	Status   string            `json:"status"`
+	Evicted  []string          `json:"evicted,omitempty"`
+	Blocked  []drainBlockedPod `json:"blocked,omitempty"`
+	Failed   []drainFailedPod  `json:"failed,omitempty"`
+	Warnings []string          `json:"warnings,omitempty"`
+}
+
+// nodeDrainOptions are the kubectl drain options accepted by /drain-node.
+type nodeDrainOptions struct {
+	// DryRun only reports the pods that would be evicted; the node is neither cordoned nor drained.
+	DryRun bool `json:"dryRun"`
+	// DeleteEmptyDirData allows evicting pods using emptyDir volumes, whose data is lost.
+	DeleteEmptyDirData bool `json:"deleteEmptyDirData"`
+	// Force allows evicting pods that are not managed by a controller.
+	Force bool `json:"force"`
+	// IgnoreDaemonSets skips DaemonSet-managed pods instead of refusing to drain. Defaults to true.
+	IgnoreDaemonSets *bool `json:"ignoreDaemonSets"`
+	// PodSelector is a label selector limiting the pods that are evicted.
+	PodSelector string `json:"podSelector"`
+	// GracePeriodSeconds overrides the pods' own termination grace period when set.
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds"`
+	// Timeout overrides the server's drain-node-timeout, as a duration such as "90s".
+	Timeout string `json:"timeout"`
+
+	timeout time.Duration
+}
+
+// Validate checks the options and parses the timeout.
+func (o *nodeDrainOptions) Validate() error {
+	if _, err := labels.Parse(o.PodSelector); err != nil {
+		return fmt.Errorf("invalid podSelector: %w", err)
+	}
+
+	if o.GracePeriodSeconds != nil && *o.GracePeriodSeconds < 0 {
+		return errors.New("gracePeriodSeconds cannot be negative")
+	}
+
+	if o.Timeout == "" {
+		return nil
+	}
+
+	timeout, err := time.ParseDuration(o.Timeout)
+	if err != nil {
+		return fmt.Errorf("invalid timeout: %w", err)
+	}
+
+	if timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+
+	o.timeout = timeout
+
+	return nil
+}
+
+func (o *nodeDrainOptions) ignoreDaemonSets() bool {
+	return o.IgnoreDaemonSets == nil || *o.IgnoreDaemonSets
+}
+
+// drainPodSelection is the outcome of applying the drain options to the pods on a node.
+type drainPodSelection struct {
+	// Evict are the pods the drain evicts.
+	Evict []corev1.Pod
+	// Warnings are pods that are skipped or evicted despite a potential problem.
+	Warnings []string
+	// Errors are pods that prevent the drain unless the matching option is set.
+	Errors []drainFailedPod
+}
+
+/*
+This function is used to handle the node drain request.
+*/
+func (c *HeadlampConfig) handleNodeDrain(w http.ResponseWriter, r *http.Request) { //nolint:funlen
+	ctx := r.Context()
+	_, span := telemetry.CreateSpan(ctx, r, "node-management", "handleNodeDrain")
+	c.TelemetryHandler.RecordRequestCount(ctx, r)
+	c.TelemetryHandler.RecordEvent(span, "node drain request started")
+
+	defer span.End()
+
+	var drainPayload struct {
+		Cluster  string `json:"cluster"`
+		NodeName string `json:"nodeName"`
+		nodeDrainOptions
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&drainPayload); err != nil {
+		c.handleError(w, ctx, span, err, "decoding payload", http.StatusBadRequest)
+
+		return
+	}
+
+	if err := drainPayload.nodeDrainOptions.Validate(); err != nil {
+		c.handleError(w, ctx, span, err, "invalid drain options", http.StatusBadRequest)
+
+		return
+	}
+
+	if drainPayload.NodeName == "" {
+		c.handleError(w, ctx, span, errors.New("nodeName not found"), "missing nodeName", http.StatusBadRequest)
+		return
+	}
+
+	if drainPayload.Cluster == "" {
+		c.handleError(w, ctx, span, errors.New("clusterName not found"), "missing clusterName", http.StatusBadRequest)
+
+		return
+	}
+
+	clientset := c.requestClientset(w, r, span, drainPayload.Cluster)
+	if clientset == nil {
+		return
+	}
+
+	if drainPayload.DryRun {
+		c.handleNodeDrainDryRun(w, r, span, clientset, drainPayload.Cluster, drainPayload.NodeName,
+			drainPayload.nodeDrainOptions)
+
+		return
+	}
+
+	serverCtx := c.ServerCtx
+	if serverCtx == nil {
+		serverCtx = context.Background()
+	}
+
+	job := c.drainNode(serverCtx, clientset, drainPayload.NodeName, drainPayload.Cluster, drainPayload.nodeDrainOptions)
+	if job == nil {
+		c.handleError(w, ctx, span, serverCtx.Err(), "server is shutting down", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	var responsePayload struct {
+		Message string `json:"message"`
+		Cluster string `json:"cluster"`
+		JobID   string `json:"jobId"`
+	}
+
+	responsePayload.Cluster = drainPayload.Cluster
+	responsePayload.Message = "Drain node request submitted successfully"
+	responsePayload.JobID = job.ID()
+
+	if err := json.NewEncoder(w).Encode(responsePayload); err != nil {
+		c.handleError(w, ctx, span, err, "writing response", http.StatusInternalServerError)
+	}
+}
+
+// handleNodeDrainDryRun responds with the pods a drain would evict, without changing anything.
+func (c *HeadlampConfig) handleNodeDrainDryRun(
+	w http.ResponseWriter,
+	r *http.Request,
+	span trace.Span,
+	clientset kubernetes.Interface,
+	cluster string,
+	nodeName string,
+	opts nodeDrainOptions,
+) {
+	ctx := r.Context()
+
+	selection, err := listDrainPods(ctx, clientset, nodeName, opts)
+	if err != nil {
+		c.handleError(w, ctx, span, err, "listing pods", http.StatusInternalServerError)
+
+		return
+	}
+
+	responsePayload := struct {
+		Message  string           `json:"message"`
+		Cluster  string           `json:"cluster"`
+		DryRun   bool             `json:"dryRun"`
+		Pods     []string         `json:"pods"`
+		Warnings []string         `json:"warnings,omitempty"`
+		Errors   []drainFailedPod `json:"errors,omitempty"`
+	}{
+		Message:  "Drain node dry run completed",
+		Cluster:  cluster,
+		DryRun:   true,
+		Pods:     make([]string, 0, len(selection.Evict)),
+		Warnings: selection.Warnings,
+		Errors:   selection.Errors,
+	}
+
+	for i := range selection.Evict {
+		responsePayload.Pods = append(responsePayload.Pods, selection.Evict[i].Namespace+"/"+selection.Evict[i].Name)
+	}
+
+	if err = json.NewEncoder(w).Encode(responsePayload); err != nil {
+		c.handleError(w, ctx, span, err, "writing response", http.StatusInternalServerError)
+	}
+}
+
+// drainTimeout returns how long a drain may wait for its pods to be evicted.
+func (c *HeadlampConfig) drainTimeout(opts nodeDrainOptions) time.Duration {
+	if opts.timeout > 0 {
+		return opts.timeout
+	}
+
+	if c.HeadlampCFG == nil || c.DrainNodeTimeout <= 0 {
+		return cfg.DefaultDrainNodeTimeout
+	}
+
+	return c.DrainNodeTimeout
+}
+
+// drainNode starts draining the node in the background and returns the drain
+// job tracking it, or nil if ctx is already done.
+func (c *HeadlampConfig) drainNode(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	nodeName string,
+	cluster string,
+	opts nodeDrainOptions,
+) *drainJob {
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	job := newDrainJob(cluster, nodeName)
+	cacheKey := uuid.NewSHA1(uuid.Nil, []byte(nodeName+"\x00"+cluster)).String()
+
+	// Keep the running job around for as long as the drain may take.
+	c.storeDrainJob(ctx, job, cacheKey, c.drainTimeout(opts)+DrainNodeCacheTTL*time.Second)
+
+	go c.runDrainJob(ctx, clientset, job, opts, cacheKey)
+
+	return job
+}
+
+// drainInterruptedMessage fails the drains still running when the server shuts
+// down, so that their watchers stop waiting for them.
+const drainInterruptedMessage = "error: the server shut down before the drain finished"
+
+// runDrainJob cordons the node, evicts its pods and records the outcome in the job.
+func (c *HeadlampConfig) runDrainJob(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	job *drainJob,
+	opts nodeDrainOptions,
+	cacheKey string,
+) {
+	status, _ := job.Status()
+	cacheItemTTL := DrainNodeCacheTTL * time.Second
+
+	finish := func(message string) {
+		job.finish(message)
+		// The job is kept for its watchers even if the server is shutting down.
+		c.storeDrainJob(context.WithoutCancel(ctx), job, cacheKey, cacheItemTTL)
+	}
+
+	// cordon the node first
+	if err := setNodeUnschedulable(ctx, clientset, status.NodeName, true); err != nil {
+		if ctx.Err() != nil {
+			finish(drainInterruptedMessage)
+			return
+		}
+
+		finish("error: " + err.Error())
+
+		return
+	}
+
+	selection, err := listDrainPods(ctx, clientset, status.NodeName, opts)
+	if err != nil {
+		if ctx.Err() != nil {
+			finish(drainInterruptedMessage)
+			return
+		}
+
+		finish("error: " + err.Error())
+
+		return
+	}
+
+	job.setSelection(selection)
+
+	// Like kubectl, refuse to evict anything if some pods need an option that was not given.
+	if len(selection.Errors) > 0 {
+		finish(fmt.Sprintf("error: cannot evict %d pod(s) without additional drain options", len(selection.Errors)))
+
+		return
+	}
+
+	evictCtx, cancel := context.WithTimeout(ctx, c.drainTimeout(opts))
+	defer cancel()
+
+	evictPods(evictCtx, clientset, selection.Evict, opts.GracePeriodSeconds, job)
+
+	// The server is shutting down, don't report a partial drain as such.
+	if ctx.Err() != nil {
+		finish(drainInterruptedMessage)
+		return
+	}
+
+	status, _ = job.Status()
+	result := status.Result()
+
+	notEvicted := len(result.Blocked) + len(result.Failed)
+	if notEvicted == 0 {
+		finish("")
+
+		return
+	}
+
+	details := make([]string, 0, notEvicted)
+	for _, blocked := range result.Blocked {
+		details = append(details, fmt.Sprintf("%s: blocked by PodDisruptionBudget %q",
+			blocked.Pod, blocked.PodDisruptionBudget))
+	}
+
+	for _, failed := range result.Failed {
+		details = append(details, fmt.Sprintf("%s: %s", failed.Pod, failed.Error))
+	}
+
+	logger.Log(logger.LevelError, nil, nil,
+		fmt.Sprintf("node drain: failed to evict %d pod(s): %s", notEvicted, strings.Join(details, "; ")))
+
+	finish(fmt.Sprintf("error: failed to evict %d pod(s)", notEvicted))
+}
+
+// listDrainPods lists the pods on the node matching the drain's pod selector
+// and sorts out which of them the drain evicts.
+func listDrainPods(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	nodeName string,
+	opts nodeDrainOptions,
+) (drainPodSelection, error) {
+	pods, err := clientset.CoreV1().Pods("").List(ctx, v1.ListOptions{
+		FieldSelector: "spec.nodeName=" + nodeName,
+		LabelSelector: opts.PodSelector,
+	})
+	if err != nil {
+		return drainPodSelection{}, err
+	}
+
+	return selectDrainPods(pods.Items, opts), nil
+}
+
+// selectDrainPods applies kubectl drain's rules to the pods on a node:
+// mirror pods are skipped, DaemonSet pods are skipped when ignoreDaemonSets is set,
+// and pods without a controller or with emptyDir volumes need force and
+// deleteEmptyDirData respectively. Finished pods can always be evicted.
+func selectDrainPods(pods []corev1.Pod, opts nodeDrainOptions) drainPodSelection {
+	var selection drainPodSelection
+
+	for i := range pods {
+		pod := &pods[i]
+		podName := pod.Namespace + "/" + pod.Name
+
+		if _, isMirror := pod.Annotations[corev1.MirrorPodAnnotationKey]; isMirror {
+			continue
+		}
+
+		// Like kubectl drain, DaemonSet-managed pods are filtered even when
+		// they're finished.
+		if isDaemonSetPod(pod) {
+			if opts.ignoreDaemonSets() {
+				selection.Warnings = append(selection.Warnings, podName+": ignoring DaemonSet-managed pod")
+			} else {
+				selection.Errors = append(selection.Errors, drainFailedPod{
+					Pod: podName, Error: "cannot evict DaemonSet-managed pod (use ignoreDaemonSets to ignore)",
+				})
+			}
+
+			continue
+		}
+
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			selection.Evict = append(selection.Evict, *pod)
+			continue
+		}
+
+		if err := checkDrainPodOptions(pod, opts, &selection); err != "" {
+			selection.Errors = append(selection.Errors, drainFailedPod{Pod: podName, Error: err})
+			continue
+		}
+
+		selection.Evict = append(selection.Evict, *pod)
+	}
+
+	return selection
+}
+
+// checkDrainPodOptions returns why the pod cannot be evicted with the given
+// options, or an empty string if it can. Warnings for pods that are evicted
+// only because of an option are added to the selection.
+func checkDrainPodOptions(pod *corev1.Pod, opts nodeDrainOptions, selection *drainPodSelection) string {
+	podName := pod.Namespace + "/" + pod.Name
+
+	if v1.GetControllerOf(pod) == nil {
+		if !opts.Force {
+			return "cannot evict pod that declares no controller (use force to override)"
+		}
+
+		selection.Warnings = append(selection.Warnings, podName+": evicting pod that declares no controller")
+	}
+
+	if hasEmptyDirVolume(pod) {
+		if !opts.DeleteEmptyDirData {
+			return "cannot evict pod with local storage (use deleteEmptyDirData to override)"
+		}
+
+		selection.Warnings = append(selection.Warnings, podName+": evicting pod with local storage")
+	}
+
+	return ""
+}
+
+func hasEmptyDirVolume(pod *corev1.Pod) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// evictPods evicts the given pods concurrently and waits for them to be deleted
+// until ctx is done, recording each pod's progress in the job.
+// A nil gracePeriodSeconds keeps each pod's own termination grace period.
+func evictPods(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	pods []corev1.Pod,
+	gracePeriodSeconds *int64,
+	job *drainJob,
+) {
+	var wg sync.WaitGroup
+
+	for i := range pods {
+		pod := &pods[i]
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			blockedBy, err := evictPod(ctx, clientset, pod, gracePeriodSeconds, job)
+
+			switch {
+			case err == nil:
+				job.setPodState(pod, drainPodEvicted, "", "")
+			case apierrors.IsTooManyRequests(err):
+				job.setPodState(pod, drainPodBlocked, err.Error(), blockedBy)
+			default:
+				job.setPodState(pod, drainPodFailed, err.Error(), "")
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// evictPod evicts a pod through the policy/v1 Eviction subresource, so that
+// PodDisruptionBudgets and the termination grace period are honored, and waits
+// for the pod to be deleted.
+// The pod is reported as blocked in the job while evictions are refused.
+// Evictions refused with 429 are retried until ctx is done; in that case the
+// last 429 error is returned along with the name of the blocking budget, if known.
+func evictPod(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	pod *corev1.Pod,
+	gracePeriodSeconds *int64,
+	job *drainJob,
+) (string, error) {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+	}
+
+	if gracePeriodSeconds != nil {
+		eviction.DeleteOptions = &v1.DeleteOptions{GracePeriodSeconds: gracePeriodSeconds}
+	}
+
+	var blockedBy string
+
+	for {
+		err := clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+
+		switch {
+		case err == nil, apierrors.IsNotFound(err):
+			job.setPodState(pod, drainPodEvicting, "", "")
+
+			return "", waitForPodDeletion(ctx, clientset, pod)
+		case !apierrors.IsTooManyRequests(err):
+			return "", err
+		}
+
+		if blockedBy == "" {
+			blockedBy = blockingDisruptionBudget(ctx, clientset, pod)
+			job.setPodState(pod, drainPodBlocked, err.Error(), blockedBy)
+		}
+
+		select {
+		case <-ctx.Done():
+			return blockedBy, err
+		case <-time.After(drainEvictionRetryInterval):
+		}
+	}
+}
+
+// blockingDisruptionBudget returns the name of the PodDisruptionBudget selecting
+// the pod, or an empty string if none can be found.
+func blockingDisruptionBudget(ctx context.Context, clientset kubernetes.Interface, pod *corev1.Pod) string {
+	pdbs, err := clientset.PolicyV1().PodDisruptionBudgets(pod.Namespace).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return ""
+	}
+
+	for i := range pdbs.Items {
+		selector, err := v1.LabelSelectorAsSelector(pdbs.Items[i].Spec.Selector)
+		if err != nil {
+			continue
+		}
+
+		if selector.Matches(labels.Set(pod.Labels)) {
+			return pdbs.Items[i].Name
+		}
+	}
+
+	return ""
+}
+
+// waitForPodDeletion waits until the pod is gone or has been replaced by a new
+// pod with the same name.
+func waitForPodDeletion(ctx context.Context, clientset kubernetes.Interface, pod *corev1.Pod) error {
+	for {
+		current, err := clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, v1.GetOptions{})
+		if apierrors.IsNotFound(err) || (err == nil && current.UID != pod.UID) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for pod to be deleted: %w", ctx.Err())
+		case <-time.After(drainPodDeletionPollInterval):
+		}
+	}
+}
+
+func isDaemonSetPod(pod *corev1.Pod) bool {
+	controllerRef := v1.GetControllerOf(pod)
+	if controllerRef == nil {
+		return false
+	}
+
+	return controllerRef.Kind == "DaemonSet"
+}
+
+/*
+* Handle node drain status
+Since node drain is an async operation, we need to poll for the status of the drain operation
+This endpoint returns the status of the drain operation.
+*/
+func (c *HeadlampConfig) handleNodeDrainStatus(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ctx := r.Context()
+
+	_, span := telemetry.CreateSpan(ctx, r, "node-management", "handleNodeDrainStatus",
+		attribute.String("cluster", r.URL.Query().Get("cluster")),
+		attribute.String("nodeName", r.URL.Query().Get("nodeName")),
+	)
+	c.TelemetryHandler.RecordEvent(span, "handleNodeDrainStatus request started")
+	c.TelemetryHandler.RecordRequestCount(ctx, r)
+
+	defer span.End()
+
+	// Parse query parameters
+	drainPayload := struct {
+		Cluster  string `json:"cluster"`
+		NodeName string `json:"nodeName"`
+	}{
+		Cluster:  r.URL.Query().Get("cluster"),
+		NodeName: r.URL.Query().Get("nodeName"),
+	}
+
+	if drainPayload.NodeName == "" {
+		c.handleError(w, ctx, span, errors.New("nodeName is required"), "nodeName is missing", http.StatusBadRequest)
+		return
+	}
+
+	if drainPayload.Cluster == "" {
+		c.handleError(w, ctx, span, errors.New("clusterName is required"), "clusterName is missing", http.StatusBadRequest)
+
+		return
+	}
+
+	cacheKey := uuid.NewSHA1(uuid.Nil, []byte(drainPayload.NodeName+"\x00"+drainPayload.Cluster)).String()
+
+	job, err := c.getDrainJob(ctx, cacheKey)
+	if err != nil {
+		c.handleError(w, ctx, span, err, "failed to get cache item", http.StatusNotFound)
+
+		return
+	}
+
+	status, _ := job.Status()
+	result := status.Result()
+
+	// Prepare successful response. The status is sent as "id" for compatibility with older clients.
+	responsePayload := struct {
+		ID       string            `json:"id"`
+		Cluster  string            `json:"cluster"`
+		Evicted  []string          `json:"evicted,omitempty"`
+		Blocked  []drainBlockedPod `json:"blocked,omitempty"`
+		Failed   []drainFailedPod  `json:"failed,omitempty"`
+		Warnings []string          `json:"warnings,omitempty"`
+		JobID    string            `json:"jobId"`
+	}{
+		ID:       result.Status,
+		Cluster:  drainPayload.Cluster,
+		Evicted:  result.Evicted,
+		Blocked:  result.Blocked,
+		Failed:   result.Failed,
+		Warnings: result.Warnings,
+		JobID:    status.ID,
+	}
+
+	c.TelemetryHandler.RecordEvent(span, "Drain status found", attribute.String("cache.key", cacheKey))
+
+	if err = json.NewEncoder(w).Encode(responsePayload); err != nil {
+		c.handleError(w, ctx, span, err, "failed to encode response", http.StatusInternalServerError)
+
+		return
+	}
+
+	c.TelemetryHandler.RecordDuration(ctx, start, attribute.String("api.route", "handleNodeDrainStatus"))
+	logger.Log(logger.LevelInfo,
+		map[string]string{logFieldDurationMs: fmt.Sprintf("%d", time.Since(start).Milliseconds())},
+		nil, "handleNodeDrainStatus completed")
+}
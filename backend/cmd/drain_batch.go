@@ -0,0 +1,477 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// drainPhasePending nodes of a batch drain have not been drained yet.
+	drainPhasePending drainPhase = "Pending"
+	// drainPhaseSkipped nodes of a batch drain were not drained because an earlier node failed.
+	drainPhaseSkipped drainPhase = "Skipped"
+)
+
+// nodeBatchDrainOptions select the nodes of a batch drain and how they are rolled through.
+type nodeBatchDrainOptions struct {
+	// NodeNames are the nodes to drain, in order.
+	NodeNames []string `json:"nodeNames"`
+	// NodeSelector is a label selector for the nodes to drain, as an alternative to NodeNames.
+	NodeSelector string `json:"nodeSelector"`
+	// MaxUnavailable is how many nodes are drained at the same time. Defaults to 1.
+	MaxUnavailable int `json:"maxUnavailable"`
+	// StopOnFailure stops starting new node drains once one of them fails.
+	StopOnFailure bool `json:"stopOnFailure"`
+}
+
+// Validate checks the options and applies defaults.
+func (o *nodeBatchDrainOptions) Validate() error {
+	if len(o.NodeNames) > 0 && o.NodeSelector != "" {
+		return errors.New("nodeNames and nodeSelector are mutually exclusive")
+	}
+
+	if len(o.NodeNames) == 0 && o.NodeSelector == "" {
+		return errors.New("either nodeNames or nodeSelector is required")
+	}
+
+	seen := make(map[string]bool, len(o.NodeNames))
+
+	for _, nodeName := range o.NodeNames {
+		if nodeName == "" {
+			return errors.New("nodeNames cannot contain empty names")
+		}
+
+		if seen[nodeName] {
+			return fmt.Errorf("nodeNames contains %q more than once", nodeName)
+		}
+
+		seen[nodeName] = true
+	}
+
+	if _, err := labels.Parse(o.NodeSelector); err != nil {
+		return fmt.Errorf("invalid nodeSelector: %w", err)
+	}
+
+	if o.MaxUnavailable < 0 {
+		return errors.New("maxUnavailable cannot be negative")
+	}
+
+	if o.MaxUnavailable == 0 {
+		o.MaxUnavailable = 1
+	}
+
+	return nil
+}
+
+// drainBatchNode is the state of one node in a batch drain.
+type drainBatchNode struct {
+	NodeName string     `json:"nodeName"`
+	Phase    drainPhase `json:"phase"`
+	// JobID is the ID of the node's drain job, once it has started.
+	JobID   string `json:"jobId,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// drainBatchStatus is a point-in-time copy of a batch drain, as returned by the API.
+type drainBatchStatus struct {
+	ID             string           `json:"id"`
+	Cluster        string           `json:"cluster"`
+	Phase          drainPhase       `json:"phase"`
+	MaxUnavailable int              `json:"maxUnavailable"`
+	StopOnFailure  bool             `json:"stopOnFailure"`
+	StartTime      time.Time        `json:"startTime"`
+	EndTime        *time.Time       `json:"endTime,omitempty"`
+	Nodes          []drainBatchNode `json:"nodes"`
+	// Counts is the number of nodes in each phase.
+	Counts map[drainPhase]int `json:"counts"`
+}
+
+// drainBatch tracks a drain of several nodes, each of which runs as a regular drain job.
+type drainBatch struct {
+	mu     sync.Mutex
+	status drainBatchStatus
+}
+
+func newDrainBatch(cluster string, nodeNames []string, opts nodeBatchDrainOptions) *drainBatch {
+	nodes := make([]drainBatchNode, 0, len(nodeNames))
+	for _, nodeName := range nodeNames {
+		nodes = append(nodes, drainBatchNode{NodeName: nodeName, Phase: drainPhasePending})
+	}
+
+	return &drainBatch{
+		status: drainBatchStatus{
+			ID:             uuid.NewString(),
+			Cluster:        cluster,
+			Phase:          drainPhaseRunning,
+			MaxUnavailable: opts.MaxUnavailable,
+			StopOnFailure:  opts.StopOnFailure,
+			StartTime:      time.Now().UTC(),
+			Nodes:          nodes,
+		},
+	}
+}
+
+// ID returns the batch's ID.
+func (b *drainBatch) ID() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.status.ID
+}
+
+// Status returns a copy of the batch's status.
+func (b *drainBatch) Status() drainBatchStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := b.status
+	status.Nodes = append([]drainBatchNode{}, b.status.Nodes...)
+	status.Counts = map[drainPhase]int{}
+
+	for _, node := range status.Nodes {
+		status.Counts[node.Phase]++
+	}
+
+	return status
+}
+
+// setNode updates the state of the i-th node.
+func (b *drainBatch) setNode(i int, phase drainPhase, jobID, message string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.status.Nodes[i].Phase = phase
+	b.status.Nodes[i].Message = message
+
+	if jobID != "" {
+		b.status.Nodes[i].JobID = jobID
+	}
+}
+
+// finish ends the batch, which succeeded only if every node was drained.
+func (b *drainBatch) finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now().UTC()
+	b.status.EndTime = &now
+	b.status.Phase = drainPhaseSucceeded
+
+	for _, node := range b.status.Nodes {
+		if node.Phase != drainPhaseSucceeded {
+			b.status.Phase = drainPhaseFailed
+		}
+	}
+}
+
+/*
+Handle batch node drain
+This endpoint drains several nodes, at most maxUnavailable at a time, and
+returns the ID of the batch whose aggregate status is served by
+handleDrainBatch.
+*/
+func (c *HeadlampConfig) handleNodeBatchDrain(w http.ResponseWriter, r *http.Request) { //nolint:funlen
+	ctx := r.Context()
+	_, span := telemetry.CreateSpan(ctx, r, "node-management", "handleNodeBatchDrain")
+	c.TelemetryHandler.RecordRequestCount(ctx, r)
+	c.TelemetryHandler.RecordEvent(span, "batch node drain request started")
+
+	defer span.End()
+
+	var payload struct {
+		Cluster string `json:"cluster"`
+		nodeBatchDrainOptions
+		nodeDrainOptions
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		c.handleError(w, ctx, span, err, "decoding payload", http.StatusBadRequest)
+
+		return
+	}
+
+	if payload.Cluster == "" {
+		c.handleError(w, ctx, span, errors.New("clusterName not found"), "missing clusterName", http.StatusBadRequest)
+
+		return
+	}
+
+	if err := payload.nodeBatchDrainOptions.Validate(); err != nil {
+		c.handleError(w, ctx, span, err, "invalid batch drain options", http.StatusBadRequest)
+
+		return
+	}
+
+	if err := payload.nodeDrainOptions.Validate(); err != nil {
+		c.handleError(w, ctx, span, err, "invalid drain options", http.StatusBadRequest)
+
+		return
+	}
+
+	if payload.DryRun {
+		c.handleError(w, ctx, span, errors.New("dryRun is not supported for batch drains"),
+			"invalid drain options", http.StatusBadRequest)
+
+		return
+	}
+
+	clientset := c.requestClientset(w, r, span, payload.Cluster)
+	if clientset == nil {
+		return
+	}
+
+	nodeNames, err := batchDrainNodeNames(ctx, clientset, payload.nodeBatchDrainOptions)
+	if err != nil {
+		c.handleError(w, ctx, span, err, "listing nodes", kubernetesErrorStatus(err))
+
+		return
+	}
+
+	if len(nodeNames) == 0 {
+		c.handleError(w, ctx, span, errors.New("no nodes match the nodeSelector"), "no nodes to drain",
+			http.StatusBadRequest)
+
+		return
+	}
+
+	serverCtx := c.ServerCtx
+	if serverCtx == nil {
+		serverCtx = context.Background()
+	}
+
+	batch := newDrainBatch(payload.Cluster, nodeNames, payload.nodeBatchDrainOptions)
+
+	// Keep the running batch around until its first node changes, which
+	// keeps it around again.
+	c.storeDrainBatch(ctx, batch, c.drainBatchTTL(payload.nodeDrainOptions))
+
+	go c.runDrainBatch(serverCtx, clientset, batch, payload.nodeBatchDrainOptions, payload.nodeDrainOptions)
+
+	responsePayload := struct {
+		Message string   `json:"message"`
+		Cluster string   `json:"cluster"`
+		BatchID string   `json:"batchId"`
+		Nodes   []string `json:"nodes"`
+	}{
+		Message: "Batch drain request submitted successfully",
+		Cluster: payload.Cluster,
+		BatchID: batch.ID(),
+		Nodes:   nodeNames,
+	}
+
+	if err := json.NewEncoder(w).Encode(responsePayload); err != nil {
+		c.handleError(w, ctx, span, err, "writing response", http.StatusInternalServerError)
+	}
+}
+
+// batchDrainNodeNames returns the nodes a batch drain goes through, either as
+// given or listed with the node selector, sorted by name.
+func batchDrainNodeNames(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	opts nodeBatchDrainOptions,
+) ([]string, error) {
+	if len(opts.NodeNames) > 0 {
+		return opts.NodeNames, nil
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, v1.ListOptions{LabelSelector: opts.NodeSelector})
+	if err != nil {
+		return nil, err
+	}
+
+	nodeNames := make([]string, 0, len(nodes.Items))
+	for i := range nodes.Items {
+		nodeNames = append(nodeNames, nodes.Items[i].Name)
+	}
+
+	sort.Strings(nodeNames)
+
+	return nodeNames, nil
+}
+
+// runDrainBatch drains the nodes of the batch in order, with at most
+// MaxUnavailable node drains running at a time. If the server shuts down
+// meanwhile, the nodes not drained yet fail and the batch finishes.
+func (c *HeadlampConfig) runDrainBatch(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	batch *drainBatch,
+	batchOpts nodeBatchDrainOptions,
+	opts nodeDrainOptions,
+) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		stopped bool
+	)
+
+	status := batch.Status()
+	slots := make(chan struct{}, batchOpts.MaxUnavailable)
+
+	// Each node's drain may take up to the drain timeout, so the batch is kept
+	// for that long after every change of one of its nodes.
+	setNode := func(i int, phase drainPhase, jobID, message string) {
+		batch.setNode(i, phase, jobID, message)
+		c.storeDrainBatch(ctx, batch, c.drainBatchTTL(opts))
+	}
+
+	// The nodes from i on are not drained once the server shuts down.
+	interrupt := func(i int) {
+		for ; i < len(status.Nodes); i++ {
+			setNode(i, drainPhaseSkipped, "", drainInterruptedMessage)
+		}
+	}
+
+	for i, node := range status.Nodes {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			interrupt(i)
+			break
+		}
+
+		mu.Lock()
+		skip := stopped
+		mu.Unlock()
+
+		if skip {
+			setNode(i, drainPhaseSkipped, "", "an earlier node failed to drain")
+			<-slots
+
+			continue
+		}
+
+		job := c.drainNode(ctx, clientset, node.NodeName, status.Cluster, opts)
+		if job == nil {
+			interrupt(i)
+			break
+		}
+
+		setNode(i, drainPhaseRunning, job.ID(), "")
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			jobStatus := waitForDrainJob(ctx, job)
+			if !jobStatus.Done() {
+				setNode(i, drainPhaseFailed, "", drainInterruptedMessage)
+				return
+			}
+
+			setNode(i, jobStatus.Phase, "", jobStatus.Message)
+
+			if jobStatus.Phase == drainPhaseFailed && batchOpts.StopOnFailure {
+				mu.Lock()
+				stopped = true
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	batch.finish()
+	c.storeDrainBatch(ctx, batch, DrainNodeCacheTTL*time.Second)
+}
+
+// drainBatchTTL returns how long a batch drain is kept after one of its nodes
+// changes: long enough for the drain of a node to finish.
+func (c *HeadlampConfig) drainBatchTTL(opts nodeDrainOptions) time.Duration {
+	return c.drainTimeout(opts) + DrainNodeCacheTTL*time.Second
+}
+
+// storeDrainBatch caches the batch under its ID, even if the server is
+// shutting down, so that it can still be queried.
+func (c *HeadlampConfig) storeDrainBatch(ctx context.Context, batch *drainBatch, ttl time.Duration) {
+	_ = c.Cache.SetWithTTL(context.WithoutCancel(ctx), batch.ID(), batch, ttl)
+}
+
+// waitForDrainJob waits until the job is done or ctx is, and returns its latest status.
+func waitForDrainJob(ctx context.Context, job *drainJob) drainJobStatus {
+	for {
+		status, changed := job.Status()
+		if status.Done() {
+			return status
+		}
+
+		select {
+		case <-ctx.Done():
+			return status
+		case <-changed:
+		}
+	}
+}
+
+/*
+Handle batch drain status
+This endpoint returns the aggregate status of the batch drain with the given ID.
+The progress of each node is available from its drain job.
+*/
+func (c *HeadlampConfig) handleDrainBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	batchID := mux.Vars(r)["id"]
+
+	_, span := telemetry.CreateSpan(ctx, r, "node-management", "handleDrainBatch",
+		attribute.String("batchID", batchID),
+	)
+	c.TelemetryHandler.RecordRequestCount(ctx, r)
+
+	defer span.End()
+
+	cacheItem, err := c.Cache.Get(ctx, batchID)
+	if err != nil {
+		c.handleError(w, ctx, span, err, "batch drain not found", http.StatusNotFound)
+
+		return
+	}
+
+	batch, ok := cacheItem.(*drainBatch)
+	if !ok {
+		c.handleError(w, ctx, span, errors.New("not a batch drain"), "batch drain not found", http.StatusNotFound)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(batch.Status()); err != nil {
+		c.handleError(w, ctx, span, err, "failed to encode response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,374 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestNodeBatchDrainOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name               string
+		opts               nodeBatchDrainOptions
+		wantErr            string
+		wantMaxUnavailable int
+	}{
+		{name: "node names", opts: nodeBatchDrainOptions{NodeNames: []string{"a"}}, wantMaxUnavailable: 1},
+		{
+			name:               "selector",
+			opts:               nodeBatchDrainOptions{NodeSelector: "pool=blue", MaxUnavailable: 3},
+			wantMaxUnavailable: 3,
+		},
+		{name: "no nodes", opts: nodeBatchDrainOptions{}, wantErr: "required"},
+		{
+			name:    "names and selector",
+			opts:    nodeBatchDrainOptions{NodeNames: []string{"a"}, NodeSelector: "pool=blue"},
+			wantErr: "mutually exclusive",
+		},
+		{
+			name:    "invalid selector",
+			opts:    nodeBatchDrainOptions{NodeSelector: "pool in (blue"},
+			wantErr: "invalid nodeSelector",
+		},
+		{
+			name:    "empty node name",
+			opts:    nodeBatchDrainOptions{NodeNames: []string{"a", ""}},
+			wantErr: "empty names",
+		},
+		{
+			name:    "duplicate node name",
+			opts:    nodeBatchDrainOptions{NodeNames: []string{"a", "b", "a"}},
+			wantErr: `"a" more than once`,
+		},
+		{
+			name:    "negative maxUnavailable",
+			opts:    nodeBatchDrainOptions{NodeNames: []string{"a"}, MaxUnavailable: -1},
+			wantErr: "maxUnavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMaxUnavailable, tt.opts.MaxUnavailable)
+		})
+	}
+}
+
+// newBatchDrainTestClient returns a clientset with the given nodes, each
+// running one ReplicaSet pod, whose evictions delete the pod.
+func newBatchDrainTestClient(nodeLabels map[string]map[string]string) *fake.Clientset {
+	var objects []k8sruntime.Object
+
+	for nodeName, nodeLabels := range nodeLabels {
+		objects = append(objects, &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName, Labels: nodeLabels},
+		})
+
+		pod := newDrainTestPod("default", "pod-on-"+nodeName)
+		pod.Spec.NodeName = nodeName
+		objects = append(objects, pod)
+	}
+
+	fakeClient := fake.NewClientset(objects...)
+	evictionDeletesPods(fakeClient)
+
+	return fakeClient
+}
+
+func waitForDrainBatch(t *testing.T, batch *drainBatch) drainBatchStatus {
+	t.Helper()
+
+	var status drainBatchStatus
+
+	require.Eventually(t, func() bool {
+		status = batch.Status()
+		return status.Phase != drainPhaseRunning
+	}, 5*time.Second, 20*time.Millisecond)
+
+	return status
+}
+
+func TestRunDrainBatchLimitsConcurrentDrains(t *testing.T) {
+	useFastDrainPolling(t)
+
+	fakeClient := newBatchDrainTestClient(map[string]map[string]string{
+		"node-a": nil, "node-b": nil, "node-c": nil,
+	})
+
+	var active, maxActive atomic.Int32
+
+	fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		if action.GetSubresource() == "eviction" {
+			n := active.Add(1)
+			defer active.Add(-1)
+
+			for {
+				current := maxActive.Load()
+				if n <= current || maxActive.CompareAndSwap(current, n) {
+					break
+				}
+			}
+
+			time.Sleep(30 * time.Millisecond)
+		}
+
+		return false, nil, nil
+	})
+
+	c := &HeadlampConfig{HeadlampConfig: &headlampconfig.HeadlampConfig{Cache: cache.New[interface{}]()}}
+	opts := nodeBatchDrainOptions{NodeNames: []string{"node-a", "node-b", "node-c"}}
+	require.NoError(t, opts.Validate())
+
+	batch := newDrainBatch("test-cluster", opts.NodeNames, opts)
+	go c.runDrainBatch(context.Background(), fakeClient, batch, opts, nodeDrainOptions{})
+
+	status := waitForDrainBatch(t, batch)
+
+	assert.Equal(t, drainPhaseSucceeded, status.Phase)
+	assert.Equal(t, map[drainPhase]int{drainPhaseSucceeded: 3}, status.Counts)
+	assert.Equal(t, int32(1), maxActive.Load(), "only one node should be drained at a time")
+
+	for _, node := range status.Nodes {
+		assert.NotEmpty(t, node.JobID)
+	}
+
+	for _, nodeName := range opts.NodeNames {
+		node, err := fakeClient.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.True(t, node.Spec.Unschedulable)
+	}
+}
+
+func TestRunDrainBatchStopsOnFailure(t *testing.T) {
+	useFastDrainPolling(t)
+
+	fakeClient := newBatchDrainTestClient(map[string]map[string]string{"node-b": nil, "node-c": nil})
+
+	c := &HeadlampConfig{HeadlampConfig: &headlampconfig.HeadlampConfig{Cache: cache.New[interface{}]()}}
+	// node-a does not exist, so cordoning it fails.
+	opts := nodeBatchDrainOptions{NodeNames: []string{"node-a", "node-b", "node-c"}, StopOnFailure: true}
+	require.NoError(t, opts.Validate())
+
+	batch := newDrainBatch("test-cluster", opts.NodeNames, opts)
+	go c.runDrainBatch(context.Background(), fakeClient, batch, opts, nodeDrainOptions{})
+
+	status := waitForDrainBatch(t, batch)
+
+	assert.Equal(t, drainPhaseFailed, status.Phase)
+	assert.Equal(t, drainPhaseFailed, status.Nodes[0].Phase)
+	assert.Contains(t, status.Nodes[0].Message, "not found")
+	assert.Equal(t, drainPhaseSkipped, status.Nodes[1].Phase)
+	assert.Equal(t, drainPhaseSkipped, status.Nodes[2].Phase)
+
+	node, err := fakeClient.CoreV1().Nodes().Get(context.Background(), "node-b", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.False(t, node.Spec.Unschedulable, "nodes after a failure should not be drained")
+}
+
+func TestRunDrainBatchContinuesAfterFailure(t *testing.T) {
+	useFastDrainPolling(t)
+
+	fakeClient := newBatchDrainTestClient(map[string]map[string]string{"node-b": nil})
+
+	c := &HeadlampConfig{HeadlampConfig: &headlampconfig.HeadlampConfig{Cache: cache.New[interface{}]()}}
+	opts := nodeBatchDrainOptions{NodeNames: []string{"node-a", "node-b"}, MaxUnavailable: 2}
+	require.NoError(t, opts.Validate())
+
+	batch := newDrainBatch("test-cluster", opts.NodeNames, opts)
+	go c.runDrainBatch(context.Background(), fakeClient, batch, opts, nodeDrainOptions{})
+
+	status := waitForDrainBatch(t, batch)
+
+	assert.Equal(t, drainPhaseFailed, status.Phase)
+	assert.Equal(t, map[drainPhase]int{drainPhaseFailed: 1, drainPhaseSucceeded: 1}, status.Counts)
+}
+
+func TestBatchDrainNodeNamesFromSelector(t *testing.T) {
+	fakeClient := newBatchDrainTestClient(map[string]map[string]string{
+		"node-b": {"pool": "blue"},
+		"node-a": {"pool": "blue"},
+		"node-c": {"pool": "green"},
+	})
+
+	nodeNames, err := batchDrainNodeNames(context.Background(), fakeClient,
+		nodeBatchDrainOptions{NodeSelector: "pool=blue"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"node-a", "node-b"}, nodeNames)
+}
+
+// ttlRecordingCache records the TTLs the values of a key are cached with.
+type ttlRecordingCache struct {
+	cache.Cache[interface{}]
+	key  string
+	mu   sync.Mutex
+	ttls []time.Duration
+}
+
+func (c *ttlRecordingCache) SetWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if key == c.key {
+		c.mu.Lock()
+		c.ttls = append(c.ttls, ttl)
+		c.mu.Unlock()
+	}
+
+	return c.Cache.SetWithTTL(ctx, key, value, ttl)
+}
+
+func TestRunDrainBatchRefreshesCacheTTL(t *testing.T) {
+	useFastDrainPolling(t)
+
+	fakeClient := newBatchDrainTestClient(map[string]map[string]string{"node-a": nil, "node-b": nil})
+
+	opts := nodeBatchDrainOptions{NodeNames: []string{"node-a", "node-b"}}
+	require.NoError(t, opts.Validate())
+
+	batch := newDrainBatch("test-cluster", opts.NodeNames, opts)
+	testCache := &ttlRecordingCache{Cache: cache.New[interface{}](), key: batch.ID()}
+	c := &HeadlampConfig{HeadlampConfig: &headlampconfig.HeadlampConfig{Cache: testCache}}
+
+	go c.runDrainBatch(context.Background(), fakeClient, batch, opts, nodeDrainOptions{timeout: time.Minute})
+
+	assert.Equal(t, drainPhaseSucceeded, waitForDrainBatch(t, batch).Phase)
+
+	require.Eventually(t, func() bool {
+		testCache.mu.Lock()
+		defer testCache.mu.Unlock()
+
+		return len(testCache.ttls) == 5
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Each node is kept when it starts and when it's drained, and the batch once it's done.
+	nodeTTL := time.Minute + DrainNodeCacheTTL*time.Second
+	assert.Equal(t, []time.Duration{nodeTTL, nodeTTL, nodeTTL, nodeTTL, DrainNodeCacheTTL * time.Second},
+		testCache.ttls)
+}
+
+func TestRunDrainBatchFailsWhenServerShutsDown(t *testing.T) {
+	useFastDrainPolling(t)
+
+	// The evicted pods are never deleted, so the drain of node-a waits until it times out.
+	var objects []k8sruntime.Object
+
+	for _, nodeName := range []string{"node-a", "node-b"} {
+		pod := newDrainTestPod("default", "pod-on-"+nodeName)
+		pod.Spec.NodeName = nodeName
+		objects = append(objects, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}, pod)
+	}
+
+	fakeClient := fake.NewClientset(objects...)
+
+	testCache := cache.New[interface{}]()
+	c := &HeadlampConfig{HeadlampConfig: &headlampconfig.HeadlampConfig{Cache: testCache}}
+	opts := nodeBatchDrainOptions{NodeNames: []string{"node-a", "node-b"}}
+	require.NoError(t, opts.Validate())
+
+	serverCtx, shutdown := context.WithCancel(context.Background())
+	defer shutdown()
+
+	batch := newDrainBatch("test-cluster", opts.NodeNames, opts)
+	go c.runDrainBatch(serverCtx, fakeClient, batch, opts, nodeDrainOptions{timeout: time.Hour})
+
+	require.Eventually(t, func() bool {
+		return batch.Status().Nodes[0].Phase == drainPhaseRunning
+	}, 5*time.Second, 10*time.Millisecond)
+
+	shutdown()
+
+	status := waitForDrainBatch(t, batch)
+	assert.Equal(t, drainPhaseFailed, status.Phase)
+	assert.Equal(t, drainPhaseFailed, status.Nodes[0].Phase)
+	assert.Equal(t, drainInterruptedMessage, status.Nodes[0].Message)
+	assert.Equal(t, drainPhaseSkipped, status.Nodes[1].Phase)
+	assert.Equal(t, drainInterruptedMessage, status.Nodes[1].Message)
+
+	require.Eventually(t, func() bool {
+		_, err := testCache.Get(context.Background(), batch.ID())
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "the finished batch is still cached")
+}
+
+func TestHandleDrainBatch(t *testing.T) {
+	testCache := cache.New[interface{}]()
+	handler := createHeadlampHandler(context.Background(), &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			HeadlampCFG: &headlampconfig.HeadlampCFG{
+				KubeConfigPath:  filepath.Join("headlamp_testdata", "kubeconfig"),
+				KubeConfigStore: kubeconfig.NewContextStore(),
+			},
+			Cache:            testCache,
+			TelemetryConfig:  GetDefaultTestTelemetryConfig(),
+			TelemetryHandler: &telemetry.RequestHandler{},
+		},
+	})
+
+	opts := nodeBatchDrainOptions{NodeNames: []string{"node-a", "node-b"}, MaxUnavailable: 1}
+	batch := newDrainBatch("test-cluster", opts.NodeNames, opts)
+	batch.setNode(0, drainPhaseRunning, "job-a", "")
+	require.NoError(t, testCache.Set(context.Background(), batch.ID(), batch))
+
+	rr, err := getResponseFromRestrictedEndpoint(handler, "GET", "/drain-node-batches/"+batch.ID(), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var status drainBatchStatus
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&status))
+	assert.Equal(t, drainPhaseRunning, status.Phase)
+	assert.Equal(t, []drainBatchNode{
+		{NodeName: "node-a", Phase: drainPhaseRunning, JobID: "job-a"},
+		{NodeName: "node-b", Phase: drainPhasePending},
+	}, status.Nodes)
+	assert.Equal(t, map[drainPhase]int{drainPhaseRunning: 1, drainPhasePending: 1}, status.Counts)
+
+	rr, err = getResponseFromRestrictedEndpoint(handler, "GET", "/drain-node-batches/unknown", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr, err = getResponseFromRestrictedEndpoint(handler, "POST", "/drain-nodes", map[string]interface{}{
+		"cluster": "test-cluster",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,339 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// drainPhase is the lifecycle phase of a drain job.
+type drainPhase string
+
+const (
+	drainPhaseRunning   drainPhase = "Running"
+	drainPhaseSucceeded drainPhase = "Succeeded"
+	drainPhaseFailed    drainPhase = "Failed"
+)
+
+// drainPodState is the progress of a single pod in a drain job.
+type drainPodState string
+
+const (
+	// drainPodPending pods have not been evicted yet.
+	drainPodPending drainPodState = "pending"
+	// drainPodEvicting pods have been evicted and the drain waits for them to be deleted.
+	drainPodEvicting drainPodState = "evicting"
+	// drainPodBlocked pods are refused eviction, usually by a PodDisruptionBudget.
+	drainPodBlocked drainPodState = "blocked-by-pdb"
+	drainPodEvicted drainPodState = "evicted"
+	drainPodFailed  drainPodState = "failed"
+)
+
+// drainJobPod is the state of a pod in a drain job.
+type drainJobPod struct {
+	Pod                 string        `json:"pod"`
+	State               drainPodState `json:"state"`
+	Reason              string        `json:"reason,omitempty"`
+	PodDisruptionBudget string        `json:"podDisruptionBudget,omitempty"`
+}
+
+// drainJobStatus is a point-in-time copy of a drain job, as returned by the API.
+type drainJobStatus struct {
+	ID        string     `json:"id"`
+	Cluster   string     `json:"cluster"`
+	NodeName  string     `json:"nodeName"`
+	Phase     drainPhase `json:"phase"`
+	Message   string     `json:"message,omitempty"`
+	StartTime time.Time  `json:"startTime"`
+	// EndTime is set once the job is no longer running.
+	EndTime  *time.Time    `json:"endTime,omitempty"`
+	Pods     []drainJobPod `json:"pods"`
+	Warnings []string      `json:"warnings,omitempty"`
+}
+
+// drainJob tracks the progress of a node drain. It is updated by the drain
+// goroutine while the status handlers read it, so all access goes through its methods.
+type drainJob struct {
+	mu     sync.Mutex
+	status drainJobStatus
+	// podIndex maps a pod's namespace/name to its position in status.Pods.
+	podIndex map[string]int
+	// changed is closed and replaced on every update, waking up watchers.
+	changed chan struct{}
+}
+
+func newDrainJob(cluster, nodeName string) *drainJob {
+	return &drainJob{
+		status: drainJobStatus{
+			ID:        uuid.NewString(),
+			Cluster:   cluster,
+			NodeName:  nodeName,
+			Phase:     drainPhaseRunning,
+			StartTime: time.Now().UTC(),
+			Pods:      []drainJobPod{},
+		},
+		podIndex: map[string]int{},
+		changed:  make(chan struct{}),
+	}
+}
+
+// ID returns the job's ID.
+func (j *drainJob) ID() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.status.ID
+}
+
+// Status returns a copy of the job's status, and a channel that is closed on the next update.
+func (j *drainJob) Status() (drainJobStatus, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := j.status
+	status.Pods = append([]drainJobPod{}, j.status.Pods...)
+	status.Warnings = append([]string(nil), j.status.Warnings...)
+
+	return status, j.changed
+}
+
+// notify wakes up watchers. It must be called with j.mu held.
+func (j *drainJob) notify() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// setSelection records the pods the drain evicts as pending, and the pods
+// that prevent the drain as failed.
+func (j *drainJob) setSelection(selection drainPodSelection) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for i := range selection.Evict {
+		j.status.Pods = append(j.status.Pods, drainJobPod{
+			Pod:   selection.Evict[i].Namespace + "/" + selection.Evict[i].Name,
+			State: drainPodPending,
+		})
+	}
+
+	for _, failed := range selection.Errors {
+		j.status.Pods = append(j.status.Pods, drainJobPod{Pod: failed.Pod, State: drainPodFailed, Reason: failed.Error})
+	}
+
+	sort.Slice(j.status.Pods, func(a, b int) bool { return j.status.Pods[a].Pod < j.status.Pods[b].Pod })
+
+	for i := range j.status.Pods {
+		j.podIndex[j.status.Pods[i].Pod] = i
+	}
+
+	j.status.Warnings = selection.Warnings
+	j.notify()
+}
+
+// setPodState updates the state of a pod previously added with setSelection.
+func (j *drainJob) setPodState(pod *corev1.Pod, state drainPodState, reason, podDisruptionBudget string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	i, ok := j.podIndex[pod.Namespace+"/"+pod.Name]
+	if !ok {
+		return
+	}
+
+	j.status.Pods[i].State = state
+	j.status.Pods[i].Reason = reason
+	j.status.Pods[i].PodDisruptionBudget = podDisruptionBudget
+	j.notify()
+}
+
+// finish ends the job. An empty message means it succeeded.
+func (j *drainJob) finish(message string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now().UTC()
+	j.status.EndTime = &now
+	j.status.Message = message
+	j.status.Phase = drainPhaseSucceeded
+
+	if message != "" {
+		j.status.Phase = drainPhaseFailed
+	}
+
+	j.notify()
+}
+
+// Done reports whether the job is no longer running.
+func (s drainJobStatus) Done() bool {
+	return s.Phase != drainPhaseRunning
+}
+
+// Result summarizes the job in the format of /drain-node-status.
+func (s drainJobStatus) Result() nodeDrainResult {
+	result := nodeDrainResult{Warnings: s.Warnings}
+
+	switch s.Phase {
+	case drainPhaseRunning:
+		result.Status = drainStatusInProgress
+	case drainPhaseSucceeded:
+		result.Status = drainStatusSuccess
+	case drainPhaseFailed:
+		result.Status = s.Message
+	}
+
+	for _, pod := range s.Pods {
+		switch pod.State {
+		case drainPodEvicted:
+			result.Evicted = append(result.Evicted, pod.Pod)
+		case drainPodBlocked:
+			result.Blocked = append(result.Blocked, drainBlockedPod{Pod: pod.Pod, PodDisruptionBudget: pod.PodDisruptionBudget})
+		case drainPodFailed:
+			result.Failed = append(result.Failed, drainFailedPod{Pod: pod.Pod, Error: pod.Reason})
+		case drainPodPending, drainPodEvicting:
+		}
+	}
+
+	return result
+}
+
+// storeDrainJob caches the job under its ID and under the node's drain status key.
+func (c *HeadlampConfig) storeDrainJob(ctx context.Context, job *drainJob, nodeCacheKey string, ttl time.Duration) {
+	_ = c.Cache.SetWithTTL(ctx, job.ID(), job, ttl)
+	_ = c.Cache.SetWithTTL(ctx, nodeCacheKey, job, ttl)
+}
+
+// getDrainJob returns the cached job stored under key.
+func (c *HeadlampConfig) getDrainJob(ctx context.Context, key string) (*drainJob, error) {
+	cacheItem, err := c.Cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	job, ok := cacheItem.(*drainJob)
+	if !ok {
+		return nil, errors.New("not a drain job")
+	}
+
+	return job, nil
+}
+
+/*
+Handle drain job status
+This endpoint returns the drain job with the given ID, with the state of each pod.
+*/
+func (c *HeadlampConfig) handleDrainJob(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	jobID := mux.Vars(r)["id"]
+
+	_, span := telemetry.CreateSpan(ctx, r, "node-management", "handleDrainJob",
+		attribute.String("jobID", jobID),
+	)
+	c.TelemetryHandler.RecordRequestCount(ctx, r)
+
+	defer span.End()
+
+	job, err := c.getDrainJob(ctx, jobID)
+	if err != nil {
+		c.handleError(w, ctx, span, err, "drain job not found", http.StatusNotFound)
+
+		return
+	}
+
+	status, _ := job.Status()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		c.handleError(w, ctx, span, err, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+/*
+Handle drain job events
+This endpoint streams the drain job with the given ID as server-sent events.
+An event with the whole job is sent on every change, and the stream ends once
+the job is no longer running.
+*/
+func (c *HeadlampConfig) handleDrainJobEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	jobID := mux.Vars(r)["id"]
+
+	_, span := telemetry.CreateSpan(ctx, r, "node-management", "handleDrainJobEvents",
+		attribute.String("jobID", jobID),
+	)
+	c.TelemetryHandler.RecordRequestCount(ctx, r)
+
+	defer span.End()
+
+	job, err := c.getDrainJob(ctx, jobID)
+	if err != nil {
+		c.handleError(w, ctx, span, err, "drain job not found", http.StatusNotFound)
+
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		c.handleError(w, ctx, span, errors.New("streaming unsupported"),
+			"streaming unsupported", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	for {
+		status, changed := job.Status()
+
+		data, err := json.Marshal(status)
+		if err != nil {
+			c.TelemetryHandler.RecordError(span, err, "failed to encode drain job")
+			return
+		}
+
+		if _, err := fmt.Fprintf(w, "event: drain\ndata: %s\n\n", data); err != nil {
+			return
+		}
+
+		flusher.Flush()
+
+		if status.Done() {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestDrainJobLifecycle(t *testing.T) {
+	podA := newDrainTestPod("default", "pod-a")
+	podB := newDrainTestPod("default", "pod-b")
+
+	job := newDrainJob("test-cluster", "test-node")
+	job.setSelection(drainPodSelection{
+		Evict:    []corev1.Pod{*podB, *podA},
+		Errors:   []drainFailedPod{{Pod: "default/pod-c", Error: "needs force"}},
+		Warnings: []string{"a warning"},
+	})
+
+	status, changed := job.Status()
+	assert.Equal(t, drainPhaseRunning, status.Phase)
+	assert.False(t, status.Done())
+	assert.Nil(t, status.EndTime)
+	assert.Equal(t, []drainJobPod{
+		{Pod: "default/pod-a", State: drainPodPending},
+		{Pod: "default/pod-b", State: drainPodPending},
+		{Pod: "default/pod-c", State: drainPodFailed, Reason: "needs force"},
+	}, status.Pods)
+	assert.Equal(t, drainStatusInProgress, status.Result().Status)
+
+	job.setPodState(podA, drainPodBlocked, "too many requests", "quorum-pdb")
+
+	select {
+	case <-changed:
+	default:
+		t.Fatal("watchers should be notified of pod state changes")
+	}
+
+	assert.Equal(t, drainPodPending, status.Pods[0].State, "a status must not change after it is returned")
+
+	job.setPodState(podB, drainPodEvicted, "", "")
+	job.finish("error: failed to evict 2 pod(s)")
+
+	status, _ = job.Status()
+	assert.Equal(t, drainPhaseFailed, status.Phase)
+	assert.True(t, status.Done())
+	require.NotNil(t, status.EndTime)
+	assert.False(t, status.EndTime.Before(status.StartTime))
+
+	result := status.Result()
+	assert.Equal(t, "error: failed to evict 2 pod(s)", result.Status)
+	assert.Equal(t, []string{"default/pod-b"}, result.Evicted)
+	assert.Equal(t, []drainBlockedPod{{Pod: "default/pod-a", PodDisruptionBudget: "quorum-pdb"}}, result.Blocked)
+	assert.Equal(t, []drainFailedPod{{Pod: "default/pod-c", Error: "needs force"}}, result.Failed)
+	assert.Equal(t, []string{"a warning"}, result.Warnings)
+}
+
+func TestDrainNodeRecordsPodStatesInJob(t *testing.T) {
+	useFastDrainPolling(t)
+
+	podFree := newDrainTestPod("default", "pod-free")
+	podGuarded := newDrainTestPod("default", "pod-guarded")
+	podGuarded.Labels = map[string]string{"app": "quorum"}
+
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "quorum-pdb", Namespace: "default"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "quorum"}},
+		},
+	}
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test-node"}}
+
+	fakeClient := fake.NewClientset(node, podFree, podGuarded, pdb)
+	evictionDeletesPods(fakeClient)
+	fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		createAction := action.(k8stesting.CreateAction)
+		if createAction.GetSubresource() == "eviction" &&
+			createAction.GetObject().(*policyv1.Eviction).Name == "pod-guarded" {
+			return true, nil, apierrors.NewTooManyRequests("disruption budget", 0)
+		}
+
+		return false, nil, nil
+	})
+
+	testCache := cache.New[interface{}]()
+	c := &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			HeadlampCFG: &headlampconfig.HeadlampCFG{DrainNodeTimeout: 100 * time.Millisecond},
+			Cache:       testCache,
+		},
+	}
+
+	job := c.drainNode(context.Background(), fakeClient, "test-node", "test-cluster", nodeDrainOptions{})
+	require.NotNil(t, job)
+
+	waitForDrainResult(t, testCache)
+
+	cached, err := c.getDrainJob(context.Background(), job.ID())
+	require.NoError(t, err)
+	assert.Same(t, job, cached, "the job should be readable by its ID")
+
+	status, _ := job.Status()
+	assert.Equal(t, drainPhaseFailed, status.Phase)
+	require.Len(t, status.Pods, 2)
+	assert.Equal(t, drainJobPod{Pod: "default/pod-free", State: drainPodEvicted}, status.Pods[0])
+	assert.Equal(t, "default/pod-guarded", status.Pods[1].Pod)
+	assert.Equal(t, drainPodBlocked, status.Pods[1].State)
+	assert.Equal(t, "quorum-pdb", status.Pods[1].PodDisruptionBudget)
+	assert.NotEmpty(t, status.Pods[1].Reason)
+}
+
+func TestHandleDrainJob(t *testing.T) {
+	testCache := cache.New[interface{}]()
+	handler := createHeadlampHandler(context.Background(), &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			HeadlampCFG: &headlampconfig.HeadlampCFG{
+				KubeConfigPath:  filepath.Join("headlamp_testdata", "kubeconfig"),
+				KubeConfigStore: kubeconfig.NewContextStore(),
+			},
+			Cache:            testCache,
+			TelemetryConfig:  GetDefaultTestTelemetryConfig(),
+			TelemetryHandler: &telemetry.RequestHandler{},
+		},
+	})
+
+	job := newDrainTestJob("test-cluster", "test-node", newDrainTestPod("default", "pod-1"))
+	require.NoError(t, testCache.Set(context.Background(), job.ID(), job))
+
+	rr, err := getResponseFromRestrictedEndpoint(handler, "GET", "/drain-node-jobs/"+job.ID(), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var status drainJobStatus
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&status))
+	assert.Equal(t, job.ID(), status.ID)
+	assert.Equal(t, "test-node", status.NodeName)
+	assert.Equal(t, drainPhaseRunning, status.Phase)
+	assert.Equal(t, []drainJobPod{{Pod: "default/pod-1", State: drainPodPending}}, status.Pods)
+
+	rr, err = getResponseFromRestrictedEndpoint(handler, "GET", "/drain-node-jobs/unknown", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestHandleDrainJobEventsStreamsUntilDone(t *testing.T) {
+	testCache := cache.New[interface{}]()
+	c := &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			Cache:            testCache,
+			TelemetryConfig:  GetDefaultTestTelemetryConfig(),
+			TelemetryHandler: &telemetry.RequestHandler{},
+		},
+	}
+
+	pod := newDrainTestPod("default", "pod-1")
+	job := newDrainTestJob("test-cluster", "test-node", pod)
+	require.NoError(t, testCache.Set(context.Background(), job.ID(), job))
+
+	req := httptest.NewRequest(http.MethodGet, "/drain-node-jobs/"+job.ID()+"/events", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": job.ID()})
+	rr := httptest.NewRecorder()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		c.handleDrainJobEvents(rr, req)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	job.setPodState(pod, drainPodEvicted, "", "")
+	job.finish("")
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("event stream should end once the job is done")
+	}
+
+	assert.Equal(t, "text/event-stream", rr.Header().Get("Content-Type"))
+
+	var events []drainJobStatus
+
+	scanner := bufio.NewScanner(rr.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+
+		var status drainJobStatus
+
+		require.NoError(t, json.Unmarshal([]byte(data), &status))
+		events = append(events, status)
+	}
+
+	require.GreaterOrEqual(t, len(events), 2)
+	assert.Equal(t, drainPhaseRunning, events[0].Phase)
+
+	last := events[len(events)-1]
+	assert.Equal(t, drainPhaseSucceeded, last.Phase)
+	assert.Equal(t, drainPodEvicted, last.Pods[0].State)
+}
+
+func TestHandleDrainJobEventsEndsWhenServerShutsDown(t *testing.T) {
+	useFastDrainPolling(t)
+
+	// The evicted pod is never deleted, so the drain waits until it times out.
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test-node"}}
+	fakeClient := fake.NewClientset(node, newDrainTestPod("default", "pod-1"))
+
+	testCache := cache.New[interface{}]()
+	c := &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			HeadlampCFG:      &headlampconfig.HeadlampCFG{DrainNodeTimeout: time.Hour},
+			Cache:            testCache,
+			TelemetryConfig:  GetDefaultTestTelemetryConfig(),
+			TelemetryHandler: &telemetry.RequestHandler{},
+		},
+	}
+
+	serverCtx, shutdown := context.WithCancel(context.Background())
+	defer shutdown()
+
+	job := c.drainNode(serverCtx, fakeClient, "test-node", "test-cluster", nodeDrainOptions{})
+	require.NotNil(t, job)
+
+	req := httptest.NewRequest(http.MethodGet, "/drain-node-jobs/"+job.ID()+"/events", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": job.ID()})
+	rr := httptest.NewRecorder()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		c.handleDrainJobEvents(rr, req)
+	}()
+
+	require.Eventually(t, func() bool {
+		status, _ := job.Status()
+		return len(status.Pods) == 1 && status.Pods[0].State == drainPodEvicting
+	}, 5*time.Second, 10*time.Millisecond)
+
+	shutdown()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("event stream should end once the server shuts down")
+	}
+
+	status, _ := job.Status()
+	assert.Equal(t, drainPhaseFailed, status.Phase)
+	assert.Equal(t, drainInterruptedMessage, status.Message)
+
+	cached, err := c.getDrainJob(context.Background(), job.ID())
+	require.NoError(t, err)
+	assert.Same(t, job, cached)
+}
@@ -0,0 +1,886 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// useFastDrainPolling shortens the drain retry and poll intervals for the duration of a test.
+func useFastDrainPolling(t *testing.T) {
+	t.Helper()
+
+	retryInterval, pollInterval := drainEvictionRetryInterval, drainPodDeletionPollInterval
+	drainEvictionRetryInterval = 10 * time.Millisecond
+	drainPodDeletionPollInterval = 10 * time.Millisecond
+
+	t.Cleanup(func() {
+		drainEvictionRetryInterval, drainPodDeletionPollInterval = retryInterval, pollInterval
+	})
+}
+
+// evictionDeletesPods makes the fake clientset delete a pod when it is evicted,
+// like the API server does once the eviction is admitted.
+func evictionDeletesPods(fakeClient *fake.Clientset) {
+	fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		err := fakeClient.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"),
+			eviction.Namespace, eviction.Name)
+
+		return true, nil, err
+	})
+}
+
+// newDrainTestPod returns a pod on test-node managed by a ReplicaSet, which a
+// drain evicts without any additional options.
+func newDrainTestPod(namespace, name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					Kind: "ReplicaSet", Name: name + "-rs", APIVersion: "apps/v1",
+					Controller: func() *bool { b := true; return &b }(),
+				},
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "test-node",
+		},
+	}
+}
+
+// newDrainTestJob returns a running drain job evicting the given pods.
+func newDrainTestJob(cluster, nodeName string, pods ...*corev1.Pod) *drainJob {
+	job := newDrainJob(cluster, nodeName)
+
+	var selection drainPodSelection
+	for _, pod := range pods {
+		selection.Evict = append(selection.Evict, *pod)
+	}
+
+	job.setSelection(selection)
+
+	return job
+}
+
+func waitForDrainResult(t *testing.T, testCache cache.Cache[interface{}]) nodeDrainResult {
+	t.Helper()
+
+	cacheKey := uuid.NewSHA1(uuid.Nil, []byte("test-node"+"\x00"+"test-cluster")).String()
+
+	var result nodeDrainResult
+
+	require.Eventually(t, func() bool {
+		cacheItem, err := testCache.Get(context.Background(), cacheKey)
+		if err != nil {
+			return false
+		}
+
+		job, ok := cacheItem.(*drainJob)
+		if !ok {
+			return false
+		}
+
+		status, _ := job.Status()
+		result = status.Result()
+
+		return status.Done()
+	}, 5*time.Second, 20*time.Millisecond)
+
+	return result
+}
+
+func TestDrainAndCordonNode(t *testing.T) { //nolint:funlen
+	type test struct {
+		handler http.Handler
+	}
+
+	cache := cache.New[interface{}]()
+	kubeConfigStore := kubeconfig.NewContextStore()
+	kubeConfigPath := filepath.Join("headlamp_testdata", "kubeconfig")
+
+	tests := []test{
+		{
+			handler: createHeadlampHandler(context.Background(), &HeadlampConfig{
+				HeadlampConfig: &headlampconfig.HeadlampConfig{
+					HeadlampCFG: &headlampconfig.HeadlampCFG{
+						UseInCluster:    false,
+						KubeConfigPath:  kubeConfigPath,
+						KubeConfigStore: kubeConfigStore,
+					},
+					Cache:            cache,
+					TelemetryConfig:  GetDefaultTestTelemetryConfig(),
+					TelemetryHandler: &telemetry.RequestHandler{},
+				},
+			}),
+		},
+	}
+
+	var drainNodePayload struct {
+		Cluster  string `json:"cluster"`
+		NodeName string `json:"nodeName"`
+	}
+
+	for _, tc := range tests {
+		drainNodePayload.Cluster = minikubeName
+		drainNodePayload.NodeName = minikubeName
+
+		rr, err := getResponseFromRestrictedEndpoint(tc.handler, "POST", "/drain-node", drainNodePayload)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v",
+				status, http.StatusOK)
+		}
+
+		cacheKey := uuid.NewSHA1(uuid.Nil, []byte(drainNodePayload.NodeName+"\x00"+drainNodePayload.Cluster)).String()
+		cacheItemTTL := DrainNodeCacheTTL * time.Second
+		ctx := context.Background()
+
+		job := newDrainTestJob(drainNodePayload.Cluster, drainNodePayload.NodeName, newDrainTestPod("default", "pod-1"))
+		job.setPodState(newDrainTestPod("default", "pod-1"), drainPodEvicted, "", "")
+		job.finish("")
+		(&HeadlampConfig{HeadlampConfig: &headlampconfig.HeadlampConfig{Cache: cache}}).
+			storeDrainJob(ctx, job, cacheKey, cacheItemTTL)
+
+		url := fmt.Sprintf(
+			"/drain-node-status?cluster=%s&nodeName=%s",
+			drainNodePayload.Cluster, drainNodePayload.NodeName,
+		)
+
+		rr, err = getResponseFromRestrictedEndpoint(tc.handler, "GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v",
+				status, http.StatusOK)
+		}
+
+		var statusResponse struct {
+			ID      string   `json:"id"`
+			Evicted []string `json:"evicted"`
+			JobID   string   `json:"jobId"`
+		}
+
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&statusResponse))
+		assert.Equal(t, drainStatusSuccess, statusResponse.ID)
+		assert.Equal(t, []string{"default/pod-1"}, statusResponse.Evicted)
+		assert.Equal(t, job.ID(), statusResponse.JobID)
+	}
+}
+
+func TestDrainNodePodEvictionFailure(t *testing.T) { //nolint:funlen
+	useFastDrainPolling(t)
+
+	podOk := newDrainTestPod("default", "pod-ok")
+	podDaemonset := newDrainTestPod("default", "pod-daemonset")
+	podDaemonset.OwnerReferences = []metav1.OwnerReference{
+		{
+			Kind: "DaemonSet", Name: "my-daemonset", APIVersion: "apps/v1",
+			Controller: func() *bool { b := true; return &b }(),
+		},
+	}
+	podFail := newDrainTestPod("kube-system", "pod-fail")
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-node",
+		},
+	}
+
+	fakeClient := fake.NewClientset(node, podOk, podDaemonset, podFail)
+	evictionDeletesPods(fakeClient)
+
+	// Inject error for evicting pod-fail
+	fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		createAction := action.(k8stesting.CreateAction)
+		if createAction.GetSubresource() == "eviction" &&
+			createAction.GetObject().(*policyv1.Eviction).Name == "pod-fail" {
+			return true, nil, apierrors.NewForbidden(corev1.Resource("pods"), "pod-fail", fmt.Errorf("not allowed"))
+		}
+
+		return false, nil, nil
+	})
+
+	testCache := cache.New[interface{}]()
+	c := &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			Cache: testCache,
+		},
+	}
+
+	c.drainNode(context.Background(), fakeClient, "test-node", "test-cluster", nodeDrainOptions{})
+
+	result := waitForDrainResult(t, testCache)
+
+	assert.True(t, strings.HasPrefix(result.Status, "error:"),
+		"expected error status, got: %s", result.Status)
+	assert.Contains(t, result.Status, "failed to evict 1 pod(s)")
+	assert.Equal(t, []string{"default/pod-ok"}, result.Evicted)
+	require.Len(t, result.Failed, 1)
+	assert.Equal(t, "kube-system/pod-fail", result.Failed[0].Pod)
+	assert.Empty(t, result.Blocked)
+}
+
+func TestDrainNodeAllPodsEvictedSuccessfully(t *testing.T) {
+	useFastDrainPolling(t)
+
+	pod1 := newDrainTestPod("default", "pod-1")
+	pod1.Spec.TerminationGracePeriodSeconds = func() *int64 { g := int64(30); return &g }()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-node",
+		},
+	}
+
+	fakeClient := fake.NewClientset(node, pod1)
+	evictionDeletesPods(fakeClient)
+
+	var podDeletes atomic.Int32
+
+	fakeClient.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		podDeletes.Add(1)
+		return false, nil, nil
+	})
+
+	var evictions []*policyv1.Eviction
+
+	fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		if eviction, ok := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction); ok {
+			evictions = append(evictions, eviction)
+		}
+
+		return false, nil, nil
+	})
+
+	testCache := cache.New[interface{}]()
+	c := &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			Cache: testCache,
+		},
+	}
+
+	c.drainNode(context.Background(), fakeClient, "test-node", "test-cluster", nodeDrainOptions{})
+
+	result := waitForDrainResult(t, testCache)
+
+	assert.Equal(t, drainStatusSuccess, result.Status)
+	assert.Equal(t, []string{"default/pod-1"}, result.Evicted)
+	assert.Zero(t, podDeletes.Load(), "pods should be evicted, not deleted directly")
+	require.Len(t, evictions, 1)
+	assert.Nil(t, evictions[0].DeleteOptions, "eviction should use the pod's own grace period")
+}
+
+func TestDrainNodeReportsPodsBlockedByDisruptionBudget(t *testing.T) { //nolint:funlen
+	useFastDrainPolling(t)
+
+	podFree := newDrainTestPod("default", "pod-free")
+	podGuarded := newDrainTestPod("default", "pod-guarded")
+	podGuarded.Labels = map[string]string{"app": "quorum"}
+
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "quorum-pdb",
+			Namespace: "default",
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "quorum"}},
+		},
+	}
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-node",
+		},
+	}
+
+	fakeClient := fake.NewClientset(node, podFree, podGuarded, pdb)
+	evictionDeletesPods(fakeClient)
+
+	var blockedAttempts atomic.Int32
+
+	fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		createAction := action.(k8stesting.CreateAction)
+		if createAction.GetSubresource() == "eviction" &&
+			createAction.GetObject().(*policyv1.Eviction).Name == "pod-guarded" {
+			blockedAttempts.Add(1)
+
+			return true, nil, apierrors.NewTooManyRequests(
+				"Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+
+		return false, nil, nil
+	})
+
+	testCache := cache.New[interface{}]()
+	c := &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			HeadlampCFG: &headlampconfig.HeadlampCFG{
+				DrainNodeTimeout: 200 * time.Millisecond,
+			},
+			Cache: testCache,
+		},
+	}
+
+	c.drainNode(context.Background(), fakeClient, "test-node", "test-cluster", nodeDrainOptions{})
+
+	result := waitForDrainResult(t, testCache)
+
+	assert.Equal(t, "error: failed to evict 1 pod(s)", result.Status)
+	assert.Equal(t, []string{"default/pod-free"}, result.Evicted)
+	assert.Equal(t, []drainBlockedPod{{Pod: "default/pod-guarded", PodDisruptionBudget: "quorum-pdb"}}, result.Blocked)
+	assert.Greater(t, blockedAttempts.Load(), int32(1), "blocked evictions should be retried")
+
+	_, err := fakeClient.CoreV1().Pods("default").Get(context.Background(), "pod-guarded", metav1.GetOptions{})
+	assert.NoError(t, err, "a pod protected by a disruption budget must not be deleted")
+}
+
+func TestDrainNodeWaitsForEvictedPodDeletion(t *testing.T) {
+	useFastDrainPolling(t)
+
+	// Without evictionDeletesPods the fake API server accepts the eviction but
+	// never deletes the pod, like a pod stuck terminating.
+	pod1 := newDrainTestPod("default", "pod-1")
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-node",
+		},
+	}
+
+	fakeClient := fake.NewClientset(node, pod1)
+
+	testCache := cache.New[interface{}]()
+	c := &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			HeadlampCFG: &headlampconfig.HeadlampCFG{
+				DrainNodeTimeout: 100 * time.Millisecond,
+			},
+			Cache: testCache,
+		},
+	}
+
+	c.drainNode(context.Background(), fakeClient, "test-node", "test-cluster", nodeDrainOptions{})
+
+	result := waitForDrainResult(t, testCache)
+
+	assert.Equal(t, "error: failed to evict 1 pod(s)", result.Status)
+	require.Len(t, result.Failed, 1)
+	assert.Contains(t, result.Failed[0].Error, "waiting for pod to be deleted")
+}
+
+func TestHandleNodeDrainUsesRequestedClusterCookieForCustomNamedContext(t *testing.T) { //nolint:funlen
+	const (
+		originalCluster = "original-cluster"
+		customCluster   = "custom-cluster"
+		nodeName        = "node-a"
+		testToken       = "custom-cluster-token"
+	)
+
+	authHeaders := make(chan string, 3)
+	kubeAPI := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case authHeaders <- r.Header.Get("Authorization"):
+		default:
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/v1/nodes/"+nodeName:
+			_ = json.NewEncoder(w).Encode(&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+			})
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/pods":
+			_ = json.NewEncoder(w).Encode(&corev1.PodList{})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(kubeAPI.Close)
+
+	kubeConfigStore := kubeconfig.NewContextStore()
+	err := kubeConfigStore.AddContext(&kubeconfig.Context{
+		Name: originalCluster,
+		KubeContext: &api.Context{
+			Cluster:  originalCluster,
+			AuthInfo: originalCluster,
+			Extensions: map[string]k8sruntime.Object{
+				"headlamp_info": &kubeconfig.CustomObject{CustomName: customCluster},
+			},
+		},
+		Cluster: &api.Cluster{
+			Server:                kubeAPI.URL,
+			InsecureSkipTLSVerify: true,
+		},
+		AuthInfo: &api.AuthInfo{},
+		Source:   kubeconfig.InCluster,
+	})
+	require.NoError(t, err)
+
+	cfg := &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			HeadlampCFG: &headlampconfig.HeadlampCFG{
+				UseInCluster:    true,
+				KubeConfigStore: kubeConfigStore,
+			},
+			Cache:            cache.New[interface{}](),
+			TelemetryConfig:  GetDefaultTestTelemetryConfig(),
+			TelemetryHandler: &telemetry.RequestHandler{},
+		},
+	}
+
+	req, err := makeJSONReq(http.MethodPost, "/drain-node", struct {
+		Cluster  string `json:"cluster"`
+		NodeName string `json:"nodeName"`
+	}{
+		Cluster:  customCluster,
+		NodeName: nodeName,
+	})
+	require.NoError(t, err)
+	req.AddCookie(&http.Cookie{
+		Name:     "headlamp-auth-" + customCluster + ".0",
+		Value:    testToken,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	rr := httptest.NewRecorder()
+	cfg.handleNodeDrain(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	select {
+	case got := <-authHeaders:
+		assert.Equal(t, "Bearer "+testToken, got)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for drain request to reach Kubernetes API")
+	}
+}
+
+func TestDrainNodeCancelledContextDoesNotWriteCache(t *testing.T) {
+	pod1 := newDrainTestPod("default", "pod-1")
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-node",
+		},
+	}
+
+	fakeClient := fake.NewClientset(node, pod1)
+
+	testCache := cache.New[interface{}]()
+	c := &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			Cache: testCache,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cacheKey := uuid.NewSHA1(uuid.Nil, []byte("test-node"+"\x00"+"test-cluster")).String()
+
+	c.drainNode(ctx, fakeClient, "test-node", "test-cluster", nodeDrainOptions{})
+
+	assert.Never(t, func() bool {
+		_, err := testCache.Get(context.Background(), cacheKey)
+		return err == nil
+	}, 100*time.Millisecond, 10*time.Millisecond, "cache should not be written when context is already cancelled")
+}
+
+func TestDrainNodeSkipsDaemonSetPods(t *testing.T) {
+	podDaemonset := newDrainTestPod("default", "pod-daemonset")
+	podDaemonset.OwnerReferences = []metav1.OwnerReference{
+		{
+			Kind:       "DaemonSet",
+			Name:       "my-daemonset",
+			APIVersion: "apps/v1",
+			Controller: func() *bool { b := true; return &b }(),
+		},
+	}
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-node",
+		},
+	}
+
+	fakeClient := fake.NewClientset(node, podDaemonset)
+
+	var evicted atomic.Bool
+
+	fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		if action.GetSubresource() == "eviction" {
+			evicted.Store(true)
+		}
+
+		return false, nil, nil
+	})
+
+	testCache := cache.New[interface{}]()
+	c := &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			Cache: testCache,
+		},
+	}
+
+	c.drainNode(context.Background(), fakeClient, "test-node", "test-cluster", nodeDrainOptions{})
+
+	result := waitForDrainResult(t, testCache)
+
+	assert.Equal(t, drainStatusSuccess, result.Status)
+	assert.False(t, evicted.Load(), "DaemonSet pod should not be evicted during drain")
+}
+
+func TestNodeDrainOptionsValidate(t *testing.T) {
+	negative := int64(-1)
+
+	tests := []struct {
+		name    string
+		opts    nodeDrainOptions
+		wantErr string
+		timeout time.Duration
+	}{
+		{name: "defaults", opts: nodeDrainOptions{}},
+		{
+			name:    "selector and timeout",
+			opts:    nodeDrainOptions{PodSelector: "app=web", Timeout: "90s"},
+			timeout: 90 * time.Second,
+		},
+		{name: "invalid selector", opts: nodeDrainOptions{PodSelector: "app in (web"}, wantErr: "invalid podSelector"},
+		{name: "negative grace period", opts: nodeDrainOptions{GracePeriodSeconds: &negative}, wantErr: "gracePeriodSeconds"},
+		{name: "invalid timeout", opts: nodeDrainOptions{Timeout: "soon"}, wantErr: "invalid timeout"},
+		{name: "zero timeout", opts: nodeDrainOptions{Timeout: "0s"}, wantErr: "timeout must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.timeout, tt.opts.timeout)
+		})
+	}
+}
+
+func TestSelectDrainPods(t *testing.T) { //nolint:funlen
+	mirror := newDrainTestPod("kube-system", "mirror")
+	mirror.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "hash"}
+
+	daemon := newDrainTestPod("default", "daemon")
+	daemon.OwnerReferences[0].Kind = "DaemonSet"
+
+	unmanaged := newDrainTestPod("default", "unmanaged")
+	unmanaged.OwnerReferences = nil
+
+	finished := newDrainTestPod("default", "finished")
+	finished.OwnerReferences = nil
+	finished.Status.Phase = corev1.PodSucceeded
+
+	scratch := newDrainTestPod("default", "scratch")
+	scratch.Spec.Volumes = []corev1.Volume{
+		{Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+	}
+
+	// A finished DaemonSet pod is filtered like the running ones.
+	finishedDaemon := newDrainTestPod("default", "finished-daemon")
+	finishedDaemon.OwnerReferences[0].Kind = "DaemonSet"
+	finishedDaemon.Status.Phase = corev1.PodFailed
+
+	managed := newDrainTestPod("default", "managed")
+	pods := []corev1.Pod{*mirror, *daemon, *unmanaged, *finished, *finishedDaemon, *scratch, *managed}
+
+	noDaemonSets := false
+
+	tests := []struct {
+		name      string
+		opts      nodeDrainOptions
+		wantEvict []string
+		wantError []string
+		warnings  int
+	}{
+		{
+			name:      "defaults",
+			opts:      nodeDrainOptions{},
+			wantEvict: []string{"finished", "managed"},
+			wantError: []string{"unmanaged", "scratch"},
+			warnings:  2,
+		},
+		{
+			name:      "force and deleteEmptyDirData",
+			opts:      nodeDrainOptions{Force: true, DeleteEmptyDirData: true},
+			wantEvict: []string{"unmanaged", "finished", "scratch", "managed"},
+			warnings:  4,
+		},
+		{
+			name:      "DaemonSets not ignored",
+			opts:      nodeDrainOptions{IgnoreDaemonSets: &noDaemonSets, Force: true, DeleteEmptyDirData: true},
+			wantEvict: []string{"unmanaged", "finished", "scratch", "managed"},
+			wantError: []string{"daemon", "finished-daemon"},
+			warnings:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection := selectDrainPods(pods, tt.opts)
+
+			evict := []string{}
+			for _, pod := range selection.Evict {
+				evict = append(evict, pod.Name)
+			}
+
+			errored := []string{}
+			for _, failed := range selection.Errors {
+				errored = append(errored, strings.TrimPrefix(failed.Pod, "default/"))
+			}
+
+			assert.Equal(t, tt.wantEvict, evict)
+			assert.ElementsMatch(t, tt.wantError, errored)
+			assert.Len(t, selection.Warnings, tt.warnings)
+		})
+	}
+}
+
+func TestDrainNodeRefusesPodsNeedingOptions(t *testing.T) {
+	podManaged := newDrainTestPod("default", "pod-managed")
+	podUnmanaged := newDrainTestPod("default", "pod-unmanaged")
+	podUnmanaged.OwnerReferences = nil
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-node",
+		},
+	}
+
+	fakeClient := fake.NewClientset(node, podManaged, podUnmanaged)
+
+	var evictions atomic.Int32
+
+	fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		if action.GetSubresource() == "eviction" {
+			evictions.Add(1)
+		}
+
+		return false, nil, nil
+	})
+
+	testCache := cache.New[interface{}]()
+	c := &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			Cache: testCache,
+		},
+	}
+
+	c.drainNode(context.Background(), fakeClient, "test-node", "test-cluster", nodeDrainOptions{})
+
+	result := waitForDrainResult(t, testCache)
+
+	assert.Equal(t, "error: cannot evict 1 pod(s) without additional drain options", result.Status)
+	require.Len(t, result.Failed, 1)
+	assert.Equal(t, "default/pod-unmanaged", result.Failed[0].Pod)
+	assert.Contains(t, result.Failed[0].Error, "use force")
+	assert.Zero(t, evictions.Load(), "no pod should be evicted when the drain is refused")
+}
+
+func TestDrainNodeAppliesPodSelectorAndGracePeriod(t *testing.T) {
+	useFastDrainPolling(t)
+
+	podWeb := newDrainTestPod("default", "pod-web")
+	podWeb.Labels = map[string]string{"app": "web"}
+	podDB := newDrainTestPod("default", "pod-db")
+	podDB.Labels = map[string]string{"app": "db"}
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-node",
+		},
+	}
+
+	fakeClient := fake.NewClientset(node, podWeb, podDB)
+	evictionDeletesPods(fakeClient)
+
+	var evictions []*policyv1.Eviction
+
+	fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		if eviction, ok := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction); ok {
+			evictions = append(evictions, eviction)
+		}
+
+		return false, nil, nil
+	})
+
+	testCache := cache.New[interface{}]()
+	c := &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			Cache: testCache,
+		},
+	}
+
+	gracePeriod := int64(5)
+	c.drainNode(context.Background(), fakeClient, "test-node", "test-cluster", nodeDrainOptions{
+		PodSelector:        "app=web",
+		GracePeriodSeconds: &gracePeriod,
+	})
+
+	result := waitForDrainResult(t, testCache)
+
+	assert.Equal(t, drainStatusSuccess, result.Status)
+	assert.Equal(t, []string{"default/pod-web"}, result.Evicted)
+	require.Len(t, evictions, 1)
+	require.NotNil(t, evictions[0].DeleteOptions)
+	assert.Equal(t, &gracePeriod, evictions[0].DeleteOptions.GracePeriodSeconds)
+}
+
+func TestHandleNodeDrainDryRunDoesNotCordonOrEvict(t *testing.T) { //nolint:funlen
+	const nodeName = "node-a"
+
+	var writes atomic.Int32
+
+	kubeAPI := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != http.MethodGet {
+			writes.Add(1)
+		}
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/pods":
+			unmanaged := newDrainTestPod("default", "pod-unmanaged")
+			unmanaged.OwnerReferences = nil
+
+			_ = json.NewEncoder(w).Encode(&corev1.PodList{
+				Items: []corev1.Pod{*newDrainTestPod("default", "pod-managed"), *unmanaged},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(kubeAPI.Close)
+
+	kubeConfigStore := kubeconfig.NewContextStore()
+	err := kubeConfigStore.AddContext(&kubeconfig.Context{
+		Name:        "test-cluster",
+		KubeContext: &api.Context{Cluster: "test-cluster", AuthInfo: "test-cluster"},
+		Cluster: &api.Cluster{
+			Server:                kubeAPI.URL,
+			InsecureSkipTLSVerify: true,
+		},
+		AuthInfo: &api.AuthInfo{},
+	})
+	require.NoError(t, err)
+
+	cfg := &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			HeadlampCFG: &headlampconfig.HeadlampCFG{
+				KubeConfigStore: kubeConfigStore,
+			},
+			Cache:            cache.New[interface{}](),
+			TelemetryConfig:  GetDefaultTestTelemetryConfig(),
+			TelemetryHandler: &telemetry.RequestHandler{},
+		},
+	}
+
+	req, err := makeJSONReq(http.MethodPost, "/drain-node", map[string]interface{}{
+		"cluster":  "test-cluster",
+		"nodeName": nodeName,
+		"dryRun":   true,
+	})
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	cfg.handleNodeDrain(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response struct {
+		DryRun bool             `json:"dryRun"`
+		Pods   []string         `json:"pods"`
+		Errors []drainFailedPod `json:"errors"`
+	}
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.True(t, response.DryRun)
+	assert.Equal(t, []string{"default/pod-managed"}, response.Pods)
+	require.Len(t, response.Errors, 1)
+	assert.Equal(t, "default/pod-unmanaged", response.Errors[0].Pod)
+	assert.Zero(t, writes.Load(), "a dry run must not modify the cluster")
+}
+
+func TestHandleNodeDrainRejectsInvalidOptions(t *testing.T) {
+	cfg := &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			HeadlampCFG:      &headlampconfig.HeadlampCFG{KubeConfigStore: kubeconfig.NewContextStore()},
+			Cache:            cache.New[interface{}](),
+			TelemetryConfig:  GetDefaultTestTelemetryConfig(),
+			TelemetryHandler: &telemetry.RequestHandler{},
+		},
+	}
+
+	req, err := makeJSONReq(http.MethodPost, "/drain-node", map[string]interface{}{
+		"cluster":  "test-cluster",
+		"nodeName": "node-a",
+		"timeout":  "forever",
+	})
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	cfg.handleNodeDrain(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -44,7 +44,6 @@ import (
 
 	oidc "github.com/coreos/go-oidc/v3/oidc"
 	"github.com/gobwas/glob"
-	"github.com/google/uuid"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	auth "github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
@@ -64,8 +63,6 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
-	corev1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation"
@@ -82,6 +79,17 @@ type HeadlampConfig struct {
 	proxyURLMu        sync.Mutex
 	compiledProxyURLs []glob.Glob
 	oidcStateReader   io.Reader
+
+	helmCredentialStoreMu       sync.Mutex
+	helmCredentialStore         helm.RepositoryCredentialStore
+	helmRegistryCredentialStore helm.RepositoryCredentialStore
+	// helmRepoRefresher is set before the server starts, if enabled.
+	helmRepoRefresher *helm.RepositoryRefresher
+	// helmActionHistory is set before the server starts, if enabled.
+	helmActionHistory helm.ActionHistory
+	// portForwardDefinitions is set before the server starts, if port
+	// forwards are saved across restarts.
+	portForwardDefinitions *portforward.DefinitionStore
 }
 
 func compileProxyURLPatterns(patterns []string) ([]glob.Glob, error) {
@@ -148,6 +156,12 @@ const (
 	serverIdleTimeout = 120 * time.Second
 	// serviceAccountNamespacePath contains the namespace of an in-cluster pod.
 	serviceAccountNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+	// helmRepositoryCredentialsSecret is the Secret in the namespace of an
+	// in-cluster pod that keeps the credentials of Helm repositories.
+	helmRepositoryCredentialsSecret = "headlamp-helm-repository-credentials"
+	// helmRegistryCredentialsSecret is the Secret in the namespace of an
+	// in-cluster pod that keeps the logins of OCI registries.
+	helmRegistryCredentialsSecret = "headlamp-helm-registry-credentials"
 )
 
 // maxProxyResponseSize is the maximum size (in bytes) for proxied responses.
@@ -522,6 +536,149 @@ func validateServiceAccountNamespace(data []byte) (string, error) {
 	return namespace, nil
 }
 
+// newHelmHandler returns a helm handler configured for the server: local
+// chart directories are only allowed for the desktop app, and repository credentials and
+// registry logins are kept in Secrets in-cluster.
+func (c *HeadlampConfig) newHelmHandler() (*helm.Handler, error) {
+	helmHandler, err := helm.NewHandler(c.Cache)
+	if err != nil {
+		return nil, err
+	}
+
+	helmHandler.AllowLocalCharts = c.isDesktopApp()
+	helmHandler.Refresher = c.helmRepoRefresher
+	helmHandler.History = c.helmActionHistory
+	helmHandler.PostRenderers = c.HelmPostRenderers
+	helmHandler.Keyring = c.HelmKeyring
+	helmHandler.VerifyCharts = c.HelmVerifyCharts
+
+	if c.UseInCluster {
+		helmHandler.CredentialStore, helmHandler.RegistryCredentialStore, err = c.helmCredentialStores()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return helmHandler, nil
+}
+
+// startHelmRepoRefresher refreshes the Helm repository indexes in the
+// background every HelmRepoRefreshInterval, if Helm is enabled.
+func startHelmRepoRefresher(ctx context.Context, config *HeadlampConfig) {
+	if !config.EnableHelm || config.HelmRepoRefreshInterval <= 0 {
+		return
+	}
+
+	helmHandler, err := config.newHelmHandler()
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "failed to start helm repository refresher")
+		return
+	}
+
+	config.helmRepoRefresher = helm.NewRepositoryRefresher(helmHandler, config.HelmRepoRefreshInterval)
+
+	go config.helmRepoRefresher.Run(ctx)
+}
+
+// setupHelmActionHistory sets the file Helm actions are recorded in, if Helm
+// is enabled. Without a configured file, the desktop app records them in the
+// Headlamp config dir, and in-cluster they aren't recorded.
+func setupHelmActionHistory(config *HeadlampConfig) {
+	if !config.EnableHelm {
+		return
+	}
+
+	path := config.HelmActionHistoryFile
+	if path == "" {
+		if config.UseInCluster {
+			return
+		}
+
+		var err error
+
+		path, err = cfg.DefaultHelmActionHistoryFile()
+		if err != nil {
+			logger.Log(logger.LevelError, nil, err, "failed to set up helm action history")
+			return
+		}
+	}
+
+	config.helmActionHistory = helm.NewFileActionHistory(path)
+}
+
+// restorePortForwards sets the file port forwards are saved in and restores
+// the saved ones. Without a configured file, the desktop app saves them in the
+// Headlamp config dir. In-cluster they aren't saved, as they would be shared
+// between users, so importing and exporting them is refused too.
+func restorePortForwards(config *HeadlampConfig) {
+	if config.UseInCluster {
+		return
+	}
+
+	path := config.PortForwardsFile
+	if path == "" {
+		var err error
+
+		path, err = cfg.DefaultPortForwardsFile()
+		if err != nil {
+			logger.Log(logger.LevelError, nil, err, "failed to set up port forward persistence")
+			return
+		}
+	}
+
+	config.portForwardDefinitions = portforward.NewDefinitionStore(path)
+
+	err := portforward.RestorePortForwards(
+		config.KubeConfigStore, config.Cache, config.portForwardDefinitions, config.Metrics,
+	)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{"file": path}, err, "failed to restore port forwards")
+	}
+}
+
+// isDesktopApp returns whether the server runs for the desktop app, which
+// launches it with a backend token, rather than for a browser.
+func (c *HeadlampConfig) isDesktopApp() bool {
+	return !c.UseInCluster && os.Getenv("HEADLAMP_BACKEND_TOKEN") != ""
+}
+
+// helmCredentialStores returns the stores that keep Helm repository
+// credentials out of repositories.yaml and registry logins out of the registry
+// config file when running in-cluster, Secrets in the namespace of the pod.
+func (c *HeadlampConfig) helmCredentialStores() (
+	repositories, registries helm.RepositoryCredentialStore,
+	err error,
+) {
+	c.helmCredentialStoreMu.Lock()
+	defer c.helmCredentialStoreMu.Unlock()
+
+	if c.helmCredentialStore != nil {
+		return c.helmCredentialStore, c.helmRegistryCredentialStore, nil
+	}
+
+	var restConfig *rest.Config
+
+	restConfig, err = rest.InClusterConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("get in-cluster config for helm credentials: %w", err)
+	}
+
+	namespace, err := readServiceAccountNamespace()
+	if err != nil {
+		return nil, nil, fmt.Errorf("get pod namespace for helm credentials: %w", err)
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create client for helm credentials: %w", err)
+	}
+
+	c.helmCredentialStore = helm.NewSecretCredentialStore(client, namespace, helmRepositoryCredentialsSecret)
+	c.helmRegistryCredentialStore = helm.NewSecretCredentialStore(client, namespace, helmRegistryCredentialsSecret)
+
+	return c.helmCredentialStore, c.helmRegistryCredentialStore, nil
+}
+
 func startClusterInventory(ctx context.Context, config *HeadlampConfig) error {
 	if !config.EnableClusterInventory {
 		return nil
@@ -771,6 +928,8 @@ func createHeadlampHandler(ctx context.Context, config *HeadlampConfig) http.Han
 			portforward.StartPortForward(
 				config.KubeConfigStore,
 				config.Cache,
+				config.portForwardDefinitions,
+				config.Metrics,
 				config.shouldUseUnsafeServiceAccountToken(),
 				contextKey,
 				w,
@@ -788,7 +947,7 @@ func createHeadlampHandler(ctx context.Context, config *HeadlampConfig) http.Han
 				return
 			}
 
-			portforward.StopOrDeletePortForward(config.Cache, contextKey, w, r)
+			portforward.StopOrDeletePortForward(config.Cache, config.portForwardDefinitions, contextKey, w, r)
 		})),
 	).Methods("DELETE")
 
@@ -817,6 +976,24 @@ func createHeadlampHandler(ctx context.Context, config *HeadlampConfig) http.Han
 		})),
 	).Methods("GET")
 
+	// Export and import the saved port forwards of all clusters. In-cluster,
+	// port forwards aren't saved, so both are refused.
+	r.Handle(
+		"/portforwards/export",
+		auth.NewBackendTokenMiddleware(config.UseInCluster)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			portforward.ExportPortForwards(config.portForwardDefinitions, w, r)
+		})),
+	).Methods("GET")
+
+	r.Handle(
+		"/portforwards/import",
+		auth.NewBackendTokenMiddleware(config.UseInCluster)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			portforward.ImportPortForwards(
+				config.KubeConfigStore, config.Cache, config.portForwardDefinitions, config.Metrics, w, r,
+			)
+		})),
+	).Methods("POST")
+
 	// Expose user info so the frontend can show the current user in the top bar using the per-cluster auth cookie.
 	r.Handle("/clusters/{clusterName}/me", auth.NewBackendTokenMiddleware(config.UseInCluster)(
 		auth.HandleMe(auth.MeHandlerOptions{
@@ -1131,6 +1308,18 @@ func createHeadlampHandler(ctx context.Context, config *HeadlampConfig) http.Han
 	r.Handle("/drain-node-status",
 		auth.NewBackendTokenMiddleware(config.UseInCluster)(http.HandlerFunc(
 			config.handleNodeDrainStatus))).Methods("GET").Queries("cluster", "{cluster}", "nodeName", "{node}")
+	r.Handle("/drain-node-jobs/{id}",
+		auth.NewBackendTokenMiddleware(config.UseInCluster)(http.HandlerFunc(config.handleDrainJob))).Methods("GET")
+	r.Handle("/drain-node-jobs/{id}/events",
+		auth.NewBackendTokenMiddleware(config.UseInCluster)(http.HandlerFunc(config.handleDrainJobEvents))).Methods("GET")
+	r.Handle("/drain-nodes",
+		auth.NewBackendTokenMiddleware(config.UseInCluster)(http.HandlerFunc(config.handleNodeBatchDrain))).Methods("POST")
+	r.Handle("/drain-node-batches/{id}",
+		auth.NewBackendTokenMiddleware(config.UseInCluster)(http.HandlerFunc(config.handleDrainBatch))).Methods("GET")
+	r.Handle("/cordon-node",
+		auth.NewBackendTokenMiddleware(config.UseInCluster)(http.HandlerFunc(config.handleNodeCordon))).Methods("POST")
+	r.Handle("/uncordon-node",
+		auth.NewBackendTokenMiddleware(config.UseInCluster)(http.HandlerFunc(config.handleNodeUncordon))).Methods("POST")
 
 	r.HandleFunc("/oidc-callback", func(w http.ResponseWriter, r *http.Request) {
 		// Shadow createHeadlampHandler's outer-scope err so any log call in
@@ -1518,6 +1707,10 @@ func serverHandler(ctx context.Context, config *HeadlampConfig) (http.Handler, e
 		return nil, err
 	}
 
+	setupHelmActionHistory(config)
+	startHelmRepoRefresher(ctx, config)
+	restorePortForwards(config)
+
 	handler = config.OIDCTokenRefreshMiddleware(handler)
 
 	// Only validate the Host header when listening on a loopback address.
@@ -1702,7 +1895,7 @@ func getHelmHandler(c *HeadlampConfig, w http.ResponseWriter, r *http.Request) (
 	clusterName := mux.Vars(r)["clusterName"]
 	telemetry.AddSpanAttributes(ctx, attribute.String("clusterName", clusterName))
 
-	helmHandler, err := helm.NewHandler(c.Cache)
+	helmHandler, err := c.newHelmHandler()
 	if err != nil {
 		logger.Log(logger.LevelError, map[string]string{"clusterName": clusterName},
 			err, "failed to create helm handler")
@@ -1714,6 +1907,8 @@ func getHelmHandler(c *HeadlampConfig, w http.ResponseWriter, r *http.Request) (
 		return nil, err
 	}
 
+	helmHandler.Cluster = clusterName
+
 	c.TelemetryHandler.RecordDuration(ctx, start, attribute.String("status", "success"))
 	c.TelemetryHandler.RecordEvent(span, "Successfully created helm handler")
 
@@ -1756,6 +1951,72 @@ func handleClusterHelm(c *HeadlampConfig, router *mux.Router) {
 		})))
 }
 
+// handleHelmReleaseInventory registers the route listing the Helm releases of
+// several clusters at once.
+func handleHelmReleaseInventory(c *HeadlampConfig, router *mux.Router) {
+	router.Handle("/helm/releases/inventory",
+		auth.NewBackendTokenMiddleware(c.UseInCluster)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			_, span := telemetry.CreateSpan(ctx, r, "helm", "handleHelmReleaseInventory")
+			defer span.End()
+
+			c.TelemetryHandler.RecordRequestCount(ctx, r)
+
+			helmHandler, err := getHelmHandler(c, w, r)
+			if err != nil {
+				c.handleError(w, ctx, span, err, "failed to get helm handler", http.StatusForbidden)
+				return
+			}
+
+			clusters, err := c.helmInventoryClusters(r)
+			if err != nil {
+				c.handleError(w, ctx, span, err, "failed to get contexts", http.StatusInternalServerError)
+				return
+			}
+
+			helmHandler.ListReleaseInventory(clusters, w, r)
+		}))).Methods("GET")
+}
+
+// helmInventoryClusters returns the clusters of the Helm release inventory,
+// authenticated with the token of their cookie. Dynamic clusters are left out
+// like in the cluster list.
+func (c *HeadlampConfig) helmInventoryClusters(r *http.Request) ([]helm.InventoryCluster, error) {
+	contexts, err := c.KubeConfigStore.GetContexts()
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := make([]helm.InventoryCluster, 0, len(contexts))
+
+	for _, kContext := range contexts {
+		if kContext.Internal {
+			continue
+		}
+
+		if kContext.Error != "" {
+			clusters = append(clusters, helm.InventoryCluster{Name: kContext.Name, Err: errors.New(kContext.Error)})
+			continue
+		}
+
+		// Create a copy of the context to avoid modifying the cached context
+		kContext = kContext.Copy()
+
+		if !c.shouldUseUnsafeServiceAccountTokenForContext(kContext) {
+			// The Authorization header of the request is for a single cluster,
+			// so only the cookie of each cluster is used.
+			clusterRequest := r.Clone(r.Context())
+			clusterRequest.Header.Del("Authorization")
+			applyRequestTokenToContext(clusterRequest, kContext.Name, kContext)
+		}
+
+		clusters = append(clusters, helm.InventoryCluster{Name: kContext.Name, ClientConfig: kContext.ClientConfig()})
+	}
+
+	return clusters, nil
+}
+
 func (c *HeadlampConfig) helmRouteReleaseHandler(
 	ctx context.Context,
 	span trace.Span,
@@ -1863,6 +2124,8 @@ func (c *HeadlampConfig) dispatchHelmRoute(
 		routeReleaseHandler("/releases/list", "ListRelease", helmHandler.ListRelease)
 	case strings.HasSuffix(path, "/release/install") && r.Method == http.MethodPost:
 		routeReleaseHandler("/release/install", "InstallRelease", helmHandler.InstallRelease)
+	case strings.HasSuffix(path, "/release/install/chart") && r.Method == http.MethodPost:
+		routeReleaseHandler("/release/install/chart", "InstallLocalChart", helmHandler.InstallLocalChart)
 	case strings.HasSuffix(path, "/release/history") && r.Method == http.MethodGet:
 		routeReleaseHandler("/release/history", "GetReleaseHistory", helmHandler.GetReleaseHistory)
 	case strings.HasSuffix(path, "/releases/uninstall") && r.Method == http.MethodDelete:
@@ -1871,6 +2134,22 @@ func (c *HeadlampConfig) dispatchHelmRoute(
 		routeReleaseHandler("/releases/rollback", "RollbackRelease", helmHandler.RollbackRelease)
 	case strings.HasSuffix(path, "/releases/upgrade") && r.Method == http.MethodPut:
 		routeReleaseHandler("/releases/upgrade", "UpgradeRelease", helmHandler.UpgradeRelease)
+	case strings.HasSuffix(path, "/releases/upgrade/chart") && r.Method == http.MethodPut:
+		routeReleaseHandler("/releases/upgrade/chart", "UpgradeLocalChart", helmHandler.UpgradeLocalChart)
+	case strings.HasSuffix(path, "/releases/test") && r.Method == http.MethodPost:
+		routeReleaseHandler("/releases/test", "TestRelease", helmHandler.TestRelease)
+	case strings.HasSuffix(path, "/releases/diff") && r.Method == http.MethodPost:
+		routeReleaseHandler("/releases/diff", "DiffRelease", helmHandler.DiffRelease)
+	case strings.HasSuffix(path, "/releases/manifest") && r.Method == http.MethodGet:
+		routeReleaseHandler("/releases/manifest", "GetReleaseManifest", helmHandler.GetReleaseManifest)
+	case strings.HasSuffix(path, "/releases/values") && r.Method == http.MethodGet:
+		routeReleaseHandler("/releases/values", "GetReleaseValues", helmHandler.GetReleaseValues)
+	case strings.HasSuffix(path, "/releases/notes") && r.Method == http.MethodGet:
+		routeReleaseHandler("/releases/notes", "GetReleaseNotes", helmHandler.GetReleaseNotes)
+	case strings.HasSuffix(path, "/releases/hooks") && r.Method == http.MethodGet:
+		routeReleaseHandler("/releases/hooks", "GetReleaseHooks", helmHandler.GetReleaseHooks)
+	case strings.HasSuffix(path, "/releases/drift") && r.Method == http.MethodGet:
+		routeReleaseHandler("/releases/drift", "GetReleaseDrift", helmHandler.GetReleaseDrift)
 	case strings.HasSuffix(path, "/releases") && r.Method == http.MethodGet:
 		routeReleaseHandler("/releases", "GetRelease", helmHandler.GetRelease)
 	case strings.HasSuffix(path, "/repositories") && r.Method == http.MethodGet:
@@ -1883,6 +2162,28 @@ func (c *HeadlampConfig) dispatchHelmRoute(
 		routeRepositoryHandler("/repositories/update", "UpdateRepository", helmHandler.UpdateRepository)
 	case strings.HasSuffix(path, "/charts") && r.Method == http.MethodGet:
 		routeRepositoryHandler("/charts", "ListCharts", helmHandler.ListCharts)
+	case strings.HasSuffix(path, "/charts/tags") && r.Method == http.MethodGet:
+		routeRepositoryHandler("/charts/tags", "ListChartTags", helmHandler.ListChartTags)
+	case strings.HasSuffix(path, "/charts/versions") && r.Method == http.MethodGet:
+		routeRepositoryHandler("/charts/versions", "ListChartVersions", helmHandler.ListChartVersions)
+	case strings.HasSuffix(path, "/charts/readme") && r.Method == http.MethodGet:
+		routeRepositoryHandler("/charts/readme", "GetChartReadme", helmHandler.GetChartReadme)
+	case strings.HasSuffix(path, "/charts/values") && r.Method == http.MethodGet:
+		routeRepositoryHandler("/charts/values", "GetChartValues", helmHandler.GetChartValues)
+	case strings.HasSuffix(path, "/charts/values-schema") && r.Method == http.MethodGet:
+		routeRepositoryHandler("/charts/values-schema", "GetChartValuesSchema", helmHandler.GetChartValuesSchema)
+	case strings.HasSuffix(path, "/charts/values/validate") && r.Method == http.MethodPost:
+		routeRepositoryHandler("/charts/values/validate", "ValidateValues", helmHandler.ValidateValues)
+	case strings.HasSuffix(path, "/charts/metadata") && r.Method == http.MethodGet:
+		routeRepositoryHandler("/charts/metadata", "GetChartMetadata", helmHandler.GetChartMetadata)
+	case strings.HasSuffix(path, "/registries") && r.Method == http.MethodGet:
+		routeRepositoryHandler("/registries", "ListRegistries", helmHandler.ListRegistries)
+	case strings.HasSuffix(path, "/registries/login") && r.Method == http.MethodPost:
+		routeRepositoryHandler("/registries/login", "RegistryLogin", helmHandler.RegistryLogin)
+	case strings.HasSuffix(path, "/registries/logout") && r.Method == http.MethodPost:
+		routeRepositoryHandler("/registries/logout", "RegistryLogout", helmHandler.RegistryLogout)
+	case strings.HasSuffix(path, "/actions/history") && r.Method == http.MethodGet:
+		routeReleaseHandler("/actions/history", "GetActionHistory", helmHandler.GetActionHistory)
 	case strings.HasSuffix(path, "/action/status") && r.Method == http.MethodGet:
 		routeReleaseHandler("/action/status", "GetActionStatus", helmHandler.GetActionStatus)
 	default:
@@ -2109,6 +2410,7 @@ func processTokenProtocol(r *http.Request, protocol, tokenPrefix string) {
 func (c *HeadlampConfig) handleClusterRequests(router *mux.Router) {
 	if c.EnableHelm {
 		handleClusterHelm(c, router)
+		handleHelmReleaseInventory(c, router)
 	}
 
 	handleClusterServiceProxy(c, router)
@@ -2920,257 +3222,6 @@ func (c *HeadlampConfig) addClusterSetupRoute(r *mux.Router) {
 		auth.NewBackendTokenMiddleware(c.UseInCluster)(http.HandlerFunc(c.renameCluster))).Methods("PUT")
 }
 
-/*
-This function is used to handle the node drain request.
-*/
-func (c *HeadlampConfig) handleNodeDrain(w http.ResponseWriter, r *http.Request) { //nolint:funlen
-	ctx := r.Context()
-	_, span := telemetry.CreateSpan(ctx, r, "node-management", "handleNodeDrain")
-	c.TelemetryHandler.RecordRequestCount(ctx, r)
-	c.TelemetryHandler.RecordEvent(span, "node drain request started")
-
-	defer span.End()
-
-	var drainPayload struct {
-		Cluster  string `json:"cluster"`
-		NodeName string `json:"nodeName"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&drainPayload); err != nil {
-		c.handleError(w, ctx, span, err, "decoding payload", http.StatusBadRequest)
-
-		return
-	}
-
-	if drainPayload.NodeName == "" {
-		c.handleError(w, ctx, span, errors.New("nodeName not found"), "missing nodeName", http.StatusBadRequest)
-		return
-	}
-
-	if drainPayload.Cluster == "" {
-		c.handleError(w, ctx, span, errors.New("clusterName not found"), "missing clusterName", http.StatusBadRequest)
-
-		return
-	}
-
-	ctxtProxy, err := c.KubeConfigStore.GetContext(drainPayload.Cluster)
-	if err != nil {
-		c.handleError(w, ctx, span, err, "Cluster not found", http.StatusNotFound)
-
-		return
-	}
-
-	token := c.requestTokenForContext(r, drainPayload.Cluster, ctxtProxy)
-
-	clientset, err := ctxtProxy.ClientSetWithToken(token)
-	if err != nil {
-		c.handleError(w, ctx, span, err, "getting client", http.StatusInternalServerError)
-
-		return
-	}
-
-	var responsePayload struct {
-		Message string `json:"message"`
-		Cluster string `json:"cluster"`
-	}
-
-	responsePayload.Cluster = drainPayload.Cluster
-	responsePayload.Message = "Drain node request submitted successfully"
-
-	if err = json.NewEncoder(w).Encode(responsePayload); err != nil {
-		c.handleError(w, ctx, span, err, "writing response", http.StatusInternalServerError)
-
-		return
-	}
-
-	serverCtx := c.ServerCtx
-	if serverCtx == nil {
-		serverCtx = context.Background()
-	}
-
-	c.drainNode(serverCtx, clientset, drainPayload.NodeName, drainPayload.Cluster)
-}
-
-func (c *HeadlampConfig) drainNode(
-	ctx context.Context,
-	clientset kubernetes.Interface,
-	nodeName string,
-	cluster string,
-) {
-	go func() {
-		if ctx.Err() != nil {
-			return
-		}
-
-		nodeClient := clientset.CoreV1().Nodes()
-		cacheKey := uuid.NewSHA1(uuid.Nil, []byte(nodeName+"\x00"+cluster)).String()
-		cacheItemTTL := DrainNodeCacheTTL * time.Second
-
-		node, err := nodeClient.Get(ctx, nodeName, v1.GetOptions{})
-		if err != nil {
-			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-				return
-			}
-
-			_ = c.Cache.SetWithTTL(ctx, cacheKey, "error: "+err.Error(), cacheItemTTL)
-
-			return
-		}
-
-		// cordon the node first
-		node.Spec.Unschedulable = true
-
-		_, err = nodeClient.Update(ctx, node, v1.UpdateOptions{})
-		if err != nil {
-			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-				return
-			}
-
-			_ = c.Cache.SetWithTTL(ctx, cacheKey, "error: "+err.Error(), cacheItemTTL)
-
-			return
-		}
-
-		pods, err := clientset.CoreV1().Pods("").List(ctx,
-			v1.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
-		if err != nil {
-			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-				return
-			}
-
-			_ = c.Cache.SetWithTTL(ctx, cacheKey, "error: "+err.Error(), cacheItemTTL)
-
-			return
-		}
-
-		c.drainNodePods(ctx, clientset, pods.Items, cacheKey, cacheItemTTL)
-	}()
-}
-
-func (c *HeadlampConfig) drainNodePods(
-	ctx context.Context,
-	clientset kubernetes.Interface,
-	pods []corev1.Pod,
-	cacheKey string,
-	cacheItemTTL time.Duration,
-) {
-	var gracePeriod int64 = 0
-
-	var deleteErrors []string
-
-	for i := range pods {
-		if ctx.Err() != nil {
-			return
-		}
-
-		pod := &pods[i]
-
-		// ignore daemonsets
-		if isDaemonSetPod(pod) {
-			continue
-		}
-
-		if err := clientset.CoreV1().Pods(pod.Namespace).Delete(ctx,
-			pod.Name, v1.DeleteOptions{GracePeriodSeconds: &gracePeriod}); err != nil &&
-			!apierrors.IsNotFound(err) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
-			deleteErrors = append(deleteErrors, fmt.Sprintf("%s/%s: %v", pod.Namespace, pod.Name, err))
-		}
-	}
-
-	if ctx.Err() != nil {
-		return
-	}
-
-	if len(deleteErrors) > 0 {
-		errMsg := fmt.Sprintf("error: failed to delete %d pod(s)", len(deleteErrors))
-		logger.Log(logger.LevelError, nil, nil,
-			fmt.Sprintf("node drain: failed to delete %d pod(s): %s",
-				len(deleteErrors), strings.Join(deleteErrors, "; ")))
-
-		_ = c.Cache.SetWithTTL(ctx, cacheKey, errMsg, cacheItemTTL)
-	} else {
-		_ = c.Cache.SetWithTTL(ctx, cacheKey, "success", cacheItemTTL)
-	}
-}
-
-func isDaemonSetPod(pod *corev1.Pod) bool {
-	controllerRef := v1.GetControllerOf(pod)
-	if controllerRef == nil {
-		return false
-	}
-
-	return controllerRef.Kind == "DaemonSet"
-}
-
-/*
-* Handle node drain status
-Since node drain is an async operation, we need to poll for the status of the drain operation
-This endpoint returns the status of the drain operation.
-*/
-func (c *HeadlampConfig) handleNodeDrainStatus(w http.ResponseWriter, r *http.Request) {
-	start := time.Now()
-	ctx := r.Context()
-
-	_, span := telemetry.CreateSpan(ctx, r, "node-management", "handleNodeDrainStatus",
-		attribute.String("cluster", r.URL.Query().Get("cluster")),
-		attribute.String("nodeName", r.URL.Query().Get("nodeName")),
-	)
-	c.TelemetryHandler.RecordEvent(span, "handleNodeDrainStatus request started")
-	c.TelemetryHandler.RecordRequestCount(ctx, r)
-
-	defer span.End()
-
-	// Parse query parameters
-	drainPayload := struct {
-		Cluster  string `json:"cluster"`
-		NodeName string `json:"nodeName"`
-	}{
-		Cluster:  r.URL.Query().Get("cluster"),
-		NodeName: r.URL.Query().Get("nodeName"),
-	}
-
-	if drainPayload.NodeName == "" {
-		c.handleError(w, ctx, span, errors.New("nodeName is required"), "nodeName is missing", http.StatusBadRequest)
-		return
-	}
-
-	if drainPayload.Cluster == "" {
-		c.handleError(w, ctx, span, errors.New("clusterName is required"), "clusterName is missing", http.StatusBadRequest)
-
-		return
-	}
-
-	cacheKey := uuid.NewSHA1(uuid.Nil, []byte(drainPayload.NodeName+"\x00"+drainPayload.Cluster)).String()
-
-	cacheItem, err := c.Cache.Get(ctx, cacheKey)
-	if err != nil {
-		c.handleError(w, ctx, span, err, "failed to get cache item", http.StatusNotFound)
-
-		return
-	}
-	// Prepare successful response
-	responsePayload := struct {
-		ID      string `json:"id"`
-		Cluster string `json:"cluster"`
-	}{
-		ID:      cacheItem.(string),
-		Cluster: drainPayload.Cluster,
-	}
-
-	c.TelemetryHandler.RecordEvent(span, "Drain status found", attribute.String("cache.key", cacheKey))
-
-	if err = json.NewEncoder(w).Encode(responsePayload); err != nil {
-		c.handleError(w, ctx, span, err, "failed to encode response", http.StatusInternalServerError)
-
-		return
-	}
-
-	c.TelemetryHandler.RecordDuration(ctx, start, attribute.String("api.route", "handleNodeDrainStatus"))
-	logger.Log(logger.LevelInfo,
-		map[string]string{logFieldDurationMs: fmt.Sprintf("%d", time.Since(start).Milliseconds())},
-		nil, "handleNodeDrainStatus completed")
-}
-
 // handlerSetToken sets the authentication token in a cookie.
 // If the token is an empty string, the cookie is cleared.
 func (c *HeadlampConfig) handleSetToken(w http.ResponseWriter, r *http.Request) {
@@ -37,7 +37,6 @@ import (
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"testing"
 	"time"
 
@@ -57,8 +56,6 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/client-go/kubernetes/fake"
-	k8stesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/clientcmd/api"
 )
@@ -1068,407 +1065,6 @@ func TestExternalProxyTimeout(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "context deadline exceeded")
 }
 
-func TestDrainAndCordonNode(t *testing.T) { //nolint:funlen
-	type test struct {
-		handler http.Handler
-	}
-
-	cache := cache.New[interface{}]()
-	kubeConfigStore := kubeconfig.NewContextStore()
-	kubeConfigPath := filepath.Join("headlamp_testdata", "kubeconfig")
-
-	tests := []test{
-		{
-			handler: createHeadlampHandler(context.Background(), &HeadlampConfig{
-				HeadlampConfig: &headlampconfig.HeadlampConfig{
-					HeadlampCFG: &headlampconfig.HeadlampCFG{
-						UseInCluster:    false,
-						KubeConfigPath:  kubeConfigPath,
-						KubeConfigStore: kubeConfigStore,
-					},
-					Cache:            cache,
-					TelemetryConfig:  GetDefaultTestTelemetryConfig(),
-					TelemetryHandler: &telemetry.RequestHandler{},
-				},
-			}),
-		},
-	}
-
-	var drainNodePayload struct {
-		Cluster  string `json:"cluster"`
-		NodeName string `json:"nodeName"`
-	}
-
-	for _, tc := range tests {
-		drainNodePayload.Cluster = minikubeName
-		drainNodePayload.NodeName = minikubeName
-
-		rr, err := getResponseFromRestrictedEndpoint(tc.handler, "POST", "/drain-node", drainNodePayload)
-		if err != nil {
-			t.Fatal(err)
-		}
-
-		if status := rr.Code; status != http.StatusOK {
-			t.Errorf("handler returned wrong status code: got %v want %v",
-				status, http.StatusOK)
-		}
-
-		cacheKey := uuid.NewSHA1(uuid.Nil, []byte(drainNodePayload.NodeName+"\x00"+drainNodePayload.Cluster)).String()
-		cacheItemTTL := DrainNodeCacheTTL * time.Second
-		ctx := context.Background()
-
-		err = cache.SetWithTTL(ctx, cacheKey, "success", cacheItemTTL)
-		if err != nil {
-			t.Fatal(err)
-		}
-
-		url := fmt.Sprintf(
-			"/drain-node-status?cluster=%s&nodeName=%s",
-			drainNodePayload.Cluster, drainNodePayload.NodeName,
-		)
-
-		rr, err = getResponseFromRestrictedEndpoint(tc.handler, "GET", url, nil)
-		if err != nil {
-			t.Fatal(err)
-		}
-
-		if status := rr.Code; status != http.StatusOK {
-			t.Errorf("handler returned wrong status code: got %v want %v",
-				status, http.StatusOK)
-		}
-	}
-}
-
-func TestDrainNodePodDeletionFailure(t *testing.T) { //nolint:funlen
-	podOk := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "pod-ok",
-			Namespace: "default",
-		},
-		Spec: corev1.PodSpec{
-			NodeName: "test-node",
-		},
-	}
-	podDaemonset := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "pod-daemonset",
-			Namespace: "default",
-			OwnerReferences: []metav1.OwnerReference{
-				{
-					Kind: "DaemonSet", Name: "my-daemonset", APIVersion: "apps/v1",
-					Controller: func() *bool { b := true; return &b }(),
-				},
-			},
-		},
-		Spec: corev1.PodSpec{
-			NodeName: "test-node",
-		},
-	}
-	podFail := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "pod-fail",
-			Namespace: "kube-system",
-		},
-		Spec: corev1.PodSpec{
-			NodeName: "test-node",
-		},
-	}
-
-	node := &corev1.Node{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "test-node",
-		},
-	}
-
-	fakeClient := fake.NewClientset(node, podOk, podDaemonset, podFail)
-
-	// Inject error for deleting pod-fail
-	fakeClient.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
-		deleteAction := action.(k8stesting.DeleteAction)
-		if deleteAction.GetName() == "pod-fail" {
-			return true, nil, fmt.Errorf("pod is protected by PodDisruptionBudget")
-		}
-
-		return false, nil, nil
-	})
-
-	testCache := cache.New[interface{}]()
-	c := &HeadlampConfig{
-		HeadlampConfig: &headlampconfig.HeadlampConfig{
-			Cache: testCache,
-		},
-	}
-
-	cacheKey := uuid.NewSHA1(uuid.Nil, []byte("test-node"+"\x00"+"test-cluster")).String()
-	ctx := context.Background()
-
-	c.drainNode(ctx, fakeClient, "test-node", "test-cluster")
-
-	require.Eventually(t, func() bool {
-		cacheItem, err := testCache.Get(ctx, cacheKey)
-		if err != nil {
-			return false
-		}
-
-		status, ok := cacheItem.(string)
-
-		return ok && strings.HasPrefix(status, "error:")
-	}, 5*time.Second, 50*time.Millisecond)
-
-	cacheItem, err := testCache.Get(ctx, cacheKey)
-	require.NoError(t, err)
-
-	status, ok := cacheItem.(string)
-	require.True(t, ok)
-
-	assert.True(t, strings.HasPrefix(status, "error:"),
-		"expected error status, got: %s", status)
-	assert.Contains(t, status, "failed to delete")
-}
-
-func TestDrainNodeAllPodsDeletedSuccessfully(t *testing.T) {
-	pod1 := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "pod-1",
-			Namespace: "default",
-		},
-		Spec: corev1.PodSpec{
-			NodeName: "test-node",
-		},
-	}
-
-	node := &corev1.Node{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "test-node",
-		},
-	}
-
-	fakeClient := fake.NewClientset(node, pod1)
-
-	testCache := cache.New[interface{}]()
-	c := &HeadlampConfig{
-		HeadlampConfig: &headlampconfig.HeadlampConfig{
-			Cache: testCache,
-		},
-	}
-
-	cacheKey := uuid.NewSHA1(uuid.Nil, []byte("test-node"+"\x00"+"test-cluster")).String()
-	ctx := context.Background()
-
-	c.drainNode(ctx, fakeClient, "test-node", "test-cluster")
-
-	require.Eventually(t, func() bool {
-		cacheItem, err := testCache.Get(ctx, cacheKey)
-		if err != nil {
-			return false
-		}
-
-		status, ok := cacheItem.(string)
-
-		return ok && status == "success"
-	}, 2*time.Second, 50*time.Millisecond)
-
-	cacheItem, err := testCache.Get(ctx, cacheKey)
-	require.NoError(t, err)
-
-	status, ok := cacheItem.(string)
-	require.True(t, ok)
-
-	assert.Equal(t, "success", status)
-}
-
-func TestHandleNodeDrainUsesRequestedClusterCookieForCustomNamedContext(t *testing.T) { //nolint:funlen
-	const (
-		originalCluster = "original-cluster"
-		customCluster   = "custom-cluster"
-		nodeName        = "node-a"
-		testToken       = "custom-cluster-token"
-	)
-
-	authHeaders := make(chan string, 3)
-	kubeAPI := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		select {
-		case authHeaders <- r.Header.Get("Authorization"):
-		default:
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-
-		switch {
-		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/nodes/"+nodeName:
-			_ = json.NewEncoder(w).Encode(&corev1.Node{
-				ObjectMeta: metav1.ObjectMeta{Name: nodeName},
-			})
-		case r.Method == http.MethodPut && r.URL.Path == "/api/v1/nodes/"+nodeName:
-			_ = json.NewEncoder(w).Encode(&corev1.Node{
-				ObjectMeta: metav1.ObjectMeta{Name: nodeName},
-			})
-		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/pods":
-			_ = json.NewEncoder(w).Encode(&corev1.PodList{})
-		default:
-			http.NotFound(w, r)
-		}
-	}))
-	t.Cleanup(kubeAPI.Close)
-
-	kubeConfigStore := kubeconfig.NewContextStore()
-	err := kubeConfigStore.AddContext(&kubeconfig.Context{
-		Name: originalCluster,
-		KubeContext: &api.Context{
-			Cluster:  originalCluster,
-			AuthInfo: originalCluster,
-			Extensions: map[string]k8sruntime.Object{
-				"headlamp_info": &kubeconfig.CustomObject{CustomName: customCluster},
-			},
-		},
-		Cluster: &api.Cluster{
-			Server:                kubeAPI.URL,
-			InsecureSkipTLSVerify: true,
-		},
-		AuthInfo: &api.AuthInfo{},
-		Source:   kubeconfig.InCluster,
-	})
-	require.NoError(t, err)
-
-	cfg := &HeadlampConfig{
-		HeadlampConfig: &headlampconfig.HeadlampConfig{
-			HeadlampCFG: &headlampconfig.HeadlampCFG{
-				UseInCluster:    true,
-				KubeConfigStore: kubeConfigStore,
-			},
-			Cache:            cache.New[interface{}](),
-			TelemetryConfig:  GetDefaultTestTelemetryConfig(),
-			TelemetryHandler: &telemetry.RequestHandler{},
-		},
-	}
-
-	req, err := makeJSONReq(http.MethodPost, "/drain-node", struct {
-		Cluster  string `json:"cluster"`
-		NodeName string `json:"nodeName"`
-	}{
-		Cluster:  customCluster,
-		NodeName: nodeName,
-	})
-	require.NoError(t, err)
-	req.AddCookie(&http.Cookie{
-		Name:     "headlamp-auth-" + customCluster + ".0",
-		Value:    testToken,
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteStrictMode,
-	})
-
-	rr := httptest.NewRecorder()
-	cfg.handleNodeDrain(rr, req)
-	require.Equal(t, http.StatusOK, rr.Code)
-
-	select {
-	case got := <-authHeaders:
-		assert.Equal(t, "Bearer "+testToken, got)
-	case <-time.After(2 * time.Second):
-		t.Fatal("timed out waiting for drain request to reach Kubernetes API")
-	}
-}
-
-func TestDrainNodeCancelledContextDoesNotWriteCache(t *testing.T) {
-	pod1 := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "pod-1",
-			Namespace: "default",
-		},
-		Spec: corev1.PodSpec{
-			NodeName: "test-node",
-		},
-	}
-
-	node := &corev1.Node{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "test-node",
-		},
-	}
-
-	fakeClient := fake.NewClientset(node, pod1)
-
-	testCache := cache.New[interface{}]()
-	c := &HeadlampConfig{
-		HeadlampConfig: &headlampconfig.HeadlampConfig{
-			Cache: testCache,
-		},
-	}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	cancel()
-
-	cacheKey := uuid.NewSHA1(uuid.Nil, []byte("test-node"+"\x00"+"test-cluster")).String()
-
-	c.drainNode(ctx, fakeClient, "test-node", "test-cluster")
-
-	assert.Never(t, func() bool {
-		_, err := testCache.Get(context.Background(), cacheKey)
-		return err == nil
-	}, 100*time.Millisecond, 10*time.Millisecond, "cache should not be written when context is already cancelled")
-}
-
-func TestDrainNodeSkipsDaemonSetPods(t *testing.T) {
-	podDaemonset := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "pod-daemonset",
-			Namespace: "default",
-			OwnerReferences: []metav1.OwnerReference{
-				{
-					Kind:       "DaemonSet",
-					Name:       "my-daemonset",
-					APIVersion: "apps/v1",
-					Controller: func() *bool { b := true; return &b }(),
-				},
-			},
-		},
-		Spec: corev1.PodSpec{
-			NodeName: "test-node",
-		},
-	}
-
-	node := &corev1.Node{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "test-node",
-		},
-	}
-
-	fakeClient := fake.NewClientset(node, podDaemonset)
-
-	var deleted atomic.Bool
-
-	fakeClient.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
-		deleted.Store(true)
-		return false, nil, nil
-	})
-
-	testCache := cache.New[interface{}]()
-	c := &HeadlampConfig{
-		HeadlampConfig: &headlampconfig.HeadlampConfig{
-			Cache: testCache,
-		},
-	}
-
-	cacheKey := uuid.NewSHA1(uuid.Nil, []byte("test-node"+"\x00"+"test-cluster")).String()
-	ctx := context.Background()
-
-	c.drainNode(ctx, fakeClient, "test-node", "test-cluster")
-
-	require.Eventually(t, func() bool {
-		cacheItem, err := testCache.Get(ctx, cacheKey)
-		if err != nil {
-			return false
-		}
-
-		status, ok := cacheItem.(string)
-
-		return ok && status == "success"
-	}, 2*time.Second, 50*time.Millisecond)
-
-	assert.False(t, deleted.Load(), "DaemonSet pod should not be deleted during drain")
-}
-
 func TestDeletePlugin(t *testing.T) {
 	// create temp dir for plugins
 	tempDir, err := os.MkdirTemp("", "plugins")
@@ -4076,6 +3672,50 @@ func TestHelmRouteReleaseHandlerUsesServiceAccountToken(t *testing.T) {
 	assert.Equal(t, tokenFile, capturedBearerTokenFile)
 }
 
+func TestHelmInventoryClustersUsesClusterCookies(t *testing.T) {
+	kubeConfigStore := kubeconfig.NewContextStore()
+
+	for _, name := range []string{"east", "west", "dynamic"} {
+		err := kubeConfigStore.AddContext(&kubeconfig.Context{
+			Name:     name,
+			Cluster:  &api.Cluster{Server: "https://" + name + ".example.com"},
+			AuthInfo: &api.AuthInfo{},
+			Internal: name == "dynamic",
+		})
+		require.NoError(t, err)
+	}
+
+	c := &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			HeadlampCFG: &headlampconfig.HeadlampCFG{
+				UseInCluster:    true,
+				KubeConfigStore: kubeConfigStore,
+			},
+		},
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/helm/releases/inventory", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer other-token")
+	req.AddCookie(&http.Cookie{Name: "headlamp-auth-east.0", Value: "east-token"})
+
+	clusters, err := c.helmInventoryClusters(req)
+	require.NoError(t, err)
+	require.Len(t, clusters, 2)
+
+	tokens := map[string]string{}
+
+	for _, cluster := range clusters {
+		restConfig, err := cluster.ClientConfig.ClientConfig()
+		require.NoError(t, err)
+
+		tokens[cluster.Name] = restConfig.BearerToken
+	}
+
+	assert.Equal(t, map[string]string{"east": "east-token", "west": ""}, tokens)
+	assert.Equal(t, "Bearer other-token", req.Header.Get("Authorization"))
+}
+
 func TestHelmRouteRepositoryHandlerUsesServiceAccountToken(t *testing.T) {
 	clusterName := "main"
 	tokenFile := writeTestTokenFile(t)
@@ -4581,3 +4221,24 @@ func TestExternalProxyOversizeResponseGzip(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Equal(t, int(maxProxyResponseSize), rr.Body.Len())
 }
+
+func TestNewHelmHandlerAllowsLocalChartsOnlyForDesktopApp(t *testing.T) {
+	c := &HeadlampConfig{
+		HeadlampConfig: &headlampconfig.HeadlampConfig{
+			HeadlampCFG: &headlampconfig.HeadlampCFG{},
+			Cache:       cache.New[interface{}](),
+		},
+	}
+
+	t.Setenv("HEADLAMP_BACKEND_TOKEN", "")
+
+	helmHandler, err := c.newHelmHandler()
+	require.NoError(t, err)
+	assert.False(t, helmHandler.AllowLocalCharts, "a server for a browser must not load charts from its file system")
+
+	t.Setenv("HEADLAMP_BACKEND_TOKEN", "desktop-token")
+
+	helmHandler, err = c.newHelmHandler()
+	require.NoError(t, err)
+	assert.True(t, helmHandler.AllowLocalCharts)
+}
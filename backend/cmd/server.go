@@ -79,6 +79,17 @@ func main() {
 	StartHeadlampServer(headlampConfig)
 }
 
+// helmPostRenderers returns the Helm post-renderers of the config, which was
+// validated when it was parsed.
+func helmPostRenderers(conf *config.Config) map[string]string {
+	postRenderers, err := config.ParseHelmPostRenderers(conf.HelmPostRenderers)
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "parsing helm post-renderers")
+	}
+
+	return postRenderers
+}
+
 // buildHeadlampCFG maps the parsed config into the struct the backend uses.
 func buildHeadlampCFG(conf *config.Config, kubeConfigStore kubeconfig.ContextStore) *headlampconfig.HeadlampCFG {
 	return &headlampconfig.HeadlampCFG{
@@ -109,6 +120,13 @@ func buildHeadlampCFG(conf *config.Config, kubeConfigStore kubeconfig.ContextSto
 
 			return strings.Split(conf.ProxyURLs, ",")
 		}(),
+		DrainNodeTimeout:                      conf.DrainNodeTimeout,
+		PortForwardsFile:                      conf.PortForwardsFile,
+		HelmRepoRefreshInterval:               conf.HelmRepoRefreshInterval,
+		HelmActionHistoryFile:                 conf.HelmActionHistoryFile,
+		HelmPostRenderers:                     helmPostRenderers(conf),
+		HelmKeyring:                           conf.HelmKeyring,
+		HelmVerifyCharts:                      conf.HelmVerifyCharts,
 		ClusterInventoryProviderFile:          conf.ClusterInventoryProviderFile,
 		ClusterInventoryLabelSelector:         conf.ClusterInventoryLabelSelector,
 		ClusterInventoryNamespaces:            conf.ClusterInventoryNamespaces,
@@ -28,6 +28,10 @@ const (
 	osWindows         = "windows"
 )
 
+// DefaultDrainNodeTimeout is how long a node drain waits for its pods to be
+// evicted before giving up.
+const DefaultDrainNodeTimeout = 5 * time.Minute
+
 const (
 	DefaultMeUsernamePath = "preferred_username,upn,username,name"
 	DefaultMeEmailPath    = "email"
@@ -68,6 +72,15 @@ type Config struct {
 	NodeShellNamespace     string `koanf:"node-shell-namespace"`
 	ProxyURLs              string `koanf:"proxy-urls"`
 
+	DrainNodeTimeout time.Duration `koanf:"drain-node-timeout"`
+	PortForwardsFile string        `koanf:"port-forwards-file"`
+
+	HelmRepoRefreshInterval time.Duration `koanf:"helm-repo-refresh-interval"`
+	HelmActionHistoryFile   string        `koanf:"helm-action-history-file"`
+	HelmPostRenderers       string        `koanf:"helm-post-renderers"`
+	HelmKeyring             string        `koanf:"helm-keyring"`
+	HelmVerifyCharts        bool          `koanf:"helm-verify-charts"`
+
 	ClusterInventoryProviderFile          string        `koanf:"cluster-inventory-provider-file"`
 	ClusterInventoryLabelSelector         string        `koanf:"cluster-inventory-label-selector"`
 	ClusterInventoryNamespaces            string        `koanf:"cluster-inventory-namespaces"`
@@ -165,6 +178,28 @@ func (c *Config) Validate() error {
 		return errors.New("session-ttl cannot be greater than 1 year")
 	}
 
+	if c.DrainNodeTimeout < 0 {
+		return errors.New("drain-node-timeout cannot be negative")
+	}
+
+	if c.InCluster && c.PortForwardsFile != "" {
+		// Port forwards are saved for the whole server, so in-cluster they
+		// would be shared between users.
+		return errors.New("port-forwards-file cannot be used in in-cluster mode")
+	}
+
+	if c.HelmRepoRefreshInterval < 0 {
+		return errors.New("helm-repo-refresh-interval cannot be negative")
+	}
+
+	if _, err := ParseHelmPostRenderers(c.HelmPostRenderers); err != nil {
+		return err
+	}
+
+	if c.HelmVerifyCharts && c.HelmKeyring == "" {
+		return errors.New("helm-keyring is required when helm-verify-charts is enabled")
+	}
+
 	if c.TracingEnabled != nil && *c.TracingEnabled {
 		if c.ServiceName == "" {
 			return errors.New("service-name is required when tracing is enabled")
@@ -560,6 +595,78 @@ func DefaultKubeConfigFile(kubeConfigDir string) (string, error) {
 	return filepath.Join(kubeConfigDir, "config"), nil
 }
 
+// ParseHelmPostRenderers parses the helm-post-renderers flag, a comma
+// separated list of name=path, into the absolute executable paths by name.
+func ParseHelmPostRenderers(value string) (map[string]string, error) {
+	postRenderers := map[string]string{}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, path, ok := strings.Cut(entry, "=")
+		name, path = strings.TrimSpace(name), strings.TrimSpace(path)
+
+		if !ok || name == "" || !filepath.IsAbs(path) {
+			return nil, fmt.Errorf("helm-post-renderers entry %q must be a name=absolute path", entry)
+		}
+
+		if _, exists := postRenderers[name]; exists {
+			return nil, fmt.Errorf("helm-post-renderers has %q more than once", name)
+		}
+
+		postRenderers[name] = path
+	}
+
+	return postRenderers, nil
+}
+
+// headlampConfigDir returns Headlamp's platform-specific config directory,
+// creating it if it doesn't exist.
+func headlampConfigDir() (string, error) {
+	userConfigDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	configDir := filepath.Join(userConfigDir, "Headlamp")
+	if runtime.GOOS == osWindows {
+		// golang is wrong for config folder on windows.
+		// This matches env-paths and headlamp-plugin.
+		configDir = filepath.Join(userConfigDir, "Headlamp", "Config")
+	}
+
+	if err := os.MkdirAll(configDir, fs.FileMode(0o755)); err != nil {
+		return "", fmt.Errorf("creating headlamp config directory: %w", err)
+	}
+
+	return configDir, nil
+}
+
+// DefaultPortForwardsFile returns the file port forwards are saved in when no
+// port-forwards-file is configured.
+func DefaultPortForwardsFile() (string, error) {
+	configDir, err := headlampConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, "port-forwards.json"), nil
+}
+
+// DefaultHelmActionHistoryFile returns the file where Helm actions are
+// recorded when no helm-action-history-file is configured.
+func DefaultHelmActionHistoryFile() (string, error) {
+	configDir, err := headlampConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, "helm-action-history.jsonl"), nil
+}
+
 // validateOpenBrowser ensures the open-browser option is only used when the
 // binary was built with embedded static files.
 func validateOpenBrowser(config *Config, explicitFlags map[string]bool) error {
@@ -625,7 +732,19 @@ func addGeneralFlags(f *flag.FlagSet, appName string) {
 	f.String("listen-addr", "", "Address to listen on; default is empty, which means listening to any address")
 	f.Uint("port", defaultPort, "Port to listen from")
 	f.String("proxy-urls", "", "Allow proxy requests to specified URLs")
+	f.Duration("drain-node-timeout", DefaultDrainNodeTimeout,
+		"Maximum time a node drain waits for its pods to be evicted, e.g. while blocked by PodDisruptionBudgets")
+	f.String("port-forwards-file", "",
+		"JSON file to save port forwards in across restarts; defaults to one in the Headlamp config dir, not in-cluster")
 	f.Bool("enable-helm", false, "Enable Helm operations")
+	f.Duration("helm-repo-refresh-interval", 0,
+		"How often Helm repository indexes are refreshed in the background, e.g. 1h; disabled if 0")
+	f.String("helm-post-renderers", "",
+		"Comma separated name=path list of the executables Helm installs and upgrades may post-render with")
+	f.String("helm-action-history-file", "",
+		"JSON lines file to record Helm actions in; defaults to one in the Headlamp config dir, except in-cluster")
+	f.String("helm-keyring", "", "Keyring of the public keys Helm chart provenance files are verified with")
+	f.Bool("helm-verify-charts", false, "Refuse to install or upgrade Helm charts without a valid provenance file")
 	f.Bool("enable-cluster-inventory", false,
 		"Enable experimental/alpha automatic discovery of clusters from ClusterProfile resources")
 	f.String("cluster-inventory-provider-file", "",
@@ -415,6 +415,16 @@ func TestParseErrors(t *testing.T) {
 			},
 			errorContains: "--service-account-token-path requires --unsafe-use-service-account-token",
 		},
+		{
+			name:          "relative_helm_post_renderer",
+			args:          []string{"go run ./cmd", "--helm-post-renderers=kustomize=bin/kustomize"},
+			errorContains: "must be a name=absolute path",
+		},
+		{
+			name:          "helm_verify_charts_without_keyring",
+			args:          []string{"go run ./cmd", "--helm-verify-charts"},
+			errorContains: "helm-keyring is required",
+		},
 		{
 			name:          "invalid_base_url",
 			args:          []string{"go run ./cmd", "--base-url=testingthis"},
@@ -430,6 +440,11 @@ func TestParseErrors(t *testing.T) {
 			args:          []string{"go run ./cmd", "--no-browser", "--in-cluster"},
 			errorContains: "no-browser cannot be used in in-cluster mode",
 		},
+		{
+			name:          "port_forwards_file_in_cluster",
+			args:          []string{"go run ./cmd", "--in-cluster", "--port-forwards-file=/tmp/port-forwards.json"},
+			errorContains: "port-forwards-file cannot be used in in-cluster mode",
+		},
 	}
 
 	for _, tt := range tests {
@@ -480,6 +495,20 @@ var parseFlagTests = []parseFlagTest{
 			assert.Equal(t, true, conf.EnableHelm)
 		},
 	},
+	{
+		name: "helm_repo_refresh_interval_disabled_by_default",
+		args: []string{"go run ./cmd", "--enable-helm"},
+		verify: func(t *testing.T, conf *config.Config) {
+			assert.Zero(t, conf.HelmRepoRefreshInterval)
+		},
+	},
+	{
+		name: "helm_repo_refresh_interval_flag",
+		args: []string{"go run ./cmd", "--enable-helm", "--helm-repo-refresh-interval=30m"},
+		verify: func(t *testing.T, conf *config.Config) {
+			assert.Equal(t, 30*time.Minute, conf.HelmRepoRefreshInterval)
+		},
+	},
 	{
 		name: "in_cluster_context_name_flag",
 		args: []string{"go run ./cmd", "--in-cluster-context-name=mycluster"},
@@ -579,6 +608,22 @@ func TestProxyAuthFlags(t *testing.T) {
 	}
 }
 
+func TestParseHelmPostRenderers(t *testing.T) {
+	dir := t.TempDir()
+	kustomize := filepath.Join(dir, "kustomize")
+	patcher := filepath.Join(dir, "patcher")
+
+	postRenderers, err := config.ParseHelmPostRenderers("kustomize=" + kustomize + ", patcher = " + patcher + ",")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"kustomize": kustomize, "patcher": patcher}, postRenderers)
+
+	_, err = config.ParseHelmPostRenderers("kustomize=" + kustomize + ",kustomize=" + patcher)
+	assert.ErrorContains(t, err, "more than once")
+
+	_, err = config.ParseHelmPostRenderers("=" + kustomize)
+	assert.ErrorContains(t, err, "must be a name=absolute path")
+}
+
 func TestParseClusterInventoryFlags(t *testing.T) {
 	providerFile := writeClusterInventoryProviderFile(t)
 
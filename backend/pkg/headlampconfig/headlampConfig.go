@@ -70,6 +70,22 @@ type HeadlampCFG struct {
 	Metrics                *telemetry.Metrics
 	BaseURL                string
 	ProxyURLs              []string
+	DrainNodeTimeout       time.Duration
+	// PortForwardsFile is where port forwards are saved across restarts, if set.
+	PortForwardsFile string
+	// HelmRepoRefreshInterval is how often Helm repository indexes are
+	// refreshed in the background, or 0 to not refresh them.
+	HelmRepoRefreshInterval time.Duration
+	// HelmActionHistoryFile is where Helm actions are recorded, if set.
+	HelmActionHistoryFile string
+	// HelmPostRenderers are the executables Helm installs and upgrades may
+	// post-render with, by name.
+	HelmPostRenderers map[string]string
+	// HelmKeyring is the keyring chart provenance files are verified with.
+	HelmKeyring string
+	// HelmVerifyCharts requires the provenance of every chart installed or
+	// upgraded to be verified.
+	HelmVerifyCharts bool
 
 	TLSCertPath                  string
 	TLSKeyPath                   string
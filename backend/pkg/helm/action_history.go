@@ -0,0 +1,491 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/schema"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	defaultActionHistoryLimit = 100
+	maxActionHistoryLimit     = 1000
+
+	historyFileMode = 0o600
+	historyDirMode  = 0o700
+	// historyMaxSize is the size the history file is rotated at. Only the
+	// last rotated file is kept, so the history takes at most twice as much.
+	historyMaxSize = 10 << 20
+
+	opGetActionHistory = "get_action_history"
+)
+
+// ActionRecord is an install, upgrade, rollback or uninstall in the action history.
+type ActionRecord struct {
+	// Time is when the action started.
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	Cluster   string    `json:"cluster"`
+	Namespace string    `json:"namespace"`
+	Release   string    `json:"release"`
+	Chart     string    `json:"chart,omitempty"`
+	Version   string    `json:"version,omitempty"`
+	// Revision is the revision a rollback went back to.
+	Revision int `json:"revision,omitempty"`
+	// ValuesHash is the SHA-256 of the values of an install or upgrade.
+	ValuesHash string `json:"valuesHash,omitempty"`
+	// User is who the cluster authenticated the action as.
+	User string `json:"user,omitempty"`
+	// DurationMs is how long the action took, in milliseconds.
+	DurationMs int64 `json:"durationMs"`
+	// Outcome is success or failed.
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ActionHistoryQuery selects records of the action history. Empty fields match any record.
+type ActionHistoryQuery struct {
+	Cluster   string
+	Namespace string
+	Release   string
+	Action    string
+	User      string
+	Outcome   string
+	// Since excludes the actions started before it.
+	Since time.Time
+	// Limit is the maximum number of records returned, the most recent ones.
+	Limit int
+}
+
+func (q ActionHistoryQuery) matches(record ActionRecord) bool {
+	for _, field := range [][2]string{
+		{q.Cluster, record.Cluster},
+		{q.Namespace, record.Namespace},
+		{q.Release, record.Release},
+		{q.Action, record.Action},
+		{q.User, record.User},
+		{q.Outcome, record.Outcome},
+	} {
+		if field[0] != "" && field[0] != field[1] {
+			return false
+		}
+	}
+
+	return !record.Time.Before(q.Since)
+}
+
+// ActionHistory is a durable store of the Helm actions run through Headlamp.
+type ActionHistory interface {
+	Record(record ActionRecord) error
+	// Query returns the matching records, most recent first.
+	Query(query ActionHistoryQuery) ([]ActionRecord, error)
+}
+
+// FileActionHistory keeps the action history in a file, one JSON record per
+// line. Once the file is too large, it's rotated to the same path with a .1
+// suffix, replacing the previous one.
+type FileActionHistory struct {
+	path    string
+	maxSize int64
+	mu      sync.Mutex
+}
+
+// NewFileActionHistory returns an action history kept in the file at path.
+func NewFileActionHistory(path string) *FileActionHistory {
+	return &FileActionHistory{path: path, maxSize: historyMaxSize}
+}
+
+func (f *FileActionHistory) rotatedPath() string {
+	return f.path + ".1"
+}
+
+// Record appends a record to the file, rotating it first if the record
+// doesn't fit.
+func (f *FileActionHistory) Record(record ActionRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	line = append(line, '\n')
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(f.path), historyDirMode); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(f.path)
+	if err == nil && info.Size() > 0 && info.Size()+int64(len(line)) > f.maxSize {
+		err = os.Rename(f.path, f.rotatedPath())
+	}
+
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, historyFileMode)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(line)
+
+	return errors.Join(err, file.Close())
+}
+
+// Query reads the rotated file and the file for the matching records.
+func (f *FileActionHistory) Query(query ActionHistoryQuery) ([]ActionRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	records := []ActionRecord{}
+
+	for _, path := range []string{f.rotatedPath(), f.path} {
+		var err error
+
+		records, err = appendMatchingRecords(records, path, query)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	slices.Reverse(records)
+
+	if query.Limit > 0 && len(records) > query.Limit {
+		records = records[:query.Limit]
+	}
+
+	return records, nil
+}
+
+// appendMatchingRecords appends the records of the file at path that match
+// the query. Lines that can't be parsed are skipped, e.g. one cut short by a
+// crash while it was written.
+func appendMatchingRecords(records []ActionRecord, path string, query ActionHistoryQuery) ([]ActionRecord, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return records, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var record ActionRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			logger.Log(logger.LevelWarn, map[string]string{"file": path}, err, "skipping action history record")
+			continue
+		}
+
+		if query.matches(record) {
+			records = append(records, record)
+		}
+	}
+
+	return records, scanner.Err()
+}
+
+// newActionRecord starts the record of an action of user on a release, or
+// returns nil if there's no action history.
+func (h *Handler) newActionRecord(actionName, namespace, releaseName, user string) *ActionRecord {
+	if h.History == nil {
+		return nil
+	}
+
+	return &ActionRecord{
+		Time:      time.Now().UTC(),
+		Action:    actionName,
+		Cluster:   h.Cluster,
+		Namespace: namespace,
+		Release:   releaseName,
+		User:      user,
+	}
+}
+
+// recordedUser returns who the cluster of an action config authenticates an
+// action as, for its record, or "" if there's no action history.
+func (h *Handler) recordedUser(actionConfig *action.Configuration, actionName, releaseName string) string {
+	if h.History == nil {
+		return ""
+	}
+
+	user, err := actionUser(actionConfig)
+	if err != nil {
+		logger.Log(logger.LevelWarn, map[string]string{logFieldReleaseName: releaseName, "action": actionName},
+			err, "getting user of action")
+	}
+
+	return user
+}
+
+// setChart sets the chart of the release an action is on.
+func (record *ActionRecord) setChart(rel *release.Release) {
+	if record == nil || rel == nil || rel.Chart == nil || rel.Chart.Metadata == nil {
+		return
+	}
+
+	record.Chart = rel.Chart.Metadata.Name
+	record.Version = rel.Chart.Metadata.Version
+}
+
+// recordAction adds the record of an action that is done to the history,
+// failed if it returned actionErr.
+func (h *Handler) recordAction(record *ActionRecord, actionErr error) {
+	if record == nil {
+		return
+	}
+
+	record.DurationMs = time.Since(record.Time).Milliseconds()
+	record.Outcome = success
+
+	if actionErr != nil {
+		record.Outcome = failed
+		record.Error = actionErr.Error()
+	}
+
+	if err := h.History.Record(*record); err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldReleaseName: record.Release, "action": record.Action},
+			err, "recording action")
+	}
+}
+
+// valuesHash returns the SHA-256 of the base64 encoded values of a request.
+func valuesHash(encoded string) string {
+	if encoded == "" {
+		return ""
+	}
+
+	values, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(values)
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+type ActionHistoryRequest struct {
+	Namespace string `json:"namespace,omitempty"`
+	Release   string `json:"release,omitempty"`
+	Action    string `json:"action,omitempty"`
+	User      string `json:"user,omitempty"`
+	Outcome   string `json:"outcome,omitempty"`
+	// Since is an RFC 3339 time to only return the actions started after.
+	Since string `json:"since,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// query returns the query of the request for the actions on a cluster.
+func (req *ActionHistoryRequest) query(cluster string) (ActionHistoryQuery, error) {
+	query := ActionHistoryQuery{
+		Cluster:   cluster,
+		Namespace: req.Namespace,
+		Release:   req.Release,
+		Action:    req.Action,
+		User:      req.User,
+		Outcome:   req.Outcome,
+		Limit:     defaultActionHistoryLimit,
+	}
+
+	if req.Limit < 0 || req.Limit > maxActionHistoryLimit {
+		return query, fmt.Errorf("limit must be between 0 and %d", maxActionHistoryLimit)
+	}
+
+	if req.Limit > 0 {
+		query.Limit = req.Limit
+	}
+
+	if req.Since != "" {
+		since, err := time.Parse(time.RFC3339, req.Since)
+		if err != nil {
+			return query, fmt.Errorf("invalid since: %w", err)
+		}
+
+		query.Since = since
+	}
+
+	return query, nil
+}
+
+type ActionHistoryResponse struct {
+	Actions []ActionRecord `json:"actions"`
+}
+
+// GetActionHistory returns the recorded actions on the cluster, most recent first.
+func (h *Handler) GetActionHistory(clientConfig clientcmd.ClientConfig, w http.ResponseWriter, r *http.Request) {
+	if h.History == nil {
+		http.Error(w, "helm action history is disabled", http.StatusNotFound)
+		return
+	}
+
+	var req ActionHistoryRequest
+
+	err := schema.NewDecoder().Decode(&req, r.URL.Query())
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRequest: opGetActionHistory}, err, "parsing request")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	query, err := req.query(h.Cluster)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRequest: opGetActionHistory}, err, "validating request")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	// The history is only shown to who can use the cluster.
+	actionConfig, err := NewActionConfig(clientConfig, "")
+	if err == nil {
+		_, err = actionUser(actionConfig)
+	}
+
+	if err != nil {
+		handleError(w, "", errUnauthorized, "verifying user for action history", http.StatusForbidden)
+		return
+	}
+
+	// The limit is applied once the actions the user can't see are left out.
+	limit := query.Limit
+	query.Limit = 0
+
+	records, err := h.History.Query(query)
+	if err == nil {
+		records, err = readableActions(r.Context(), actionConfig, records)
+	}
+
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRequest: opGetActionHistory}, err, "querying history")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if len(records) > limit {
+		records = records[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(ActionHistoryResponse{Actions: records}); err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRequest: opGetActionHistory}, err, "encoding response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// readableActions returns the actions in the namespaces whose releases the
+// user can read, i.e. where they can list the Secrets helm stores releases in.
+func readableActions(
+	ctx context.Context,
+	actionConfig *action.Configuration,
+	records []ActionRecord,
+) ([]ActionRecord, error) {
+	clientset, err := actionConfig.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+
+	// Who can list the Secrets of all the namespaces can read every action.
+	all, err := canListReleaseSecrets(ctx, clientset, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if all {
+		return records, nil
+	}
+
+	readable := []ActionRecord{}
+	allowed := map[string]bool{}
+
+	for _, record := range records {
+		canRead, checked := allowed[record.Namespace]
+		if !checked && record.Namespace != "" {
+			canRead, err = canListReleaseSecrets(ctx, clientset, record.Namespace)
+			if err != nil {
+				return nil, err
+			}
+
+			allowed[record.Namespace] = canRead
+		}
+
+		if canRead {
+			readable = append(readable, record)
+		}
+	}
+
+	return readable, nil
+}
+
+// canListReleaseSecrets returns whether the user can list the Secrets of a
+// namespace, or of all the namespaces if namespace is empty.
+func canListReleaseSecrets(ctx context.Context, clientset kubernetes.Interface, namespace string) (bool, error) {
+	review, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx,
+		&authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: namespace,
+					Verb:      "list",
+					Resource:  "secrets",
+				},
+			},
+		}, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	return review.Status.Allowed, nil
+}
@@ -0,0 +1,313 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/release"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestFileActionHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "actions.jsonl")
+	history := NewFileActionHistory(path)
+
+	records, err := history.Query(ActionHistoryQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, record := range []ActionRecord{
+		{Action: "install", Cluster: "prod", Namespace: "apps", Release: "web", Outcome: success},
+		{Action: "upgrade", Cluster: "prod", Namespace: "apps", Release: "web", Outcome: failed, Error: "timed out"},
+		{Action: "install", Cluster: "dev", Namespace: "apps", Release: "web", Outcome: success},
+		{Action: "rollback", Cluster: "prod", Namespace: "apps", Release: "web", Outcome: success, Revision: 1},
+	} {
+		record.Time = start.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, history.Record(record))
+	}
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(historyFileMode), info.Mode().Perm())
+
+	// A line cut short is skipped.
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"action":"uninst`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	records, err = history.Query(ActionHistoryQuery{Cluster: "prod"})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "rollback", records[0].Action)
+	assert.Equal(t, "install", records[2].Action)
+
+	records, err = history.Query(ActionHistoryQuery{Cluster: "prod", Outcome: failed})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "timed out", records[0].Error)
+
+	records, err = history.Query(ActionHistoryQuery{Since: start.Add(2 * time.Hour), Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "rollback", records[0].Action)
+}
+
+func TestFileActionHistoryRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "actions.jsonl")
+	history := NewFileActionHistory(path)
+
+	line, err := json.Marshal(ActionRecord{Action: "install", Release: "release-0", Outcome: success})
+	require.NoError(t, err)
+
+	// Each file holds two records.
+	history.maxSize = int64(2*len(line) + 2)
+
+	for i := range 5 {
+		require.NoError(t, history.Record(ActionRecord{Action: "install", Release: fmt.Sprintf("release-%d", i),
+			Outcome: success}))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), history.maxSize)
+	}
+
+	records, err := history.Query(ActionHistoryQuery{})
+	require.NoError(t, err)
+
+	releases := make([]string, 0, len(records))
+	for _, record := range records {
+		releases = append(releases, record.Release)
+	}
+
+	assert.Equal(t, []string{"release-4", "release-3", "release-2"}, releases,
+		"the records before the rotated file are dropped")
+}
+
+func TestRecordAction(t *testing.T) {
+	h := &Handler{
+		Cache:   cache.New[interface{}](),
+		History: NewFileActionHistory(filepath.Join(t.TempDir(), "actions.jsonl")),
+		Cluster: "prod",
+	}
+
+	values := base64.StdEncoding.EncodeToString([]byte("replicas: 2\n"))
+
+	record := h.newActionRecord("upgrade", "default", "web", "tester")
+	require.NotNil(t, record)
+
+	record.ValuesHash = valuesHash(values)
+	record.setChart(&release.Release{Chart: &chart.Chart{Metadata: &chart.Metadata{Name: "mychart", Version: "1.2.3"}}})
+
+	// The outcome is the action's, even if another action on the release
+	// changed its status in the meantime.
+	h.setReleaseStatusSilent("upgrade", "web", success, nil)
+	h.recordAction(record, errors.New("upgrade failed"))
+
+	records, err := h.History.Query(ActionHistoryQuery{Release: "web"})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "upgrade", records[0].Action)
+	assert.Equal(t, "prod", records[0].Cluster)
+	assert.Equal(t, "tester", records[0].User)
+	assert.Equal(t, "mychart", records[0].Chart)
+	assert.Equal(t, "1.2.3", records[0].Version)
+	assert.Equal(t, failed, records[0].Outcome)
+	assert.Equal(t, "upgrade failed", records[0].Error)
+	assert.Equal(t, valuesHash(values), records[0].ValuesHash)
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", records[0].ValuesHash)
+
+	assert.Nil(t, (&Handler{}).newActionRecord("upgrade", "default", "web", ""),
+		"nothing is recorded without a history")
+	assert.Empty(t, (&Handler{}).recordedUser(newMemoryActionConfig(t), "upgrade", "web"),
+		"the user isn't looked up without a history")
+}
+
+// newHistoryTestClientConfig returns the client config of a user who can
+// list the Secrets of the allowed namespaces, or of all if allowed has "".
+func newHistoryTestClientConfig(t *testing.T, allowed ...string) clientcmd.ClientConfig {
+	t.Helper()
+
+	return newTestClientConfigFor(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveSelfSubjectReview(w, r) {
+			return
+		}
+
+		if r.URL.Path != "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews" {
+			http.NotFound(w, r)
+			return
+		}
+
+		// The client may send protobuf, which the scheme decodes too.
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		review := &authorizationv1.SelfSubjectAccessReview{}
+
+		if _, _, err := scheme.Codecs.UniversalDeserializer().Decode(body, nil, review); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		attributes := review.Spec.ResourceAttributes
+		review.Status.Allowed = attributes.Verb == "list" && attributes.Resource == "secrets" &&
+			slices.Contains(allowed, attributes.Namespace)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(review)
+	}))
+}
+
+func TestGetActionHistory(t *testing.T) {
+	h := &Handler{History: NewFileActionHistory(filepath.Join(t.TempDir(), "actions.jsonl")), Cluster: "test"}
+
+	for _, cluster := range []string{"test", "other"} {
+		require.NoError(t, h.History.Record(ActionRecord{
+			Time:    time.Now(),
+			Action:  "install",
+			Cluster: cluster,
+			Release: "web",
+			Outcome: success,
+		}))
+	}
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, target, nil)
+		rr := httptest.NewRecorder()
+		h.GetActionHistory(newHistoryTestClientConfig(t, ""), rr, req)
+
+		return rr
+	}
+
+	rr := serve("/helm/actions/history?release=web")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response ActionHistoryResponse
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	require.Len(t, response.Actions, 1, "only the actions on the cluster of the request are returned")
+	assert.Equal(t, "test", response.Actions[0].Cluster)
+
+	assert.Equal(t, http.StatusBadRequest, serve("/helm/actions/history?since=yesterday").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("/helm/actions/history?limit=5000").Code)
+
+	h.History = nil
+	assert.Equal(t, http.StatusNotFound, serve("/helm/actions/history").Code)
+}
+
+func TestGetActionHistoryOnlyReturnsReadableNamespaces(t *testing.T) {
+	h := &Handler{History: NewFileActionHistory(filepath.Join(t.TempDir(), "actions.jsonl")), Cluster: "test"}
+
+	for i, namespace := range []string{"team-a", "team-b", "team-a", "team-c"} {
+		require.NoError(t, h.History.Record(ActionRecord{
+			Time:      time.Now().Add(time.Duration(i) * time.Second),
+			Action:    "install",
+			Cluster:   "test",
+			Namespace: namespace,
+			Release:   fmt.Sprintf("web-%d", i),
+			Outcome:   success,
+		}))
+	}
+
+	serve := func(clientConfig clientcmd.ClientConfig, target string) []ActionRecord {
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, target, nil)
+		rr := httptest.NewRecorder()
+		h.GetActionHistory(clientConfig, rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var response ActionHistoryResponse
+
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+		return response.Actions
+	}
+
+	releases := func(records []ActionRecord) []string {
+		names := []string{}
+		for _, record := range records {
+			names = append(names, record.Release)
+		}
+
+		return names
+	}
+
+	clientConfig := newHistoryTestClientConfig(t, "team-a", "team-c")
+	assert.Equal(t, []string{"web-3", "web-2", "web-0"}, releases(serve(clientConfig, "/helm/actions/history")))
+	assert.Equal(t, []string{"web-3", "web-2"}, releases(serve(clientConfig, "/helm/actions/history?limit=2")),
+		"the limit counts the readable actions")
+	assert.Empty(t, serve(clientConfig, "/helm/actions/history?namespace=team-b"))
+
+	assert.Empty(t, serve(newHistoryTestClientConfig(t), "/helm/actions/history"))
+	assert.Len(t, serve(newHistoryTestClientConfig(t, ""), "/helm/actions/history"), 4)
+}
+
+func TestInstallReleaseRecordsAction(t *testing.T) {
+	h := &Handler{
+		Cache:       cache.New[interface{}](),
+		EnvSettings: cli.New(),
+		History:     NewFileActionHistory(filepath.Join(t.TempDir(), "actions.jsonl")),
+		Cluster:     "test",
+	}
+
+	values := base64.StdEncoding.EncodeToString([]byte("replicas: 3\n"))
+
+	body, err := json.Marshal(InstallRequest{
+		CommonInstallUpdateRequest: CommonInstallUpdateRequest{
+			Name:        "demo",
+			Namespace:   "default",
+			Description: "recorded",
+			Chart:       writeTestChart(t),
+			Version:     "0.1.0",
+			Values:      values,
+		},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost,
+		"/clusters/test/helm/release/install", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	h.InstallRelease(newTestClientConfig(t), rr, req)
+	require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+
+	var records []ActionRecord
+
+	require.Eventually(t, func() bool {
+		records, err = h.History.Query(ActionHistoryQuery{})
+		return err == nil && len(records) == 1
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// The fake API server can't store the release, so the install fails.
+	assert.Equal(t, "install", records[0].Action)
+	assert.Equal(t, "test", records[0].Cluster)
+	assert.Equal(t, "tester", records[0].User)
+	assert.Equal(t, "0.1.0", records[0].Version)
+	assert.Equal(t, valuesHash(values), records[0].ValuesHash)
+	assert.Equal(t, failed, records[0].Outcome)
+	assert.NotEmpty(t, records[0].Error)
+}
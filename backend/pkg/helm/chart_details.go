@@ -0,0 +1,345 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/schema"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// readmeFileNames are the README files of a chart, in order of preference, like helm show readme.
+var readmeFileNames = []string{"readme.md", "readme.txt", "readme"}
+
+var (
+	errNotRepoChart  = errors.New("chart must be a repository chart like repo/name")
+	errChartNotFound = errors.New("chart not found")
+)
+
+// chartFileNotFoundError is returned when a chart has no file of the requested kind.
+type chartFileNotFoundError struct {
+	chart string
+	file  string
+}
+
+func (e chartFileNotFoundError) Error() string {
+	return fmt.Sprintf("chart %s has no %s", e.chart, e.file)
+}
+
+// chartRequestError is returned when the chart of an install or upgrade
+// request can't be used, e.g. it can't be located or fails verification.
+type chartRequestError struct {
+	err error
+}
+
+func (e chartRequestError) Error() string {
+	return e.err.Error()
+}
+
+func (e chartRequestError) Unwrap() error {
+	return e.err
+}
+
+type ListChartVersionsRequest struct {
+	// Chart is the chart name as listed by ListCharts, e.g. bitnami/nginx.
+	Chart string `json:"chart" validate:"required"`
+}
+
+func (req *ListChartVersionsRequest) Validate() error {
+	validate := validator.New()
+	return validate.Struct(req)
+}
+
+type chartVersionInfo struct {
+	Version     string    `json:"version"`
+	AppVersion  string    `json:"appVersion"`
+	Description string    `json:"description"`
+	Created     time.Time `json:"created"`
+	Deprecated  bool      `json:"deprecated,omitempty"`
+}
+
+type ListChartVersionsResponse struct {
+	// Versions are the versions in the cached repository index, newest first.
+	Versions []chartVersionInfo `json:"versions"`
+}
+
+// ChartFileRequest selects a chart version to read a file of.
+type ChartFileRequest struct {
+	// Chart is the chart name as listed by ListCharts, e.g. bitnami/nginx, or an oci:// reference.
+	Chart string `json:"chart" validate:"required"`
+	// Version is a version or a semver constraint. The latest version is used when it is empty.
+	Version string `json:"version"`
+	// PlainHTTP pulls oci:// charts over HTTP instead of HTTPS.
+	PlainHTTP bool `json:"plainHTTP"`
+}
+
+func (req *ChartFileRequest) Validate() error {
+	validate := validator.New()
+	return validate.Struct(req)
+}
+
+// ListChartVersions lists all the versions of a chart in the cached repository index.
+func (h *Handler) ListChartVersions(w http.ResponseWriter, r *http.Request) {
+	var req ListChartVersionsRequest
+
+	if err := schema.NewDecoder().Decode(&req, r.URL.Query()); err != nil {
+		logger.Log(logger.LevelError, nil, err, "parsing request for chart versions")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		logger.Log(logger.LevelError, nil, err, "validating request for chart versions")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	indexFile, name, err := h.loadChartIndex(req.Chart)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart}, err, "listing chart versions")
+		http.Error(w, err.Error(), chartErrorStatus(err))
+
+		return
+	}
+
+	versions := indexFile.Entries[name]
+
+	response := ListChartVersionsResponse{Versions: make([]chartVersionInfo, 0, len(versions))}
+	for _, version := range versions {
+		response.Versions = append(response.Versions, chartVersionInfo{
+			Version:     version.Version,
+			AppVersion:  version.AppVersion,
+			Description: version.Description,
+			Created:     version.Created,
+			Deprecated:  version.Deprecated,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart}, err, "encoding response")
+	}
+}
+
+// GetChartReadme returns the README of a chart version.
+func (h *Handler) GetChartReadme(w http.ResponseWriter, r *http.Request) {
+	h.serveChartFile(w, r, "README", "text/markdown", chartReadme)
+}
+
+// GetChartValues returns the values.yaml of a chart version, with its comments.
+func (h *Handler) GetChartValues(w http.ResponseWriter, r *http.Request) {
+	h.serveChartFile(w, r, chartutil.ValuesfileName, "application/yaml", chartRawFile(chartutil.ValuesfileName))
+}
+
+// GetChartValuesSchema returns the values.schema.json of a chart version.
+func (h *Handler) GetChartValuesSchema(w http.ResponseWriter, r *http.Request) {
+	h.serveChartFile(w, r, chartutil.SchemafileName, "application/json", chartValuesSchema)
+}
+
+// GetChartMetadata returns the Chart.yaml of a chart version.
+func (h *Handler) GetChartMetadata(w http.ResponseWriter, r *http.Request) {
+	h.serveChartFile(w, r, chartutil.ChartfileName, "application/yaml", chartRawFile(chartutil.ChartfileName))
+}
+
+// chartFileFunc returns the data of a file of a chart, or false if the chart doesn't have it.
+type chartFileFunc func(c *chart.Chart) ([]byte, bool, error)
+
+func chartReadme(c *chart.Chart) ([]byte, bool, error) {
+	for _, name := range readmeFileNames {
+		for _, file := range c.Files {
+			if strings.EqualFold(file.Name, name) {
+				return file.Data, true, nil
+			}
+		}
+	}
+
+	return nil, false, nil
+}
+
+// chartRawFile returns the chartFileFunc of a file of the chart as it was
+// packaged, e.g. values.yaml with its comments.
+func chartRawFile(name string) chartFileFunc {
+	return func(c *chart.Chart) ([]byte, bool, error) {
+		for _, file := range c.Raw {
+			if file.Name == name {
+				return file.Data, true, nil
+			}
+		}
+
+		return nil, false, nil
+	}
+}
+
+func chartValuesSchema(c *chart.Chart) ([]byte, bool, error) {
+	return c.Schema, len(c.Schema) > 0, nil
+}
+
+// serveChartFile loads the requested chart version and writes its fileName,
+// whose data is returned by file.
+func (h *Handler) serveChartFile(
+	w http.ResponseWriter,
+	r *http.Request,
+	fileName string,
+	contentType string,
+	file chartFileFunc,
+) {
+	var req ChartFileRequest
+
+	if err := schema.NewDecoder().Decode(&req, r.URL.Query()); err != nil {
+		logger.Log(logger.LevelError, nil, err, "parsing request for chart file")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		logger.Log(logger.LevelError, nil, err, "validating request for chart file")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	loadedChart, err := h.loadChartVersion(req)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart}, err, "loading chart")
+		http.Error(w, err.Error(), chartErrorStatus(err))
+
+		return
+	}
+
+	data, ok, err := file(loadedChart)
+	if err == nil && !ok {
+		err = chartFileNotFoundError{chart: req.Chart, file: fileName}
+	}
+
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart}, err, "reading chart file")
+		http.Error(w, err.Error(), chartErrorStatus(err))
+
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(data); err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart}, err, "writing response")
+	}
+}
+
+// loadChartIndex returns the cached index of the repository of a repo/name
+// chart, with its versions sorted newest first, and the name of the chart in
+// the index.
+func (h *Handler) loadChartIndex(chartName string) (*repo.IndexFile, string, error) {
+	repoName, name, ok := strings.Cut(chartName, "/")
+	if !ok || registry.IsOCI(chartName) {
+		return nil, "", errNotRepoChart
+	}
+
+	repoFile, err := repo.LoadFile(h.EnvSettings.RepositoryConfig)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !repoFile.Has(repoName) {
+		return nil, "", fmt.Errorf("%w: no repository named %s", errChartNotFound, repoName)
+	}
+
+	indexFile, err := repo.LoadIndexFile(
+		filepath.Join(h.EnvSettings.RepositoryCache, helmpath.CacheIndexFile(repoName)))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(indexFile.Entries[name]) == 0 {
+		return nil, "", fmt.Errorf("%w: %s", errChartNotFound, chartName)
+	}
+
+	return indexFile, name, nil
+}
+
+// loadChartVersion downloads the requested chart version to the repository
+// cache, like helm show, and loads it.
+func (h *Handler) loadChartVersion(req ChartFileRequest) (*chart.Chart, error) {
+	version := req.Version
+
+	if !registry.IsOCI(req.Chart) {
+		indexFile, name, err := h.loadChartIndex(req.Chart)
+		if err != nil {
+			return nil, err
+		}
+
+		chartVersion, err := indexFile.Get(name, req.Version)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errChartNotFound, err)
+		}
+
+		version = chartVersion.Version
+	}
+
+	actionConfig := &action.Configuration{}
+	if err := h.setRegistryClient(actionConfig, req.PlainHTTP); err != nil {
+		return nil, err
+	}
+
+	showClient := action.NewShowWithConfig(action.ShowAll, actionConfig)
+	showClient.Version = version
+	showClient.PlainHTTP = req.PlainHTTP
+
+	chartPath, err := h.locateChart(showClient.ChartPathOptions, req.Chart)
+	if err != nil {
+		return nil, err
+	}
+
+	return loader.Load(chartPath)
+}
+
+// chartErrorStatus returns the response status of an error listing or loading a chart.
+func chartErrorStatus(err error) int {
+	var (
+		fileErr    chartFileNotFoundError
+		requestErr chartRequestError
+	)
+
+	switch {
+	case errors.Is(err, errNotRepoChart), errors.As(err, &requestErr):
+		return http.StatusBadRequest
+	case errors.Is(err, errChartNotFound), errors.As(err, &fileErr):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
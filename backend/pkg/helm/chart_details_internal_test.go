@@ -0,0 +1,113 @@
+package helm
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func TestListChartVersions(t *testing.T) {
+	h := newChartRepoTestHandler(t)
+
+	rr := serveTestRequest(t, h.ListChartVersions, http.MethodGet, "/charts/versions?chart=local/mychart", nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response ListChartVersionsResponse
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	require.Len(t, response.Versions, 2)
+	assert.Equal(t, "0.2.0", response.Versions[0].Version)
+	assert.Equal(t, "0.1.0", response.Versions[1].Version)
+
+	rr = serveTestRequest(t, h.ListChartVersions, http.MethodGet, "/charts/versions?chart=local/other", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = serveTestRequest(t, h.ListChartVersions, http.MethodGet, "/charts/versions?chart=mychart", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestGetChartFiles(t *testing.T) {
+	h := newChartRepoTestHandler(t)
+
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "readme of latest version",
+			handler:    h.GetChartReadme,
+			query:      "chart=local/mychart",
+			wantStatus: http.StatusOK,
+			wantBody:   "# mychart\n",
+		},
+		{
+			name:       "no readme",
+			handler:    h.GetChartReadme,
+			query:      "chart=local/mychart&version=0.1.0",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "values",
+			handler:    h.GetChartValues,
+			query:      "chart=local/mychart&version=0.1.0",
+			wantStatus: http.StatusOK,
+			wantBody:   "replicas: 1\nimage: nginx\n",
+		},
+		{
+			name:       "schema",
+			handler:    h.GetChartValuesSchema,
+			query:      "chart=local/mychart&version=^0.2",
+			wantStatus: http.StatusOK,
+			wantBody:   testChartSchema,
+		},
+		{
+			name:       "metadata",
+			handler:    h.GetChartMetadata,
+			query:      "chart=local/mychart&version=0.1.0",
+			wantStatus: http.StatusOK,
+			wantBody:   "apiVersion: v2\nname: mychart\nversion: 0.1.0\n",
+		},
+		{
+			name:       "unknown version",
+			handler:    h.GetChartMetadata,
+			query:      "chart=local/mychart&version=1.0.0",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := serveTestRequest(t, tt.handler, http.MethodGet, "/charts/file?"+tt.query, nil)
+			require.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestChartRawFile(t *testing.T) {
+	chartYAML := []byte("# The chart.\napiVersion: v2\nname: mychart\nversion: 0.1.0\n")
+	testChart := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "mychart", Version: "0.1.0"},
+		Raw:      []*chart.File{{Name: chartutil.ChartfileName, Data: chartYAML}},
+	}
+
+	data, ok, err := chartRawFile(chartutil.ChartfileName)(testChart)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, chartYAML, data)
+
+	_, ok, err = chartRawFile(chartutil.ValuesfileName)(testChart)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -26,13 +26,19 @@ import (
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
 
 	"helm.sh/helm/v3/cmd/helm/search"
-	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/helmpath"
 	"helm.sh/helm/v3/pkg/repo"
 )
 
 type ListAllChartsResponse struct {
 	Charts []chartInfo `json:"charts"`
+	// Warnings are about repositories whose charts are missing or may be outdated.
+	Warnings []repositoryWarning `json:"warnings,omitempty"`
+}
+
+type repositoryWarning struct {
+	Repository string `json:"repository"`
+	Message    string `json:"message"`
 }
 
 type chartInfo struct {
@@ -43,14 +49,22 @@ type chartInfo struct {
 	Repository  string `json:"repository"`
 }
 
-func listCharts(filter string, settings *cli.EnvSettings) ([]chartInfo, error) {
+// listCharts lists the charts in the cached indexes of the configured
+// repositories. Repositories whose index can't be loaded are skipped, and
+// reported in the warnings with those whose last refresh failed.
+func (h *Handler) listCharts(filter string) ([]chartInfo, []repositoryWarning, error) {
+	settings := h.EnvSettings
+
 	// read repo file
 	repoFile, err := repo.LoadFile(settings.RepositoryConfig)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	var chartInfos []chartInfo
+	var (
+		chartInfos []chartInfo
+		warnings   []repositoryWarning
+	)
 
 	for _, re := range repoFile.Repositories {
 		index := search.NewIndex()
@@ -60,7 +74,18 @@ func listCharts(filter string, settings *cli.EnvSettings) ([]chartInfo, error) {
 
 		indexFile, err := repo.LoadIndexFile(repoIndexFile)
 		if err != nil {
-			return nil, err
+			logger.Log(logger.LevelWarn, map[string]string{"repository": name}, err, "loading repository index")
+
+			warnings = append(warnings, repositoryWarning{Repository: name, Message: err.Error()})
+
+			continue
+		}
+
+		if status := h.refreshStatus(name); status.LastError != "" {
+			warnings = append(warnings, repositoryWarning{
+				Repository: name,
+				Message:    "index may be outdated, last refresh failed: " + status.LastError,
+			})
 		}
 
 		index.AddRepo(name, indexFile, true)
@@ -88,14 +113,14 @@ func listCharts(filter string, settings *cli.EnvSettings) ([]chartInfo, error) {
 		}
 	}
 
-	return chartInfos, nil
+	return chartInfos, warnings, nil
 }
 
 // ListCharts lists all charts from configured repositories.
 func (h *Handler) ListCharts(w http.ResponseWriter, r *http.Request) {
 	filterTerm := r.URL.Query().Get("filter")
 
-	chartInfos, err := listCharts(filterTerm, h.EnvSettings)
+	chartInfos, warnings, err := h.listCharts(filterTerm)
 	if err != nil {
 		logger.Log(logger.LevelError, nil, err, "listing charts")
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -104,7 +129,8 @@ func (h *Handler) ListCharts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := ListAllChartsResponse{
-		Charts: chartInfos,
+		Charts:   chartInfos,
+		Warnings: warnings,
 	}
 
 	var buf bytes.Buffer
@@ -0,0 +1,410 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/pmezard/go-difflib/difflib"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+)
+
+const opDiffRelease = "diff_release"
+
+// ReleaseDiffRequest asks for the difference between the deployed release and
+// a proposed upgrade, or between two revisions of the release when
+// FromRevision and ToRevision are set.
+type ReleaseDiffRequest struct {
+	Name      string `json:"name" validate:"required"`
+	Namespace string `json:"namespace" validate:"required"`
+	// Chart, Version and Values describe the proposed upgrade, as in UpgradeReleaseRequest.
+	Chart   string `json:"chart"`
+	Version string `json:"version"`
+	Values  string `json:"values"`
+	// DryRun is how the proposed upgrade is rendered, "client" (the default) or "server".
+	DryRun       string `json:"dryRun,omitempty" validate:"omitempty,oneof=client server"`
+	PlainHTTP    bool   `json:"plainHTTP,omitempty"`
+	FromRevision int    `json:"fromRevision" validate:"gte=0"`
+	ToRevision   int    `json:"toRevision" validate:"gte=0"`
+	// ActionOptions and PostRenderer are the options of the proposed upgrade,
+	// as in UpgradeReleaseRequest, e.g. ReuseValues changes the values it renders with.
+	ActionOptions
+	PostRenderer *PostRendererRequest `json:"postRenderer,omitempty"`
+}
+
+func (req *ReleaseDiffRequest) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return err
+	}
+
+	if (req.FromRevision == 0) != (req.ToRevision == 0) {
+		return errors.New("fromRevision and toRevision must be set together")
+	}
+
+	if req.FromRevision == 0 && req.Chart == "" {
+		return errors.New("chart is required to diff an upgrade")
+	}
+
+	if req.FromRevision != 0 && (req.Chart != "" || req.PostRenderer != nil) {
+		return errors.New("chart and postRenderer cannot be set when diffing revisions")
+	}
+
+	if err := req.PostRenderer.Validate(); err != nil {
+		return err
+	}
+
+	return req.ActionOptions.Validate("upgrade")
+}
+
+// ResourceDiff is the change of a single resource between two manifests.
+type ResourceDiff struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	// Diff is a unified diff of the resource's YAML, with keys sorted.
+	Diff string `json:"diff"`
+}
+
+// ReleaseDiffResponse lists the resources added, changed and removed going from From to To.
+type ReleaseDiffResponse struct {
+	From    string         `json:"from"`
+	To      string         `json:"to"`
+	Added   []ResourceDiff `json:"added"`
+	Changed []ResourceDiff `json:"changed"`
+	Removed []ResourceDiff `json:"removed"`
+}
+
+// DiffRelease diffs a proposed upgrade against the deployed release, or two revisions of a release.
+func (h *Handler) DiffRelease(clientConfig clientcmd.ClientConfig, w http.ResponseWriter, r *http.Request) {
+	var req ReleaseDiffRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, req.Name, err, "parsing request for diff", http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		handleError(w, req.Name, err, "validating request for diff", http.StatusBadRequest)
+		return
+	}
+
+	actionConfig, err := NewActionConfig(clientConfig, req.Namespace)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRequest: opDiffRelease},
+			err, "creating action config")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if err = h.setRegistryClient(actionConfig, req.PlainHTTP); err != nil {
+		handleError(w, req.Name, err, "creating registry client", http.StatusInternalServerError)
+		return
+	}
+
+	response, err := h.diffRelease(req, actionConfig)
+	if errors.Is(err, driver.ErrReleaseNotFound) || errors.Is(err, driver.ErrNoDeployedReleases) {
+		handleError(w, req.Name, err, "release not found", http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		handleError(w, req.Name, err, "diffing release", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRequest: opDiffRelease, logFieldReleaseName: req.Name},
+			err, "encoding response")
+	}
+}
+
+// diffRelease loads or renders the two manifests of the request and diffs them.
+func (h *Handler) diffRelease(req ReleaseDiffRequest, actionConfig *action.Configuration) (ReleaseDiffResponse, error) {
+	var (
+		response ReleaseDiffResponse
+		from, to *release.Release
+		err      error
+	)
+
+	if req.FromRevision != 0 {
+		if from, err = actionConfig.Releases.Get(req.Name, req.FromRevision); err != nil {
+			return response, err
+		}
+
+		if to, err = actionConfig.Releases.Get(req.Name, req.ToRevision); err != nil {
+			return response, err
+		}
+
+		response.From = fmt.Sprintf("revision %d", req.FromRevision)
+		response.To = fmt.Sprintf("revision %d", req.ToRevision)
+	} else {
+		if from, err = actionConfig.Releases.Deployed(req.Name); err != nil {
+			return response, err
+		}
+
+		dryRun := req.DryRun
+		if dryRun == "" {
+			dryRun = dryRunClient
+		}
+
+		upgrade := UpgradeReleaseRequest{
+			CommonInstallUpdateRequest: CommonInstallUpdateRequest{
+				Name:          req.Name,
+				Namespace:     req.Namespace,
+				Chart:         req.Chart,
+				Version:       req.Version,
+				Values:        req.Values,
+				DryRun:        dryRun,
+				PlainHTTP:     req.PlainHTTP,
+				ActionOptions: req.ActionOptions,
+				PostRenderer:  req.PostRenderer,
+			},
+		}
+
+		if upgrade.postRenderer, err = h.postRenderer(req.PostRenderer); err != nil {
+			return response, err
+		}
+
+		to, err = h.renderUpgrade(upgrade, actionConfig)
+		if err != nil {
+			return response, fmt.Errorf("rendering upgrade: %w", err)
+		}
+
+		response.From = fmt.Sprintf("revision %d (deployed)", from.Version)
+		response.To = "proposed upgrade"
+	}
+
+	response.Added, response.Changed, response.Removed, err = diffManifests(from.Manifest, to.Manifest,
+		response.From, response.To)
+
+	return response, err
+}
+
+// secretDataFields are the fields of a Secret whose values are redacted in diffs.
+var secretDataFields = []string{"data", "stringData"}
+
+// manifestResource is a resource parsed from a release manifest.
+type manifestResource struct {
+	ResourceDiff
+	obj map[string]interface{}
+	// yaml is the resource normalized by re-marshaling it, so that formatting
+	// differences don't show up in the diff.
+	yaml string
+}
+
+// parseManifestResources parses the documents of a release manifest, keyed by kind, namespace and name.
+func parseManifestResources(manifest string) (map[string]manifestResource, error) {
+	resources := map[string]manifestResource{}
+
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			return nil, err
+		}
+
+		if len(obj) == 0 {
+			continue
+		}
+
+		normalized, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		var head struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+			Metadata   struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+		}
+
+		if err := yaml.Unmarshal(normalized, &head); err != nil {
+			return nil, err
+		}
+
+		key := head.Kind + "/" + head.Metadata.Namespace + "/" + head.Metadata.Name
+		resources[key] = manifestResource{
+			ResourceDiff: ResourceDiff{
+				APIVersion: head.APIVersion,
+				Kind:       head.Kind,
+				Namespace:  head.Metadata.Namespace,
+				Name:       head.Metadata.Name,
+			},
+			obj:  obj,
+			yaml: string(normalized),
+		}
+	}
+
+	return resources, nil
+}
+
+// diffManifests compares two release manifests resource by resource.
+// Resources are matched by kind, namespace and name, and each result list is sorted by that key.
+func diffManifests(fromManifest, toManifest, fromName, toName string) (
+	added, changed, removed []ResourceDiff, err error,
+) {
+	fromResources, err := parseManifestResources(fromManifest)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("parsing %s: %w", fromName, err)
+	}
+
+	toResources, err := parseManifestResources(toManifest)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("parsing %s: %w", toName, err)
+	}
+
+	keys := make([]string, 0, len(fromResources)+len(toResources))
+	for key := range fromResources {
+		keys = append(keys, key)
+	}
+
+	for key := range toResources {
+		if _, ok := fromResources[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	added, changed, removed = []ResourceDiff{}, []ResourceDiff{}, []ResourceDiff{}
+
+	for _, key := range keys {
+		before, inFrom := fromResources[key]
+		after, inTo := toResources[key]
+
+		if err := redactSecrets(&before, &after); err != nil {
+			return nil, nil, nil, err
+		}
+
+		switch {
+		case !inFrom:
+			after.Diff = unifiedDiff("", after.yaml, fromName, toName)
+			added = append(added, after.ResourceDiff)
+		case !inTo:
+			before.Diff = unifiedDiff(before.yaml, "", fromName, toName)
+			removed = append(removed, before.ResourceDiff)
+		case before.yaml != after.yaml:
+			after.Diff = unifiedDiff(before.yaml, after.yaml, fromName, toName)
+			changed = append(changed, after.ResourceDiff)
+		}
+	}
+
+	return added, changed, removed, nil
+}
+
+// redactSecrets replaces the values of the Secret in before and after, either
+// of which may be missing, like helm diff does: unchanged values are shown as
+// REDACTED, and changed ones as removed or added, with their sizes.
+func redactSecrets(before, after *manifestResource) error {
+	if before.Kind != "Secret" && after.Kind != "Secret" {
+		return nil
+	}
+
+	for _, field := range secretDataFields {
+		beforeData, _ := before.obj[field].(map[string]interface{})
+		afterData, _ := after.obj[field].(map[string]interface{})
+		decoded := field == "data"
+
+		for key, value := range beforeData {
+			afterValue, inAfter := afterData[key]
+			if inAfter && fmt.Sprint(afterValue) == fmt.Sprint(value) {
+				redacted := redactedValue("REDACTED", value, decoded)
+				beforeData[key], afterData[key] = redacted, redacted
+
+				continue
+			}
+
+			beforeData[key] = redactedValue("--------", value, decoded)
+
+			if inAfter {
+				afterData[key] = redactedValue("++++++++", afterValue, decoded)
+			}
+		}
+
+		for key, value := range afterData {
+			if _, inBefore := beforeData[key]; !inBefore {
+				afterData[key] = redactedValue("++++++++", value, decoded)
+			}
+		}
+	}
+
+	for _, resource := range []*manifestResource{before, after} {
+		if resource.obj == nil {
+			continue
+		}
+
+		normalized, err := yaml.Marshal(resource.obj)
+		if err != nil {
+			return err
+		}
+
+		resource.yaml = string(normalized)
+	}
+
+	return nil
+}
+
+// redactedValue returns a placeholder for a Secret value with its size, in
+// bytes once decoded for the base64 values of data.
+func redactedValue(placeholder string, value interface{}, decoded bool) string {
+	text := fmt.Sprint(value)
+	size := len(text)
+
+	if decoded {
+		if data, err := base64.StdEncoding.DecodeString(text); err == nil {
+			size = len(data)
+		}
+	}
+
+	return fmt.Sprintf("%s # (%d bytes)", placeholder, size)
+}
+
+func unifiedDiff(before, after, fromName, toName string) string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(before),
+		B:        difflib.SplitLines(after),
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  3,
+	})
+	if err != nil {
+		// The diff is written to memory, which cannot fail.
+		return ""
+	}
+
+	return diff
+}
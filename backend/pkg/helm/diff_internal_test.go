@@ -0,0 +1,235 @@
+package helm
+
+import (
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+func newTestRelease(name string, version int, status release.Status, manifest string) *release.Release {
+	return &release.Release{
+		Name:      name,
+		Namespace: "default",
+		Version:   version,
+		Info:      &release.Info{Status: status},
+		Manifest:  manifest,
+	}
+}
+
+func TestDiffManifests(t *testing.T) {
+	from := "---\n# Source: c/templates/cm.yaml\napiVersion: v1\nkind: ConfigMap\n" +
+		"metadata:\n  name: cm\ndata:\n  a: \"1\"\n" +
+		"---\n# Source: c/templates/old.yaml\napiVersion: v1\nkind: Service\nmetadata:\n  name: old\n" +
+		"---\n# Source: c/templates/same.yaml\nkind: Secret\napiVersion: v1\nmetadata:\n  name: same\n"
+	// Reordered keys in the Secret are not a change.
+	to := "---\n# Source: c/templates/cm.yaml\napiVersion: v1\nkind: ConfigMap\n" +
+		"metadata:\n  name: cm\ndata:\n  a: \"2\"\n" +
+		"---\n# Source: c/templates/new.yaml\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: new\n" +
+		"  namespace: apps\n" +
+		"---\n# Source: c/templates/same.yaml\napiVersion: v1\nkind: Secret\nmetadata:\n  name: same\n"
+
+	added, changed, removed, err := diffManifests(from, to, "revision 1", "revision 2")
+	require.NoError(t, err)
+
+	require.Len(t, added, 1)
+	assert.Equal(t, "Deployment", added[0].Kind)
+	assert.Equal(t, "apps", added[0].Namespace)
+	assert.Contains(t, added[0].Diff, "+kind: Deployment")
+
+	require.Len(t, changed, 1)
+	assert.Equal(t, ResourceDiff{
+		APIVersion: "v1", Kind: "ConfigMap", Name: "cm",
+		Diff: changed[0].Diff,
+	}, changed[0])
+	assert.Contains(t, changed[0].Diff, "--- revision 1")
+	assert.Contains(t, changed[0].Diff, "+++ revision 2")
+	assert.Contains(t, changed[0].Diff, "-  a: \"1\"")
+	assert.Contains(t, changed[0].Diff, "+  a: \"2\"")
+
+	require.Len(t, removed, 1)
+	assert.Equal(t, "old", removed[0].Name)
+	assert.Contains(t, removed[0].Diff, "-kind: Service")
+}
+
+func TestDiffManifestsRedactsSecrets(t *testing.T) {
+	from := "---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\n" +
+		"data:\n  user: YWRtaW4=\n  password: b2xkLXBhc3M=\n"
+	to := "---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\n" +
+		"data:\n  user: YWRtaW4=\n  password: bmV3LXBhc3N3b3Jk\n" +
+		"stringData:\n  token: s3cr3t\n"
+
+	added, changed, removed, err := diffManifests(from, to, "revision 1", "revision 2")
+	require.NoError(t, err)
+	assert.Empty(t, added)
+	assert.Empty(t, removed)
+	require.Len(t, changed, 1)
+
+	diff := changed[0].Diff
+	assert.Contains(t, diff, "-  password: '-------- # (8 bytes)'")
+	assert.Contains(t, diff, "+  password: '++++++++ # (12 bytes)'")
+	assert.Contains(t, diff, "   user: 'REDACTED # (5 bytes)'")
+	assert.Contains(t, diff, "+  token: '++++++++ # (6 bytes)'")
+
+	for _, secret := range []string{"YWRtaW4=", "b2xkLXBhc3M=", "bmV3LXBhc3N3b3Jk", "s3cr3t"} {
+		assert.NotContains(t, diff, secret)
+	}
+
+	added, _, _, err = diffManifests("", to, "revision 1", "revision 2")
+	require.NoError(t, err)
+	require.Len(t, added, 1)
+	assert.NotContains(t, added[0].Diff, "bmV3LXBhc3N3b3Jk")
+}
+
+func TestDiffReleaseRevisions(t *testing.T) {
+	actionConfig := newMemoryActionConfig(t)
+	require.NoError(t, actionConfig.Releases.Create(newTestRelease("demo", 1, release.StatusSuperseded,
+		"---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\ndata:\n  a: \"1\"\n")))
+	require.NoError(t, actionConfig.Releases.Create(newTestRelease("demo", 2, release.StatusDeployed,
+		"---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\ndata:\n  a: \"2\"\n")))
+
+	h := &Handler{Cache: cache.New[interface{}](), EnvSettings: cli.New()}
+
+	response, err := h.diffRelease(ReleaseDiffRequest{
+		Name: "demo", Namespace: "default", FromRevision: 1, ToRevision: 2,
+	}, actionConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "revision 1", response.From)
+	assert.Equal(t, "revision 2", response.To)
+	assert.Empty(t, response.Added)
+	assert.Empty(t, response.Removed)
+	require.Len(t, response.Changed, 1)
+	assert.Equal(t, "cm", response.Changed[0].Name)
+
+	_, err = h.diffRelease(ReleaseDiffRequest{
+		Name: "demo", Namespace: "default", FromRevision: 1, ToRevision: 5,
+	}, actionConfig)
+	assert.ErrorIs(t, err, driver.ErrReleaseNotFound)
+}
+
+func TestDiffReleaseProposedUpgrade(t *testing.T) {
+	chartDir := writeTestChart(t)
+	chart, err := loader.Load(chartDir)
+	require.NoError(t, err)
+
+	deployed := newTestRelease("demo", 1, release.StatusDeployed,
+		"---\n# Source: mychart/templates/configmap.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n"+
+			"  name: demo-config\ndata:\n  replicas: \"1\"\n  image: nginx\n"+
+			"---\n# Source: mychart/templates/legacy.yaml\napiVersion: v1\nkind: Service\nmetadata:\n  name: legacy\n")
+	deployed.Chart = chart
+
+	actionConfig := newMemoryActionConfig(t)
+	require.NoError(t, actionConfig.Releases.Create(deployed))
+
+	h := &Handler{Cache: cache.New[interface{}](), EnvSettings: cli.New()}
+
+	response, err := h.diffRelease(ReleaseDiffRequest{
+		Name: "demo", Namespace: "default", Chart: chartDir, Version: "0.1.0",
+		Values: "cmVwbGljYXM6IDIK", // replicas: 2
+	}, actionConfig)
+	require.NoError(t, err)
+
+	assert.Equal(t, "revision 1 (deployed)", response.From)
+	assert.Equal(t, "proposed upgrade", response.To)
+
+	names := func(diffs []ResourceDiff) []string {
+		result := []string{}
+		for _, diff := range diffs {
+			result = append(result, diff.Kind+"/"+diff.Name)
+		}
+
+		return result
+	}
+
+	assert.Equal(t, []string{"ConfigMap/demo-config"}, names(response.Changed))
+	assert.Contains(t, response.Changed[0].Diff, "+  replicas: \"2\"")
+	assert.Equal(t, []string{"Secret/first", "Secret/second"}, names(response.Added))
+	assert.Equal(t, []string{"Service/legacy"}, names(response.Removed))
+
+	last, err := actionConfig.Releases.Last("demo")
+	require.NoError(t, err)
+	assert.Equal(t, 1, last.Version, "a diff must not create a release revision")
+}
+
+func TestDiffReleaseProposedUpgradeOptions(t *testing.T) {
+	chartDir := writeTestChart(t)
+	chart, err := loader.Load(chartDir)
+	require.NoError(t, err)
+
+	deployed := newTestRelease("demo", 1, release.StatusDeployed,
+		"---\n# Source: mychart/templates/configmap.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n"+
+			"  name: demo-config\ndata:\n  replicas: \"3\"\n  image: nginx\n")
+	deployed.Chart = chart
+	deployed.Config = map[string]interface{}{"replicas": 3}
+
+	actionConfig := newMemoryActionConfig(t)
+	require.NoError(t, actionConfig.Releases.Create(deployed))
+
+	h := &Handler{Cache: cache.New[interface{}](), EnvSettings: cli.New()}
+
+	// The upgrade reuses the deployed values, and its manifests are post-rendered.
+	response, err := h.diffRelease(ReleaseDiffRequest{
+		Name: "demo", Namespace: "default", Chart: chartDir, Version: "0.1.0",
+		ActionOptions: ActionOptions{ReuseValues: true},
+		PostRenderer: &PostRendererRequest{Patches: []ManifestPatch{{
+			Type:   "json",
+			Patch:  `[{"op": "add", "path": "/data/mode", "value": "fast"}]`,
+			Target: &PatchTarget{Kind: "ConfigMap", Name: "demo-config"},
+		}}},
+	}, actionConfig)
+	require.NoError(t, err)
+
+	require.Len(t, response.Changed, 1)
+	assert.Contains(t, response.Changed[0].Diff, "+  mode: fast")
+	assert.Contains(t, response.Changed[0].Diff, "   replicas: \"3\"", "the deployed values must be reused")
+}
+
+func TestReleaseDiffRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     ReleaseDiffRequest
+		wantErr bool
+	}{
+		{name: "upgrade", req: ReleaseDiffRequest{Name: "a", Namespace: "b", Chart: "repo/c"}},
+		{name: "revisions", req: ReleaseDiffRequest{Name: "a", Namespace: "b", FromRevision: 1, ToRevision: 2}},
+		{name: "no chart", req: ReleaseDiffRequest{Name: "a", Namespace: "b"}, wantErr: true},
+		{name: "one revision", req: ReleaseDiffRequest{Name: "a", Namespace: "b", FromRevision: 1}, wantErr: true},
+		{
+			name:    "chart and revisions",
+			req:     ReleaseDiffRequest{Name: "a", Namespace: "b", Chart: "c", FromRevision: 1, ToRevision: 2},
+			wantErr: true,
+		},
+		{
+			name: "post-renderer and revisions",
+			req: ReleaseDiffRequest{
+				Name: "a", Namespace: "b", FromRevision: 1, ToRevision: 2, PostRenderer: &PostRendererRequest{},
+			},
+			wantErr: true,
+		},
+		{
+			name: "reuse and reset values",
+			req: ReleaseDiffRequest{
+				Name: "a", Namespace: "b", Chart: "c",
+				ActionOptions: ActionOptions{ReuseValues: true, ResetValues: true},
+			},
+			wantErr: true,
+		},
+		{name: "bad dry run", req: ReleaseDiffRequest{Name: "a", Namespace: "b", Chart: "c", DryRun: "x"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
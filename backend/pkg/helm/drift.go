@@ -0,0 +1,363 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	cliresource "k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const opGetReleaseDrift = "get_release_drift"
+
+// Drift status of a resource of a release.
+const (
+	driftInSync  = "in-sync"
+	driftChanged = "drifted"
+	driftMissing = "missing"
+	// driftUnknown is used when the live object could not be read.
+	driftUnknown = "unknown"
+)
+
+// ReleaseDrift compares the resources of a release manifest with the cluster.
+type ReleaseDrift struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Revision  int    `json:"revision"`
+	// Drifted is true if any resource is missing or changed.
+	Drifted   bool            `json:"drifted"`
+	Resources []ResourceDrift `json:"resources"`
+}
+
+// ResourceDrift is the drift of one resource of the manifest.
+type ResourceDrift struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	// Status is in-sync, drifted, missing, or unknown if the live object could not be read.
+	Status string       `json:"status"`
+	Fields []FieldDrift `json:"fields,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// FieldDrift is a field set by the chart whose live value differs.
+type FieldDrift struct {
+	// Path is the field path, e.g. spec.template.spec.containers[name=web].image.
+	Path     string      `json:"path"`
+	Expected interface{} `json:"expected"`
+	// Actual is the live value, or null if the field was removed.
+	Actual interface{} `json:"actual"`
+}
+
+// liveObjectFunc returns the live object of a manifest resource.
+type liveObjectFunc func(info *cliresource.Info) (map[string]interface{}, error)
+
+// GetReleaseDrift compares the manifest of a release revision with the live
+// objects in the cluster. Only the fields set by the chart are compared, so
+// fields defaulted by the API server or set by controllers aren't drift. The
+// response is always JSON.
+func (h *Handler) GetReleaseDrift(clientConfig clientcmd.ClientConfig, w http.ResponseWriter, r *http.Request) {
+	var req ReleaseDetailRequest
+
+	rel, actionConfig, ok := h.getReleaseForDetail(clientConfig, w, r, &req, &req, opGetReleaseDrift)
+	if !ok {
+		return
+	}
+
+	resources, err := actionConfig.KubeClient.Build(bytes.NewBufferString(rel.Manifest), false)
+	if err != nil {
+		handleError(w, req.Name, err, "building manifest resources", http.StatusInternalServerError)
+		return
+	}
+
+	drift := ReleaseDrift{
+		Name:      rel.Name,
+		Namespace: rel.Namespace,
+		Revision:  rel.Version,
+		Resources: resourcesDrift(resources, getLiveObject),
+	}
+
+	for _, resource := range drift.Resources {
+		if resource.Status == driftChanged || resource.Status == driftMissing {
+			drift.Drifted = true
+		}
+	}
+
+	writeReleaseDetailJSON(w, req, opGetReleaseDrift, drift)
+}
+
+// getLiveObject gets the live object of a resource built by the kube client.
+func getLiveObject(info *cliresource.Info) (map[string]interface{}, error) {
+	obj, err := cliresource.NewHelper(info.Client, info.Mapping).Get(info.Namespace, info.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
+
+// resourcesDrift returns the drift of each resource, in manifest order.
+func resourcesDrift(resources kube.ResourceList, getLive liveObjectFunc) []ResourceDrift {
+	drifts := make([]ResourceDrift, 0, len(resources))
+
+	for _, info := range resources {
+		drift := ResourceDrift{Namespace: info.Namespace, Name: info.Name, Status: driftInSync}
+
+		if info.Mapping != nil {
+			drift.APIVersion = info.Mapping.GroupVersionKind.GroupVersion().String()
+			drift.Kind = info.Mapping.GroupVersionKind.Kind
+		}
+
+		desired, err := runtime.DefaultUnstructuredConverter.ToUnstructured(info.Object)
+		if err == nil {
+			var live map[string]interface{}
+
+			live, err = getLive(info)
+			if err == nil {
+				drift.Fields = objectDrift(drift.Kind, desired, live)
+			}
+		}
+
+		switch {
+		case apierrors.IsNotFound(err):
+			drift.Status = driftMissing
+		case err != nil:
+			drift.Status = driftUnknown
+			drift.Error = err.Error()
+		case len(drift.Fields) > 0:
+			drift.Status = driftChanged
+		}
+
+		drifts = append(drifts, drift)
+	}
+
+	return drifts
+}
+
+// objectDrift returns the fields of desired whose value differs in live.
+// The status isn't owned by the chart and is never compared.
+func objectDrift(kind string, desired, live map[string]interface{}) []FieldDrift {
+	desired = normalizeDesiredObject(kind, desired)
+	fields := []FieldDrift{}
+
+	for _, key := range sortedKeys(desired) {
+		if key == "status" {
+			continue
+		}
+
+		fieldDrift(fieldPath("", key), desired[key], live[key], &fields)
+	}
+
+	return fields
+}
+
+// normalizeDesiredObject converts fields the API server rewrites, so they can
+// be compared with the live object. Secret stringData is stored as base64 data.
+func normalizeDesiredObject(kind string, obj map[string]interface{}) map[string]interface{} {
+	stringData, ok := obj["stringData"].(map[string]interface{})
+	if kind != "Secret" || !ok {
+		return obj
+	}
+
+	normalized := make(map[string]interface{}, len(obj))
+	for key, value := range obj {
+		normalized[key] = value
+	}
+
+	data := map[string]interface{}{}
+	if existing, ok := obj["data"].(map[string]interface{}); ok {
+		for key, value := range existing {
+			data[key] = value
+		}
+	}
+
+	for key, value := range stringData {
+		data[key] = base64.StdEncoding.EncodeToString([]byte(fmt.Sprint(value)))
+	}
+
+	normalized["data"] = data
+	delete(normalized, "stringData")
+
+	return normalized
+}
+
+// fieldDrift appends the differences between a desired value and the live value at path.
+// Maps are compared on the desired keys only. Lists whose items all have a
+// name are matched by name, other lists by index.
+func fieldDrift(path string, desired, live interface{}, fields *[]FieldDrift) {
+	if desired == nil {
+		return
+	}
+
+	switch desiredValue := desired.(type) {
+	case map[string]interface{}:
+		liveMap, ok := live.(map[string]interface{})
+		if !ok {
+			*fields = append(*fields, FieldDrift{Path: path, Expected: desired, Actual: live})
+			return
+		}
+
+		for _, key := range sortedKeys(desiredValue) {
+			fieldDrift(fieldPath(path, key), desiredValue[key], liveMap[key], fields)
+		}
+	case []interface{}:
+		liveList, ok := live.([]interface{})
+		if !ok {
+			*fields = append(*fields, FieldDrift{Path: path, Expected: desired, Actual: live})
+			return
+		}
+
+		listDrift(path, desiredValue, liveList, fields)
+	default:
+		if !scalarsEqual(path, desired, live) {
+			*fields = append(*fields, FieldDrift{Path: path, Expected: desired, Actual: live})
+		}
+	}
+}
+
+func listDrift(path string, desired, live []interface{}, fields *[]FieldDrift) {
+	desiredByName, desiredNamed := itemsByName(desired)
+	liveByName, liveNamed := itemsByName(live)
+
+	if desiredNamed && liveNamed {
+		for _, item := range desired {
+			name := item.(map[string]interface{})["name"].(string)
+			fieldDrift(fmt.Sprintf("%s[name=%s]", path, name), desiredByName[name], liveByName[name], fields)
+		}
+
+		return
+	}
+
+	if len(desired) != len(live) {
+		*fields = append(*fields, FieldDrift{Path: path, Expected: desired, Actual: live})
+		return
+	}
+
+	for i := range desired {
+		fieldDrift(fmt.Sprintf("%s[%d]", path, i), desired[i], live[i], fields)
+	}
+}
+
+// itemsByName indexes the items of a list by their name field. It returns
+// false if an item has no name, e.g. in a list of strings.
+func itemsByName(items []interface{}) (map[string]interface{}, bool) {
+	byName := make(map[string]interface{}, len(items))
+
+	for _, item := range items {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		name, ok := itemMap["name"].(string)
+		if !ok {
+			return nil, false
+		}
+
+		byName[name] = item
+	}
+
+	return byName, true
+}
+
+// quantityPath matches the paths of the fields holding resource quantities,
+// which the API server may return in another form: the limits and requests of
+// resources, and storage capacities.
+var quantityPath = regexp.MustCompile(`(^|\.)(resources\.(limits|requests)(\.[^.\[]+|\["[^"]*"\])|storage)$`)
+
+// scalarsEqual compares scalar values, treating numbers of different types,
+// and equal quantities such as "0.5" and "500m" at a quantity path, as equal.
+func scalarsEqual(path string, desired, live interface{}) bool {
+	if reflect.DeepEqual(desired, live) {
+		return true
+	}
+
+	desiredNumber, desiredIsNumber := toFloat(desired)
+	liveNumber, liveIsNumber := toFloat(live)
+
+	if desiredIsNumber && liveIsNumber {
+		return desiredNumber == liveNumber
+	}
+
+	if !quantityPath.MatchString(path) {
+		return false
+	}
+
+	desiredQuantity, err := resource.ParseQuantity(fmt.Sprint(desired))
+	if err != nil || live == nil {
+		return false
+	}
+
+	liveQuantity, err := resource.ParseQuantity(fmt.Sprint(live))
+	if err != nil {
+		return false
+	}
+
+	return desiredQuantity.Cmp(liveQuantity) == 0
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case int64:
+		return float64(number), true
+	case int:
+		return float64(number), true
+	case float64:
+		return number, true
+	}
+
+	return 0, false
+}
+
+// fieldPath appends key to a dotted path, quoting keys that contain dots such
+// as app.kubernetes.io/name.
+func fieldPath(path, key string) string {
+	if strings.ContainsAny(key, ".[]") {
+		return path + "[" + strconv.Quote(key) + "]"
+	}
+
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,223 @@
+package helm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	cliresource "k8s.io/cli-runtime/pkg/resource"
+)
+
+func newDriftTestInfo(
+	gvk schema.GroupVersionKind,
+	resource, name string,
+	obj map[string]interface{},
+) *cliresource.Info {
+	return &cliresource.Info{
+		Namespace: "default",
+		Name:      name,
+		Mapping: &meta.RESTMapping{
+			GroupVersionKind: gvk,
+			Resource:         gvk.GroupVersion().WithResource(resource),
+			Scope:            meta.RESTScopeNamespace,
+		},
+		Object: &unstructured.Unstructured{Object: obj},
+	}
+}
+
+func TestResourcesDrift(t *testing.T) {
+	deployment := newDriftTestInfo(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		"deployments", "web", map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":   "web",
+				"labels": map[string]interface{}{"app.kubernetes.io/name": "web"},
+			},
+			"spec": map[string]interface{}{
+				"replicas": int64(2),
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{
+								"name":      "web",
+								"image":     "nginx:1.25",
+								"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "0.5"}},
+							},
+						},
+					},
+				},
+			},
+		})
+
+	secret := newDriftTestInfo(schema.GroupVersionKind{Version: "v1", Kind: "Secret"},
+		"secrets", "creds", map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]interface{}{"name": "creds"},
+			"stringData": map[string]interface{}{"password": "secret"},
+		})
+
+	configMap := newDriftTestInfo(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+		"configmaps", "settings", map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"name": "settings"},
+			"data":       map[string]interface{}{"mode": "fast"},
+		})
+
+	live := map[string]map[string]interface{}{
+		"web": {
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":            "web",
+				"namespace":       "default",
+				"resourceVersion": "42",
+				"labels":          map[string]interface{}{"app.kubernetes.io/name": "web"},
+			},
+			"spec": map[string]interface{}{
+				"replicas":             int64(5),
+				"revisionHistoryLimit": int64(10),
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{"name": "sidecar", "image": "envoy"},
+							map[string]interface{}{
+								"name":                     "web",
+								"image":                    "nginx:1.26",
+								"imagePullPolicy":          "IfNotPresent",
+								"resources":                map[string]interface{}{"limits": map[string]interface{}{"cpu": "500m"}},
+								"terminationMessagePolicy": "File",
+							},
+						},
+					},
+				},
+			},
+			"status": map[string]interface{}{"replicas": int64(5)},
+		},
+		"creds": {
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]interface{}{"name": "creds", "namespace": "default"},
+			"data":       map[string]interface{}{"password": "c2VjcmV0"},
+			"type":       "Opaque",
+		},
+	}
+
+	getLive := func(info *cliresource.Info) (map[string]interface{}, error) {
+		obj, ok := live[info.Name]
+		if !ok {
+			return nil, apierrors.NewNotFound(info.Mapping.Resource.GroupResource(), info.Name)
+		}
+
+		return obj, nil
+	}
+
+	drifts := resourcesDrift(kube.ResourceList{deployment, secret, configMap}, getLive)
+	require.Len(t, drifts, 3)
+
+	assert.Equal(t, "apps/v1", drifts[0].APIVersion)
+	assert.Equal(t, "Deployment", drifts[0].Kind)
+	assert.Equal(t, driftChanged, drifts[0].Status)
+	assert.Equal(t, []FieldDrift{
+		{Path: "spec.replicas", Expected: int64(2), Actual: int64(5)},
+		{Path: "spec.template.spec.containers[name=web].image", Expected: "nginx:1.25", Actual: "nginx:1.26"},
+	}, drifts[0].Fields)
+
+	assert.Equal(t, driftInSync, drifts[1].Status)
+	assert.Empty(t, drifts[1].Fields)
+
+	assert.Equal(t, driftMissing, drifts[2].Status)
+	assert.Equal(t, "settings", drifts[2].Name)
+}
+
+func TestObjectDrift(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired map[string]interface{}
+		live    map[string]interface{}
+		want    []FieldDrift
+	}{
+		{
+			name:    "removed field",
+			desired: map[string]interface{}{"data": map[string]interface{}{"a": "1", "b": "2"}},
+			live:    map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+			want:    []FieldDrift{{Path: "data.b", Expected: "2"}},
+		},
+		{
+			name: "quoted key",
+			desired: map[string]interface{}{"metadata": map[string]interface{}{
+				"annotations": map[string]interface{}{"example.com/owner": "team-a"},
+			}},
+			live: map[string]interface{}{"metadata": map[string]interface{}{
+				"annotations": map[string]interface{}{"example.com/owner": "team-b"},
+			}},
+			want: []FieldDrift{{Path: `metadata.annotations["example.com/owner"]`, Expected: "team-a", Actual: "team-b"}},
+		},
+		{
+			name:    "list by index",
+			desired: map[string]interface{}{"args": []interface{}{"--a", "--b"}},
+			live:    map[string]interface{}{"args": []interface{}{"--a", "--c"}},
+			want:    []FieldDrift{{Path: "args[1]", Expected: "--b", Actual: "--c"}},
+		},
+		{
+			name:    "list length",
+			desired: map[string]interface{}{"args": []interface{}{"--a"}},
+			live:    map[string]interface{}{"args": []interface{}{"--a", "--b"}},
+			want: []FieldDrift{{
+				Path: "args", Expected: []interface{}{"--a"}, Actual: []interface{}{"--a", "--b"},
+			}},
+		},
+		{
+			name:    "numbers of different types",
+			desired: map[string]interface{}{"port": float64(80)},
+			live:    map[string]interface{}{"port": int64(80)},
+			want:    []FieldDrift{},
+		},
+		{
+			name: "equal quantities",
+			desired: map[string]interface{}{"resources": map[string]interface{}{
+				"limits":   map[string]interface{}{"cpu": "0.5", "nvidia.com/gpu": 1},
+				"requests": map[string]interface{}{"storage": "1Gi"},
+			}},
+			live: map[string]interface{}{"resources": map[string]interface{}{
+				"limits":   map[string]interface{}{"cpu": "500m", "nvidia.com/gpu": "1"},
+				"requests": map[string]interface{}{"storage": "1073741824"},
+			}},
+			want: []FieldDrift{},
+		},
+		{
+			name: "strings that parse as quantities",
+			desired: map[string]interface{}{
+				"env":         []interface{}{map[string]interface{}{"name": "VERSION", "value": "1.0"}},
+				"annotations": map[string]interface{}{"timeout": "1000m"},
+			},
+			live: map[string]interface{}{
+				"env":         []interface{}{map[string]interface{}{"name": "VERSION", "value": "1"}},
+				"annotations": map[string]interface{}{"timeout": "1"},
+			},
+			want: []FieldDrift{
+				{Path: "annotations.timeout", Expected: "1000m", Actual: "1"},
+				{Path: "env[name=VERSION].value", Expected: "1.0", Actual: "1"},
+			},
+		},
+		{
+			name:    "status is ignored",
+			desired: map[string]interface{}{"status": map[string]interface{}{"phase": "Active"}},
+			live:    map[string]interface{}{},
+			want:    []FieldDrift{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, objectDrift("ConfigMap", tt.desired, tt.live))
+		})
+	}
+}
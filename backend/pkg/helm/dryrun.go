@@ -0,0 +1,193 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Dry run modes accepted in the dryRun field of install and upgrade requests.
+const (
+	// dryRunClient renders the chart without contacting the cluster, like helm template.
+	// Upgrades still read the current release to compute the values.
+	dryRunClient = "client"
+	// dryRunServer renders the chart with lookups and capabilities from the cluster.
+	dryRunServer = "server"
+)
+
+// actionDryRun is the action name under which getChart records dry run failures,
+// so that they don't overwrite the status of a real install or upgrade.
+const actionDryRun = "dryrun"
+
+// RenderedManifest is the rendered output of one chart template.
+type RenderedManifest struct {
+	// Template is the path of the template in the chart, e.g. mychart/templates/service.yaml.
+	Template string `json:"template"`
+	Content  string `json:"content"`
+	// Hook lists the hook events if the template is a hook.
+	Hook []string `json:"hook,omitempty"`
+}
+
+// DryRunResponse is what a dry run install or upgrade would apply.
+type DryRunResponse struct {
+	DryRun    string             `json:"dryRun"`
+	Manifests []RenderedManifest `json:"manifests"`
+	Notes     string             `json:"notes"`
+	// Values are the computed values: the chart defaults merged with the user supplied values.
+	Values map[string]interface{} `json:"values"`
+}
+
+func (h *Handler) dryRunInstall(w http.ResponseWriter, req InstallRequest, actionConfig *action.Configuration) {
+	installClient := newInstallClient(req, actionConfig)
+	installClient.DryRun = true
+	installClient.DryRunOption = req.DryRun
+	installClient.ClientOnly = req.DryRun == dryRunClient
+
+	chart, err := h.getRequestChart(actionDryRun, req.CommonInstallUpdateRequest,
+		installClient.ChartPathOptions, req.DependencyUpdate)
+	if err != nil {
+		handleError(w, req.Name, err, "getting chart for dry run", http.StatusBadRequest)
+
+		return
+	}
+
+	values, err := decodeValues(req.Values)
+	if err != nil {
+		handleError(w, req.Name, err, "decoding values for dry run", http.StatusBadRequest)
+
+		return
+	}
+
+	rel, err := installClient.Run(chart, values)
+	if err != nil {
+		handleError(w, req.Name, err, "dry run install", http.StatusBadRequest)
+
+		return
+	}
+
+	writeDryRunResponse(w, req.Name, req.DryRun, rel)
+}
+
+func (h *Handler) dryRunUpgrade(w http.ResponseWriter, req UpgradeReleaseRequest, actionConfig *action.Configuration) {
+	rel, err := h.renderUpgrade(req, actionConfig)
+	if err != nil {
+		handleError(w, req.Name, err, "dry run upgrade", http.StatusBadRequest)
+
+		return
+	}
+
+	writeDryRunResponse(w, req.Name, req.DryRun, rel)
+}
+
+// renderUpgrade runs the upgrade in req.DryRun mode and returns the release it would create.
+func (h *Handler) renderUpgrade(
+	req UpgradeReleaseRequest,
+	actionConfig *action.Configuration,
+) (*release.Release, error) {
+	upgradeClient := newUpgradeClient(req, actionConfig)
+	upgradeClient.DryRun = true
+	upgradeClient.DryRunOption = req.DryRun
+
+	chart, err := h.getRequestChart(actionDryRun, req.CommonInstallUpdateRequest, upgradeClient.ChartPathOptions, true)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := decodeValues(req.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	return upgradeClient.Run(req.Name, chart, values)
+}
+
+func writeDryRunResponse(w http.ResponseWriter, releaseName, dryRun string, rel *release.Release) {
+	values, err := chartutil.CoalesceValues(rel.Chart, rel.Config)
+	if err != nil {
+		handleError(w, releaseName, err, "computing values for dry run", http.StatusInternalServerError)
+
+		return
+	}
+
+	response := DryRunResponse{
+		DryRun:    dryRun,
+		Manifests: splitRenderedManifests(rel.Manifest),
+		Values:    values,
+	}
+
+	if rel.Info != nil {
+		response.Notes = rel.Info.Notes
+	}
+
+	for _, hook := range rel.Hooks {
+		events := make([]string, 0, len(hook.Events))
+		for _, event := range hook.Events {
+			events = append(events, event.String())
+		}
+
+		response.Manifests = append(response.Manifests, RenderedManifest{
+			Template: hook.Path,
+			Content:  strings.TrimSpace(hook.Manifest) + "\n",
+			Hook:     events,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldReleaseName: releaseName}, err, "encoding dry run response")
+	}
+}
+
+// splitRenderedManifests splits a release manifest into the output of each
+// template, using the "# Source:" comments Helm puts before every document.
+// Documents rendered by the same template are joined.
+func splitRenderedManifests(manifest string) []RenderedManifest {
+	manifests := []RenderedManifest{}
+	index := map[string]int{}
+
+	// Splitting on a separator line avoids splitting e.g. PEM blocks ending in "-----".
+	for _, doc := range strings.Split("\n"+manifest, "\n---\n") {
+		doc = strings.TrimSpace(doc)
+		if doc == "" {
+			continue
+		}
+
+		template := ""
+		if source, ok := strings.CutPrefix(doc, "# Source: "); ok {
+			template, doc, _ = strings.Cut(source, "\n")
+		}
+
+		if i, ok := index[template]; ok {
+			manifests[i].Content += "---\n" + doc + "\n"
+			continue
+		}
+
+		index[template] = len(manifests)
+		manifests = append(manifests, RenderedManifest{Template: template, Content: doc + "\n"})
+	}
+
+	return manifests
+}
@@ -0,0 +1,101 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+func TestInstallReleaseClientDryRun(t *testing.T) {
+	h := &Handler{
+		Cache:       cache.New[interface{}](),
+		EnvSettings: cli.New(),
+	}
+
+	body, err := json.Marshal(InstallRequest{
+		CommonInstallUpdateRequest: CommonInstallUpdateRequest{
+			Name:        "demo",
+			Namespace:   "default",
+			Description: "dry run",
+			Chart:       writeTestChart(t),
+			Version:     "0.1.0",
+			Values:      base64.StdEncoding.EncodeToString([]byte("replicas: 3\n")),
+			DryRun:      dryRunClient,
+		},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost,
+		"/clusters/test/helm/release/install", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	h.InstallRelease(newTestClientConfig(t), rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response DryRunResponse
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Equal(t, dryRunClient, response.DryRun)
+	assert.Equal(t, "Installed demo.\n", response.Notes)
+	assert.Equal(t, map[string]interface{}{"replicas": float64(3), "image": "nginx"}, response.Values)
+
+	manifests := map[string]RenderedManifest{}
+	for _, manifest := range response.Manifests {
+		manifests[manifest.Template] = manifest
+	}
+
+	require.Contains(t, manifests, "mychart/templates/configmap.yaml")
+	assert.Contains(t, manifests["mychart/templates/configmap.yaml"].Content, "name: demo-config")
+	assert.Contains(t, manifests["mychart/templates/configmap.yaml"].Content, `replicas: "3"`)
+	assert.Contains(t, manifests["mychart/templates/secrets.yaml"].Content, "name: first")
+	assert.Contains(t, manifests["mychart/templates/secrets.yaml"].Content, "name: second")
+	assert.Equal(t, []string{"test"}, manifests["mychart/templates/test.yaml"].Hook)
+
+	_, err = h.Cache.Get(context.Background(), "helm_install_demo")
+	assert.Error(t, err, "a dry run must not record an install status")
+}
+
+func TestInstallReleaseRejectsUnknownDryRunMode(t *testing.T) {
+	h := &Handler{
+		Cache:       cache.New[interface{}](),
+		EnvSettings: cli.New(),
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"name": "demo", "namespace": "default", "description": "d",
+		"chart": "repo/chart", "version": "1.0.0", "dryRun": "always",
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost,
+		"/clusters/test/helm/release/install", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	h.InstallRelease(nil, rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestSplitRenderedManifests(t *testing.T) {
+	manifest := "---\n# Source: c/templates/a.yaml\nkind: A\n" +
+		"---\n# Source: c/templates/b.yaml\nkind: Secret\ndata:\n  tls.crt: |\n    -----BEGIN CERTIFICATE-----\n" +
+		"    abc\n    -----END CERTIFICATE-----\n" +
+		"---\n# Source: c/templates/a.yaml\nkind: A2\n"
+
+	assert.Equal(t, []RenderedManifest{
+		{Template: "c/templates/a.yaml", Content: "kind: A\n---\nkind: A2\n"},
+		{
+			Template: "c/templates/b.yaml",
+			Content: "kind: Secret\ndata:\n  tls.crt: |\n    -----BEGIN CERTIFICATE-----\n" +
+				"    abc\n    -----END CERTIFICATE-----\n",
+		},
+	}, splitRenderedManifests(manifest))
+}
@@ -45,6 +45,30 @@ var (
 type Handler struct {
 	*cli.EnvSettings
 	Cache cache.Cache[interface{}]
+	// AllowLocalCharts allows installing from chart directories on the file
+	// system of the server, which is only safe when it runs as a desktop app.
+	AllowLocalCharts bool
+	// CredentialStore keeps repository credentials out of repositories.yaml.
+	// Without one, they're kept next to it like the helm CLI does.
+	CredentialStore RepositoryCredentialStore
+	// RegistryCredentialStore keeps OCI registry logins out of the registry
+	// config file. Without one, they're kept in it like the helm CLI does.
+	RegistryCredentialStore RepositoryCredentialStore
+	// Refresher refreshes the repository indexes in the background, if enabled.
+	Refresher *RepositoryRefresher
+	// History records the install, upgrade, rollback and uninstall actions, if enabled.
+	History ActionHistory
+	// Cluster is the name of the cluster of the request, which the actions in
+	// the history are recorded and queried by.
+	Cluster string
+	// PostRenderers are the executables install and upgrade requests may
+	// post-render with, by name.
+	PostRenderers map[string]string
+	// Keyring is the keyring the provenance of charts is verified with.
+	Keyring string
+	// VerifyCharts requires every chart installed or upgraded to be signed by
+	// a key of the keyring. Otherwise only the requests with verify set are.
+	VerifyCharts bool
 }
 
 func NewActionConfig(clientConfig clientcmd.ClientConfig, namespace string) (*action.Configuration, error) {
@@ -125,6 +149,12 @@ func (r *restConfigGetter) ToRESTMapper() (meta.RESTMapper, error) {
 type stat struct {
 	Status string
 	Err    *string
+	// Options are the options of the install, upgrade or rollback request.
+	Options *ActionOptions `json:",omitempty"`
+	// Tests are the results of the test action.
+	Tests []ReleaseTestResult `json:",omitempty"`
+	// Signer is who signed the chart of an install or upgrade that was verified.
+	Signer *ChartSigner `json:",omitempty"`
 }
 
 // getReleaseStatus returns the status of the release.
@@ -167,8 +197,25 @@ func (h *Handler) getReleaseStatus(actionName, releaseName string) (*stat, error
 func (h *Handler) setReleaseStatus(actionName, releaseName, status string, err error) error {
 	key := "helm_" + actionName + "_" + releaseName
 
+	// Keep the options and the signer recorded when the action started.
+	var (
+		options *ActionOptions
+		signer  *ChartSigner
+	)
+
+	if status != processing {
+		if value, getErr := h.Cache.Get(context.Background(), key); getErr == nil {
+			if previous, ok := value.(stat); ok {
+				options = previous.Options
+				signer = previous.Signer
+			}
+		}
+	}
+
 	stat := stat{
-		Status: status,
+		Status:  status,
+		Options: options,
+		Signer:  signer,
 	}
 
 	if err != nil {
@@ -176,9 +223,26 @@ func (h *Handler) setReleaseStatus(actionName, releaseName, status string, err e
 		stat.Err = &errString
 	}
 
+	return h.storeReleaseStat(actionName, releaseName, stat)
+}
+
+// setReleaseProcessing marks the action as processing and records its options,
+// and who signed its chart if it was verified.
+func (h *Handler) setReleaseProcessing(
+	actionName, releaseName string,
+	options ActionOptions,
+	signer *ChartSigner,
+) error {
+	return h.storeReleaseStat(actionName, releaseName,
+		stat{Status: processing, Options: options.recorded(), Signer: signer})
+}
+
+func (h *Handler) storeReleaseStat(actionName, releaseName string, stat stat) error {
+	key := "helm_" + actionName + "_" + releaseName
+
 	cacheErr := h.Cache.SetWithTTL(context.Background(), key, stat, statusCacheTimeout)
 	if cacheErr != nil {
-		logger.Log(logger.LevelError, map[string]string{"key": key, "status": status},
+		logger.Log(logger.LevelError, map[string]string{"key": key, "status": stat.Status},
 			cacheErr, "unable to set cache value")
 
 		return cacheErr
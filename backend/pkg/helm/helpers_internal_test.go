@@ -0,0 +1,192 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/helmpath"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/repo"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+const testChartSchema = `{"type": "object", "properties": {"replicas": {"type": "integer"}}}`
+
+// writeTestChart writes a small application chart to a temporary directory and returns its path.
+func writeTestChart(t *testing.T) string {
+	t.Helper()
+
+	chartDir := filepath.Join(t.TempDir(), "mychart")
+	files := map[string]string{
+		"Chart.yaml":  "apiVersion: v2\nname: mychart\nversion: 0.1.0\n",
+		"values.yaml": "replicas: 1\nimage: nginx\n",
+		"templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}-config\n" +
+			"data:\n  replicas: {{ .Values.replicas | quote }}\n  image: {{ .Values.image }}\n",
+		"templates/secrets.yaml": "apiVersion: v1\nkind: Secret\nmetadata:\n  name: first\n---\n" +
+			"apiVersion: v1\nkind: Secret\nmetadata:\n  name: second\n",
+		"templates/test.yaml": "apiVersion: v1\nkind: Pod\nmetadata:\n  name: {{ .Release.Name }}-test\n" +
+			"  annotations:\n    helm.sh/hook: test\nspec:\n  containers: []\n",
+		"templates/NOTES.txt": "Installed {{ .Release.Name }}.\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(chartDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	return chartDir
+}
+
+// newTestHandler returns a handler whose Helm config and cache files are in a
+// temporary directory.
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+
+	// Keep the registry client from falling back to the user's Docker credentials.
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	dir := t.TempDir()
+	settings := cli.New()
+	settings.RegistryConfig = filepath.Join(dir, "registry", "config.json")
+	settings.RepositoryConfig = filepath.Join(dir, "repositories.yaml")
+	settings.RepositoryCache = filepath.Join(dir, "cache")
+
+	return &Handler{Cache: cache.New[interface{}](), EnvSettings: settings}
+}
+
+// newChartRepoTestHandler serves a repository named local with versions
+// 0.1.0 and 0.2.0 of the test chart, the latter with a README and a schema,
+// and returns a handler with the repository added and its index cached.
+func newChartRepoTestHandler(t *testing.T) *Handler {
+	t.Helper()
+
+	repoDir := t.TempDir()
+
+	for _, version := range []string{"0.1.0", "0.2.0"} {
+		testChart, err := loader.Load(writeTestChart(t))
+		require.NoError(t, err)
+
+		testChart.Metadata.Version = version
+
+		if version == "0.2.0" {
+			testChart.Files = append(testChart.Files, &chart.File{Name: "README.md", Data: []byte("# mychart\n")})
+			testChart.Schema = []byte(testChartSchema)
+		}
+
+		_, err = chartutil.Save(testChart, repoDir)
+		require.NoError(t, err)
+	}
+
+	server := httptest.NewServer(http.FileServer(http.Dir(repoDir)))
+	t.Cleanup(server.Close)
+
+	index, err := repo.IndexDirectory(repoDir, server.URL)
+	require.NoError(t, err)
+
+	h := newTestHandler(t)
+
+	require.NoError(t, os.MkdirAll(h.RepositoryCache, 0o755))
+	require.NoError(t, index.WriteFile(filepath.Join(h.RepositoryCache, helmpath.CacheIndexFile("local")), 0o644))
+
+	repoFile := repo.NewFile()
+	repoFile.Add(&repo.Entry{Name: "local", URL: server.URL})
+	require.NoError(t, repoFile.WriteFile(h.RepositoryConfig, 0o644))
+
+	return h
+}
+
+// newMemoryActionConfig returns an action configuration storing releases in
+// memory and printing, instead of applying, resources.
+func newMemoryActionConfig(t *testing.T) *action.Configuration {
+	t.Helper()
+
+	return &action.Configuration{
+		Releases:     storage.Init(driver.NewMemory()),
+		KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+		Capabilities: chartutil.DefaultCapabilities,
+		Log:          t.Logf,
+	}
+}
+
+// serveSelfSubjectReview answers the self subject review made by VerifyUser
+// for the user tester, and reports whether the request was one.
+func serveSelfSubjectReview(w http.ResponseWriter, r *http.Request) bool {
+	if r.URL.Path != "/apis/authentication.k8s.io/v1/selfsubjectreviews" {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"apiVersion":"authentication.k8s.io/v1","kind":"SelfSubjectReview",` +
+		`"status":{"userInfo":{"username":"tester"}}}`))
+
+	return true
+}
+
+// newTestClientConfig returns a client config for a fake API server that only
+// answers the self subject review made by VerifyUser.
+func newTestClientConfig(t *testing.T) clientcmd.ClientConfig {
+	t.Helper()
+
+	return newTestClientConfigFor(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !serveSelfSubjectReview(w, r) {
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// newTestClientConfigFor returns a client config for a fake API server, the
+// cluster test, served by handler.
+func newTestClientConfigFor(t *testing.T, handler http.Handler) clientcmd.ClientConfig {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	config := api.NewConfig()
+	config.Clusters["test"] = &api.Cluster{Server: server.URL}
+	config.AuthInfos["test"] = &api.AuthInfo{}
+	config.Contexts["test"] = &api.Context{Cluster: "test", AuthInfo: "test"}
+	config.CurrentContext = "test"
+
+	return clientcmd.NewDefaultClientConfig(*config, nil)
+}
+
+// serveTestRequest serves a request to handler, with body encoded as JSON
+// unless it's nil.
+func serveTestRequest(
+	t *testing.T,
+	handler http.HandlerFunc,
+	method, target string,
+	body interface{},
+) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reqBody bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&reqBody).Encode(body))
+	}
+
+	req := httptest.NewRequestWithContext(context.Background(), method, target, &reqBody)
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+
+	return rr
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// maxChartArchiveSize is the largest chart archive that can be uploaded.
+	maxChartArchiveSize = 10 << 20
+	// maxChartRequestSize is the largest request field sent with a chart archive.
+	maxChartRequestSize = 1 << 20
+	// chartFormField is the multipart field of the chart archive.
+	chartFormField = "chart"
+	// requestFormField is the multipart field of the JSON install or upgrade request.
+	requestFormField = "request"
+)
+
+var (
+	errChartTooLarge        = fmt.Errorf("chart archive is larger than %d bytes", maxChartArchiveSize)
+	errLocalChartsForbidden = errors.New("local chart paths are only supported by the desktop app")
+)
+
+// localChartPath is the chart directory or archive of a JSON local chart request.
+type localChartPath struct {
+	// ChartPath is the absolute path of a chart directory or archive on the file system of the server.
+	ChartPath string `json:"chartPath"`
+}
+
+// InstallLocalChart installs a chart that isn't in a repository. The request
+// is either multipart, with the chart archive in the chart field and the
+// install request as JSON in the request field, or JSON with a chartPath when
+// running as a desktop app. Chart and version default to the archive file name
+// or path and the chart version. The status is returned by GetActionStatus.
+func (h *Handler) InstallLocalChart(clientConfig clientcmd.ClientConfig, w http.ResponseWriter, r *http.Request) {
+	var req InstallRequest
+
+	if h.decodeLocalChartRequest(w, r, &req, &req.CommonInstallUpdateRequest) {
+		h.startInstall(clientConfig, w, req)
+	}
+}
+
+// UpgradeLocalChart upgrades a release to a chart that isn't in a repository,
+// with a request like InstallLocalChart.
+func (h *Handler) UpgradeLocalChart(clientConfig clientcmd.ClientConfig, w http.ResponseWriter, r *http.Request) {
+	var req UpgradeReleaseRequest
+
+	if h.decodeLocalChartRequest(w, r, &req, &req.CommonInstallUpdateRequest) {
+		h.startUpgrade(clientConfig, w, req)
+	}
+}
+
+// decodeLocalChartRequest decodes the request into target, which embeds common,
+// and loads its chart. It writes the error response and returns false if that fails.
+func (h *Handler) decodeLocalChartRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+	target interface{},
+	common *CommonInstallUpdateRequest,
+) bool {
+	var (
+		localChart *chart.Chart
+		source     string
+		status     int
+		err        error
+	)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		localChart, source, status, err = decodeChartUpload(w, r, target)
+	} else {
+		localChart, source, status, err = h.decodeChartPath(w, r, target)
+	}
+
+	if err == nil {
+		err = checkLocalChart(localChart, common.Version)
+		status = http.StatusBadRequest
+	}
+
+	if err == nil && h.verifiesChart(*common) {
+		common.signer, err = h.verifyLocalChart(source, mediaType == "multipart/form-data")
+		status = http.StatusBadRequest
+	}
+
+	if err != nil {
+		handleError(w, common.Name, err, "loading local chart", status)
+		return false
+	}
+
+	common.loadedChart = localChart
+	common.Version = localChart.Metadata.Version
+
+	if common.Chart == "" {
+		common.Chart = source
+	}
+
+	return true
+}
+
+// checkLocalChart checks that a local chart is installable and has the
+// requested version, if any. Its dependencies can't be updated, so they must
+// be in its charts directory.
+func checkLocalChart(localChart *chart.Chart, version string) error {
+	if version != "" && version != localChart.Metadata.Version {
+		return fmt.Errorf("chart version %s does not match the requested version %s",
+			localChart.Metadata.Version, version)
+	}
+
+	if err := checkInstallable(localChart); err != nil {
+		return err
+	}
+
+	if localChart.Metadata.Dependencies != nil {
+		return action.CheckDependencies(localChart, localChart.Metadata.Dependencies)
+	}
+
+	return nil
+}
+
+// decodeChartUpload decodes the request field of a multipart request into
+// target and loads the uploaded chart archive. It returns the chart, the file
+// name of the archive, and the response status if it fails.
+func decodeChartUpload(w http.ResponseWriter, r *http.Request, target interface{}) (*chart.Chart, string, int, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxChartArchiveSize+maxChartRequestSize)
+
+	if err := r.ParseMultipartForm(maxChartArchiveSize); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, "", http.StatusRequestEntityTooLarge, errChartTooLarge
+		}
+
+		return nil, "", http.StatusBadRequest, err
+	}
+
+	defer func() {
+		_ = r.MultipartForm.RemoveAll()
+	}()
+
+	if err := json.Unmarshal([]byte(r.FormValue(requestFormField)), target); err != nil {
+		return nil, "", http.StatusBadRequest, fmt.Errorf("parsing %s field: %w", requestFormField, err)
+	}
+
+	file, header, err := r.FormFile(chartFormField)
+	if err != nil {
+		return nil, "", http.StatusBadRequest, fmt.Errorf("reading %s field: %w", chartFormField, err)
+	}
+
+	defer file.Close()
+
+	if header.Size > maxChartArchiveSize {
+		return nil, "", http.StatusRequestEntityTooLarge, errChartTooLarge
+	}
+
+	localChart, err := loader.LoadArchive(file)
+	if err != nil {
+		return nil, "", http.StatusBadRequest, fmt.Errorf("loading chart archive: %w", err)
+	}
+
+	return localChart, header.Filename, http.StatusOK, nil
+}
+
+// decodeChartPath decodes a JSON request into target and loads the chart at its chartPath.
+func (h *Handler) decodeChartPath(
+	w http.ResponseWriter,
+	r *http.Request,
+	target interface{},
+) (*chart.Chart, string, int, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxChartArchiveSize+maxChartRequestSize)
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, "", http.StatusRequestEntityTooLarge, err
+		}
+
+		return nil, "", http.StatusBadRequest, err
+	}
+
+	var path localChartPath
+
+	if err = json.Unmarshal(data, target); err == nil {
+		err = json.Unmarshal(data, &path)
+	}
+
+	if err != nil {
+		return nil, "", http.StatusBadRequest, err
+	}
+
+	if !h.AllowLocalCharts {
+		return nil, "", http.StatusForbidden, errLocalChartsForbidden
+	}
+
+	if !filepath.IsAbs(path.ChartPath) {
+		return nil, "", http.StatusBadRequest, errors.New("chartPath must be an absolute path")
+	}
+
+	localChart, err := loader.Load(path.ChartPath)
+	if err != nil {
+		return nil, "", http.StatusBadRequest, fmt.Errorf("loading chart: %w", err)
+	}
+
+	return localChart, path.ChartPath, http.StatusOK, nil
+}
@@ -0,0 +1,169 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+func newUploadRequest(t *testing.T, req interface{}, fileName string, archive []byte) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+
+	writer := multipart.NewWriter(&body)
+
+	reqJSON, err := json.Marshal(req)
+	require.NoError(t, err)
+	require.NoError(t, writer.WriteField(requestFormField, string(reqJSON)))
+
+	part, err := writer.CreateFormFile(chartFormField, fileName)
+	require.NoError(t, err)
+
+	_, err = part.Write(archive)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	r := httptest.NewRequestWithContext(context.Background(), http.MethodPost,
+		"/clusters/test/helm/release/install/chart", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return r
+}
+
+func testChartArchive(t *testing.T) []byte {
+	t.Helper()
+
+	chart, err := loader.Load(writeTestChart(t))
+	require.NoError(t, err)
+
+	path, err := chartutil.Save(chart, t.TempDir())
+	require.NoError(t, err)
+
+	archive, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	return archive
+}
+
+func newLocalChartInstallRequest() InstallRequest {
+	return InstallRequest{
+		CommonInstallUpdateRequest: CommonInstallUpdateRequest{
+			Name:        "demo",
+			Namespace:   "default",
+			Description: "from archive",
+			DryRun:      dryRunClient,
+		},
+	}
+}
+
+func TestInstallLocalChartUpload(t *testing.T) {
+	h := &Handler{Cache: cache.New[interface{}](), EnvSettings: cli.New()}
+	rr := httptest.NewRecorder()
+
+	h.InstallLocalChart(newTestClientConfig(t), rr,
+		newUploadRequest(t, newLocalChartInstallRequest(), "mychart-0.1.0.tgz", testChartArchive(t)))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response DryRunResponse
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	require.NotEmpty(t, response.Manifests)
+	assert.Equal(t, "Installed demo.\n", response.Notes)
+}
+
+func TestInstallLocalChartUploadErrors(t *testing.T) {
+	wrongVersion := newLocalChartInstallRequest()
+	wrongVersion.Version = "0.2.0"
+
+	tests := []struct {
+		name       string
+		req        InstallRequest
+		archive    []byte
+		wantStatus int
+	}{
+		{
+			name:       "not a chart",
+			req:        newLocalChartInstallRequest(),
+			archive:    []byte("not a chart"),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "too large",
+			req:        newLocalChartInstallRequest(),
+			archive:    make([]byte, maxChartArchiveSize+1),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "version mismatch",
+			req:        wrongVersion,
+			archive:    testChartArchive(t),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{Cache: cache.New[interface{}](), EnvSettings: cli.New()}
+			rr := httptest.NewRecorder()
+
+			h.InstallLocalChart(nil, rr, newUploadRequest(t, tt.req, "mychart.tgz", tt.archive))
+			assert.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestInstallLocalChartPath(t *testing.T) {
+	chartDir := writeTestChart(t)
+
+	body, err := json.Marshal(struct {
+		InstallRequest
+		localChartPath
+	}{newLocalChartInstallRequest(), localChartPath{ChartPath: chartDir}})
+	require.NoError(t, err)
+
+	newRequest := func() *http.Request {
+		return httptest.NewRequestWithContext(context.Background(), http.MethodPost,
+			"/clusters/test/helm/release/install/chart", bytes.NewReader(body))
+	}
+
+	h := &Handler{Cache: cache.New[interface{}](), EnvSettings: cli.New()}
+	rr := httptest.NewRecorder()
+
+	h.InstallLocalChart(nil, rr, newRequest())
+	assert.Equal(t, http.StatusForbidden, rr.Code, rr.Body.String())
+
+	h.AllowLocalCharts = true
+	rr = httptest.NewRecorder()
+
+	h.InstallLocalChart(newTestClientConfig(t), rr, newRequest())
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response DryRunResponse
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.NotEmpty(t, response.Manifests)
+}
+
+func TestInstallLocalChartPathTooLarge(t *testing.T) {
+	h := &Handler{Cache: cache.New[interface{}](), EnvSettings: cli.New(), AllowLocalCharts: true}
+	rr := httptest.NewRecorder()
+
+	body := bytes.NewReader(make([]byte, maxChartArchiveSize+maxChartRequestSize+1))
+
+	h.InstallLocalChart(nil, rr, httptest.NewRequestWithContext(context.Background(), http.MethodPost,
+		"/clusters/test/helm/release/install/chart", body))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code, rr.Body.String())
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+)
+
+// defaultActionTimeout is the time to wait for resources and hooks when the
+// request has no timeout, the same as the helm CLI.
+const defaultActionTimeout = 5 * time.Minute
+
+// ActionOptions are the helm CLI flags that can be passed with install,
+// upgrade and rollback requests. Not every option applies to every action,
+// see Validate.
+type ActionOptions struct {
+	// Atomic uninstalls a failed install, or rolls back a failed upgrade. It implies Wait.
+	Atomic bool `json:"atomic,omitempty"`
+	// Wait waits until the resources are ready, up to Timeout.
+	Wait bool `json:"wait,omitempty"`
+	// WaitForJobs also waits for jobs to complete. It requires Wait or Atomic.
+	WaitForJobs bool `json:"waitForJobs,omitempty"`
+	// Timeout is a duration such as "10m" for waiting and hooks. Defaults to 5m.
+	Timeout string `json:"timeout,omitempty"`
+	// ReuseValues merges the request values into the values of the current release. Upgrade only.
+	ReuseValues bool `json:"reuseValues,omitempty"`
+	// ResetValues resets the values to the chart defaults. Upgrade only.
+	ResetValues bool `json:"resetValues,omitempty"`
+	// Force replaces resources that cannot be updated in place.
+	Force bool `json:"force,omitempty"`
+	// SkipCRDs doesn't install the CRDs of the chart's crds directory.
+	SkipCRDs bool `json:"skipCrds,omitempty"`
+	// DisableHooks doesn't run the chart hooks.
+	DisableHooks bool `json:"disableHooks,omitempty"`
+	// CleanupOnFail deletes the resources created by a failed upgrade or rollback.
+	CleanupOnFail bool `json:"cleanupOnFail,omitempty"`
+}
+
+// Validate checks that the options are consistent and apply to actionName,
+// one of install, upgrade or rollback.
+func (o ActionOptions) Validate(actionName string) error {
+	if o.Timeout != "" {
+		timeout, err := time.ParseDuration(o.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout: %w", err)
+		}
+
+		if timeout <= 0 {
+			return errors.New("timeout must be positive")
+		}
+	}
+
+	if o.WaitForJobs && !o.Wait && !o.Atomic {
+		return errors.New("waitForJobs requires wait or atomic")
+	}
+
+	if o.ReuseValues && o.ResetValues {
+		return errors.New("reuseValues and resetValues are mutually exclusive")
+	}
+
+	if actionName != "upgrade" && (o.ReuseValues || o.ResetValues) {
+		return fmt.Errorf("reuseValues and resetValues are not supported for %s", actionName)
+	}
+
+	if actionName == "install" && o.CleanupOnFail {
+		return errors.New("cleanupOnFail is not supported for install")
+	}
+
+	if actionName == "rollback" && (o.Atomic || o.SkipCRDs) {
+		return errors.New("atomic and skipCrds are not supported for rollback")
+	}
+
+	return nil
+}
+
+// timeout returns the parsed Timeout, or defaultActionTimeout. The options must be valid.
+func (o ActionOptions) timeout() time.Duration {
+	timeout, err := time.ParseDuration(o.Timeout)
+	if err != nil {
+		return defaultActionTimeout
+	}
+
+	return timeout
+}
+
+// recorded returns the options as stored in the action status, with the
+// effective timeout and wait.
+func (o ActionOptions) recorded() *ActionOptions {
+	o.Timeout = o.timeout().String()
+	o.Wait = o.Wait || o.Atomic
+
+	return &o
+}
+
+func (o ActionOptions) applyToInstall(installClient *action.Install) {
+	installClient.Atomic = o.Atomic
+	installClient.Wait = o.Wait
+	installClient.WaitForJobs = o.WaitForJobs
+	installClient.Timeout = o.timeout()
+	installClient.Force = o.Force
+	installClient.SkipCRDs = o.SkipCRDs
+	installClient.DisableHooks = o.DisableHooks
+}
+
+func (o ActionOptions) applyToUpgrade(upgradeClient *action.Upgrade) {
+	upgradeClient.Atomic = o.Atomic
+	upgradeClient.Wait = o.Wait
+	upgradeClient.WaitForJobs = o.WaitForJobs
+	upgradeClient.Timeout = o.timeout()
+	upgradeClient.ReuseValues = o.ReuseValues
+	upgradeClient.ResetValues = o.ResetValues
+	upgradeClient.Force = o.Force
+	upgradeClient.SkipCRDs = o.SkipCRDs
+	upgradeClient.DisableHooks = o.DisableHooks
+	upgradeClient.CleanupOnFail = o.CleanupOnFail
+}
+
+func (o ActionOptions) applyToRollback(rollbackClient *action.Rollback) {
+	rollbackClient.Wait = o.Wait
+	rollbackClient.WaitForJobs = o.WaitForJobs
+	rollbackClient.Timeout = o.timeout()
+	rollbackClient.Force = o.Force
+	rollbackClient.DisableHooks = o.DisableHooks
+	rollbackClient.CleanupOnFail = o.CleanupOnFail
+}
@@ -0,0 +1,130 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestActionOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		opts    ActionOptions
+		wantErr string
+	}{
+		{name: "none", action: "install"},
+		{name: "atomic wait", action: "upgrade", opts: ActionOptions{Atomic: true, WaitForJobs: true, Timeout: "10m"}},
+		{name: "rollback cleanup", action: "rollback", opts: ActionOptions{CleanupOnFail: true, Wait: true}},
+		{name: "bad timeout", action: "install", opts: ActionOptions{Timeout: "soon"}, wantErr: "invalid timeout"},
+		{name: "negative timeout", action: "install", opts: ActionOptions{Timeout: "-1m"}, wantErr: "positive"},
+		{name: "jobs without wait", action: "upgrade", opts: ActionOptions{WaitForJobs: true}, wantErr: "requires wait"},
+		{
+			name:    "reuse and reset",
+			action:  "upgrade",
+			opts:    ActionOptions{ReuseValues: true, ResetValues: true},
+			wantErr: "mutually exclusive",
+		},
+		{name: "install reuse", action: "install", opts: ActionOptions{ReuseValues: true}, wantErr: "not supported"},
+		{name: "install cleanup", action: "install", opts: ActionOptions{CleanupOnFail: true}, wantErr: "not supported"},
+		{name: "rollback atomic", action: "rollback", opts: ActionOptions{Atomic: true}, wantErr: "not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate(tt.action)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestActionOptionsApplyToUpgrade(t *testing.T) {
+	upgradeClient := action.NewUpgrade(newMemoryActionConfig(t))
+
+	ActionOptions{Atomic: true, Timeout: "90s", ReuseValues: true, Force: true, CleanupOnFail: true}.
+		applyToUpgrade(upgradeClient)
+
+	assert.True(t, upgradeClient.Atomic)
+	assert.True(t, upgradeClient.ReuseValues)
+	assert.True(t, upgradeClient.Force)
+	assert.True(t, upgradeClient.CleanupOnFail)
+	assert.Equal(t, 90*time.Second, upgradeClient.Timeout)
+
+	ActionOptions{}.applyToUpgrade(upgradeClient)
+	assert.Equal(t, defaultActionTimeout, upgradeClient.Timeout)
+}
+
+func TestInstallReleaseRejectsInvalidOptions(t *testing.T) {
+	h := &Handler{Cache: cache.New[interface{}]()}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"name": "demo", "namespace": "default", "description": "d",
+		"chart": "repo/chart", "version": "1.0.0", "resetValues": true,
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost,
+		"/clusters/test/helm/release/install", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	h.InstallRelease(nil, rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "resetValues")
+}
+
+func TestRollbackRecordsOptionsInStatus(t *testing.T) {
+	actionConfig := newMemoryActionConfig(t)
+	testChart := &chart.Chart{Metadata: &chart.Metadata{APIVersion: "v2", Name: "demo", Version: "0.1.0"}}
+
+	for version, status := range map[int]release.Status{1: release.StatusSuperseded, 2: release.StatusDeployed} {
+		rel := newTestRelease("demo", version, status, "")
+		rel.Chart = testChart
+		require.NoError(t, actionConfig.Releases.Create(rel))
+	}
+
+	h := &Handler{Cache: cache.New[interface{}]()}
+	req := RollbackReleaseRequest{
+		Name: "demo", Namespace: "default", Revision: 1,
+		ActionOptions: ActionOptions{Wait: true, Timeout: "1m", CleanupOnFail: true},
+	}
+	require.NoError(t, req.Validate())
+
+	require.NoError(t, h.setReleaseProcessing("rollback", req.Name, req.ActionOptions, nil))
+	h.rollbackRelease(req, actionConfig)
+
+	statusReq := httptest.NewRequestWithContext(context.Background(), http.MethodGet,
+		"/clusters/test/helm/action/status?action=rollback&name=demo", nil)
+	rr := httptest.NewRecorder()
+	h.GetActionStatus(nil, rr, statusReq)
+	require.Equal(t, http.StatusAccepted, rr.Code)
+
+	var response struct {
+		Status  string        `json:"status"`
+		Options ActionOptions `json:"options"`
+	}
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Equal(t, success, response.Status)
+	assert.Equal(t, ActionOptions{Wait: true, Timeout: "1m0s", CleanupOnFail: true}, response.Options)
+
+	last, err := actionConfig.Releases.Last("demo")
+	require.NoError(t, err)
+	assert.Equal(t, 3, last.Version)
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"helm.sh/helm/v3/pkg/postrender"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	patchTypeStrategic = "strategic"
+	patchTypeMerge     = "merge"
+	patchTypeJSON      = "json"
+)
+
+// PostRendererRequest is how the manifests of an install or upgrade are
+// changed after they're rendered: with patches, or with an executable the
+// server allows.
+type PostRendererRequest struct {
+	// Patches are applied in order to the resources they target.
+	Patches []ManifestPatch `json:"patches,omitempty"`
+	// Exec is the name of a post-renderer executable configured on the server.
+	Exec string `json:"exec,omitempty"`
+}
+
+// ManifestPatch is a patch of the rendered resources, like a kustomize patch.
+type ManifestPatch struct {
+	// Type is strategic, the default, merge or json.
+	Type string `json:"type,omitempty"`
+	// Patch is the YAML or JSON patch.
+	Patch string `json:"patch"`
+	// Target selects the resources to patch. Strategic and merge patches
+	// target the resource named by their kind and metadata without it.
+	Target *PatchTarget `json:"target,omitempty"`
+}
+
+// PatchTarget selects resources by kind and name, and by API version and
+// namespace when they're set.
+type PatchTarget struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+}
+
+func (t PatchTarget) matches(obj *unstructured.Unstructured) bool {
+	return t.Kind == obj.GetKind() && t.Name == obj.GetName() &&
+		(t.APIVersion == "" || t.APIVersion == obj.GetAPIVersion()) &&
+		(t.Namespace == "" || t.Namespace == obj.GetNamespace())
+}
+
+func (req *PostRendererRequest) Validate() error {
+	if req == nil {
+		return nil
+	}
+
+	if (len(req.Patches) == 0) == (req.Exec == "") {
+		return errors.New("postRenderer needs either patches or exec")
+	}
+
+	for i := range req.Patches {
+		if err := req.Patches[i].complete(); err != nil {
+			return fmt.Errorf("invalid patch %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// complete checks the patch, setting its type and the target of a patch that
+// names its resource.
+func (p *ManifestPatch) complete() error {
+	if p.Type == "" {
+		p.Type = patchTypeStrategic
+	}
+
+	if p.Type != patchTypeStrategic && p.Type != patchTypeMerge && p.Type != patchTypeJSON {
+		return fmt.Errorf("unknown type %q", p.Type)
+	}
+
+	patch, err := yaml.YAMLToJSON([]byte(p.Patch))
+	if err != nil {
+		return err
+	}
+
+	if p.Type == patchTypeJSON {
+		if _, err := jsonpatch.DecodePatch(patch); err != nil {
+			return err
+		}
+	} else if p.Target == nil {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patch); err != nil {
+			return fmt.Errorf("patch without target must have a kind and a name: %w", err)
+		}
+
+		p.Target = &PatchTarget{
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Name:       obj.GetName(),
+			Namespace:  obj.GetNamespace(),
+		}
+	}
+
+	if p.Target == nil || p.Target.Kind == "" || p.Target.Name == "" {
+		return errors.New("target needs a kind and a name")
+	}
+
+	p.Patch = string(patch)
+
+	return nil
+}
+
+// postRenderer returns the post-renderer of a valid request, if it has one.
+func (h *Handler) postRenderer(req *PostRendererRequest) (postrender.PostRenderer, error) {
+	if req == nil {
+		return nil, nil
+	}
+
+	if req.Exec == "" {
+		return patchPostRenderer(req.Patches), nil
+	}
+
+	path, ok := h.PostRenderers[req.Exec]
+	if !ok {
+		return nil, fmt.Errorf("post-renderer %q is not allowed", req.Exec)
+	}
+
+	return postrender.NewExec(path)
+}
+
+// patchPostRenderer applies patches to the rendered manifests. The documents
+// that aren't patched are kept as they are, with their source comments.
+type patchPostRenderer []ManifestPatch
+
+func (patches patchPostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	documents := splitManifestDocuments(renderedManifests.String())
+	applied := make([]bool, len(patches))
+
+	for i, document := range documents {
+		comments, content := splitDocumentComments(document)
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+
+		patched, changed, err := patches.apply(content, applied)
+		if err != nil {
+			return nil, err
+		}
+
+		if changed {
+			documents[i] = comments + patched
+		}
+	}
+
+	for i, ok := range applied {
+		if !ok {
+			return nil, fmt.Errorf("patch %d matched no resource", i)
+		}
+	}
+
+	var out bytes.Buffer
+
+	for _, document := range documents {
+		out.WriteString("---\n")
+		out.WriteString(document)
+
+		if !strings.HasSuffix(document, "\n") {
+			out.WriteString("\n")
+		}
+	}
+
+	return &out, nil
+}
+
+// apply applies the patches targeting a YAML document, marking them applied.
+func (patches patchPostRenderer) apply(content string, applied []bool) (string, bool, error) {
+	doc, err := yaml.YAMLToJSON([]byte(content))
+	if err != nil {
+		return "", false, fmt.Errorf("parsing rendered manifest: %w", err)
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(doc); err != nil {
+		// Not a resource, e.g. an empty list, so there's nothing to patch.
+		return content, false, nil //nolint:nilerr
+	}
+
+	changed := false
+
+	for i, patch := range patches {
+		if !patch.Target.matches(obj) {
+			continue
+		}
+
+		doc, err = patch.applyTo(doc, obj.GroupVersionKind())
+		if err != nil {
+			return "", false, fmt.Errorf("applying patch %d to %s %s: %w", i, obj.GetKind(), obj.GetName(), err)
+		}
+
+		applied[i] = true
+		changed = true
+	}
+
+	if !changed {
+		return content, false, nil
+	}
+
+	patched, err := yaml.JSONToYAML(doc)
+	if err != nil {
+		return "", false, err
+	}
+
+	return string(patched), true, nil
+}
+
+// applyTo applies the patch to a JSON document. Strategic merge patches of
+// kinds without a known schema, e.g. custom resources, are merge patches.
+func (p ManifestPatch) applyTo(doc []byte, gvk schema.GroupVersionKind) ([]byte, error) {
+	switch p.Type {
+	case patchTypeJSON:
+		patch, err := jsonpatch.DecodePatch([]byte(p.Patch))
+		if err != nil {
+			return nil, err
+		}
+
+		return patch.Apply(doc)
+	case patchTypeStrategic:
+		if obj, err := scheme.Scheme.New(gvk); err == nil {
+			return strategicpatch.StrategicMergePatch(doc, []byte(p.Patch), obj)
+		}
+	}
+
+	return jsonpatch.MergePatch(doc, []byte(p.Patch))
+}
+
+// splitManifestDocuments splits a multi-document YAML stream on its "---" lines.
+func splitManifestDocuments(manifests string) []string {
+	var (
+		documents []string
+		current   strings.Builder
+	)
+
+	for _, line := range strings.SplitAfter(manifests, "\n") {
+		if strings.TrimRight(line, " \t\r\n") == "---" {
+			if strings.TrimSpace(current.String()) != "" {
+				documents = append(documents, current.String())
+			}
+
+			current.Reset()
+
+			continue
+		}
+
+		current.WriteString(line)
+	}
+
+	if strings.TrimSpace(current.String()) != "" {
+		documents = append(documents, current.String())
+	}
+
+	return documents
+}
+
+// splitDocumentComments splits the leading comment lines of a YAML document,
+// such as the "# Source:" comment helm adds, from its content.
+func splitDocumentComments(document string) (string, string) {
+	lines := strings.SplitAfter(document, "\n")
+
+	i := 0
+	for i < len(lines) && (strings.HasPrefix(strings.TrimSpace(lines[i]), "#") || strings.TrimSpace(lines[i]) == "") {
+		i++
+	}
+
+	return strings.Join(lines[:i], ""), strings.Join(lines[i:], "")
+}
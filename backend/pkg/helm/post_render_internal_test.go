@@ -0,0 +1,211 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+const postRenderTestManifests = `---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.0
+      - name: sidecar
+        image: proxy:1.0
+---
+# Source: app/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-config
+data:
+  a: "1"
+---
+# Source: app/templates/widget.yaml
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: web
+spec:
+  size: small
+`
+
+func runTestPatches(t *testing.T, patches ...ManifestPatch) (string, error) {
+	t.Helper()
+
+	req := &PostRendererRequest{Patches: patches}
+	require.NoError(t, req.Validate())
+
+	out, err := patchPostRenderer(req.Patches).Run(bytes.NewBufferString(postRenderTestManifests))
+	if err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
+func TestPatchPostRenderer(t *testing.T) {
+	out, err := runTestPatches(t,
+		ManifestPatch{Patch: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n" +
+			"spec:\n  template:\n    spec:\n      containers:\n      - name: web\n        image: nginx:2.0\n"},
+		ManifestPatch{
+			Type:   patchTypeJSON,
+			Target: &PatchTarget{Kind: "ConfigMap", Name: "web-config"},
+			Patch:  `[{"op": "add", "path": "/data/b", "value": "2"}]`,
+		},
+		ManifestPatch{Patch: "apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: web\nspec:\n  size: large\n"},
+	)
+	require.NoError(t, err)
+
+	manifests := map[string]string{}
+	for _, manifest := range splitRenderedManifests(out) {
+		manifests[manifest.Template] = manifest.Content
+	}
+
+	// The strategic merge patch keeps the other containers.
+	assert.Contains(t, manifests["app/templates/deployment.yaml"], "image: nginx:2.0")
+	assert.Contains(t, manifests["app/templates/deployment.yaml"], "image: proxy:1.0")
+	assert.Contains(t, manifests["app/templates/configmap.yaml"], `b: "2"`)
+	assert.Contains(t, manifests["app/templates/widget.yaml"], "size: large")
+}
+
+func TestPatchPostRendererKeepsUnpatchedDocuments(t *testing.T) {
+	out, err := runTestPatches(t, ManifestPatch{
+		Type:   patchTypeMerge,
+		Target: &PatchTarget{Kind: "ConfigMap", Name: "web-config"},
+		Patch:  `{"data": {"a": null}}`,
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, out, "# Source: app/templates/deployment.yaml\napiVersion: apps/v1\nkind: Deployment\n")
+	assert.Contains(t, out, "# Source: app/templates/configmap.yaml\n")
+	assert.NotContains(t, out, `a: "1"`)
+}
+
+func TestPatchPostRendererUnmatchedPatch(t *testing.T) {
+	_, err := runTestPatches(t, ManifestPatch{
+		Type:   patchTypeMerge,
+		Target: &PatchTarget{Kind: "ConfigMap", Name: "missing"},
+		Patch:  `{"data": {"a": "2"}}`,
+	})
+	assert.ErrorContains(t, err, "patch 0 matched no resource")
+}
+
+func TestPostRendererRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request PostRendererRequest
+		wantErr string
+	}{
+		{name: "empty", wantErr: "either patches or exec"},
+		{
+			name:    "patches and exec",
+			request: PostRendererRequest{Exec: "kustomize", Patches: []ManifestPatch{{Patch: "{}"}}},
+			wantErr: "either patches or exec",
+		},
+		{
+			name:    "unknown type",
+			request: PostRendererRequest{Patches: []ManifestPatch{{Type: "xml", Patch: "{}"}}},
+			wantErr: `unknown type "xml"`,
+		},
+		{
+			name:    "json patch without target",
+			request: PostRendererRequest{Patches: []ManifestPatch{{Type: patchTypeJSON, Patch: "[]"}}},
+			wantErr: "target needs a kind and a name",
+		},
+		{
+			name:    "strategic patch without name",
+			request: PostRendererRequest{Patches: []ManifestPatch{{Patch: "kind: ConfigMap\n"}}},
+			wantErr: "target needs a kind and a name",
+		},
+		{name: "exec", request: PostRendererRequest{Exec: "kustomize"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.request.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestPostRendererExecAllowList(t *testing.T) {
+	executable := filepath.Join(t.TempDir(), "renderer")
+	require.NoError(t, os.WriteFile(executable, []byte("#!/bin/sh\ncat\n"), 0o700)) //nolint:gosec
+
+	h := &Handler{PostRenderers: map[string]string{"renderer": executable}}
+
+	postRenderer, err := h.postRenderer(&PostRendererRequest{Exec: "renderer"})
+	require.NoError(t, err)
+	assert.NotNil(t, postRenderer)
+
+	_, err = h.postRenderer(&PostRendererRequest{Exec: "/bin/sh"})
+	assert.ErrorContains(t, err, "is not allowed")
+}
+
+func TestInstallReleaseDryRunWithPatches(t *testing.T) {
+	h := &Handler{
+		Cache:       cache.New[interface{}](),
+		EnvSettings: cli.New(),
+	}
+
+	body, err := json.Marshal(InstallRequest{
+		CommonInstallUpdateRequest: CommonInstallUpdateRequest{
+			Name:        "demo",
+			Namespace:   "default",
+			Description: "dry run",
+			Chart:       writeTestChart(t),
+			Version:     "0.1.0",
+			Values:      base64.StdEncoding.EncodeToString([]byte("replicas: 3\n")),
+			DryRun:      dryRunClient,
+			PostRenderer: &PostRendererRequest{Patches: []ManifestPatch{{
+				Patch: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: demo-config\n  labels:\n    team: web\n",
+			}}},
+		},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost,
+		"/clusters/test/helm/release/install", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	h.InstallRelease(newTestClientConfig(t), rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response DryRunResponse
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+	manifests := map[string]RenderedManifest{}
+	for _, manifest := range response.Manifests {
+		manifests[manifest.Template] = manifest
+	}
+
+	require.Contains(t, manifests, "mychart/templates/configmap.yaml")
+	assert.Contains(t, manifests["mychart/templates/configmap.yaml"].Content, "team: web")
+	assert.Contains(t, manifests["mychart/templates/configmap.yaml"].Content, `replicas: "3"`)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/downloader"
+)
+
+var (
+	errNoKeyring           = errors.New("no keyring is configured to verify charts")
+	errUploadNotVerifiable = errors.New("uploaded charts can't be verified, they have no provenance file")
+)
+
+// ChartSigner is who signed a chart whose provenance was verified.
+type ChartSigner struct {
+	// Identities are the user IDs of the signing key, e.g. "Jane Doe <jane@example.com>".
+	Identities []string `json:"identities"`
+	// Fingerprint is the fingerprint of the signing key, in hexadecimal.
+	Fingerprint string `json:"fingerprint"`
+	// ChartHash is the hash of the verified chart archive, e.g. sha256:<hex>.
+	ChartHash string `json:"chartHash"`
+}
+
+// verifiesChart returns whether the provenance of the chart of a request must
+// be verified: when the server requires it for all charts, or the request does.
+func (h *Handler) verifiesChart(req CommonInstallUpdateRequest) bool {
+	return h.VerifyCharts || req.Verify
+}
+
+// verificationOptions returns the chart path options that locate the chart
+// of a request, verifying it with the keyring if it must be.
+func (h *Handler) verificationOptions(
+	req CommonInstallUpdateRequest,
+	chartPathOptions action.ChartPathOptions,
+) (action.ChartPathOptions, error) {
+	if !h.verifiesChart(req) {
+		return chartPathOptions, nil
+	}
+
+	if h.Keyring == "" {
+		return chartPathOptions, errNoKeyring
+	}
+
+	chartPathOptions.Verify = true
+	chartPathOptions.Keyring = h.Keyring
+
+	return chartPathOptions, nil
+}
+
+// verifyLocalChart verifies a chart that isn't in a repository, which must
+// be an archive on the file system of the server with its provenance file.
+func (h *Handler) verifyLocalChart(chartPath string, uploaded bool) (*ChartSigner, error) {
+	if h.Keyring == "" {
+		return nil, errNoKeyring
+	}
+
+	if uploaded {
+		return nil, errUploadNotVerifiable
+	}
+
+	return verifyChartProvenance(chartPath, h.Keyring)
+}
+
+// verifyChartProvenance verifies a chart archive with the provenance file
+// next to it, like helm verify, and returns who signed it.
+func verifyChartProvenance(chartPath, keyring string) (*ChartSigner, error) {
+	verification, err := downloader.VerifyChart(chartPath, keyring)
+	if err != nil {
+		return nil, fmt.Errorf("verifying chart provenance: %w", err)
+	}
+
+	signer := &ChartSigner{
+		Identities: []string{},
+		ChartHash:  verification.FileHash,
+	}
+
+	if entity := verification.SignedBy; entity != nil {
+		for name := range entity.Identities {
+			signer.Identities = append(signer.Identities, name)
+		}
+
+		slices.Sort(signer.Identities)
+
+		if entity.PrimaryKey != nil {
+			signer.Fingerprint = fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)
+		}
+	}
+
+	return signer, nil
+}
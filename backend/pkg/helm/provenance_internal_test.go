@@ -0,0 +1,165 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp" //nolint:staticcheck
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+// writeTestKeyring writes a keyring with the public key of a new signing key,
+// which it returns.
+func writeTestKeyring(t *testing.T, name string) (*openpgp.Entity, string) {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity(name, "", "signer@example.com", nil)
+	require.NoError(t, err)
+
+	var keyring bytes.Buffer
+
+	require.NoError(t, entity.Serialize(&keyring))
+
+	path := filepath.Join(t.TempDir(), "pubring.gpg")
+	require.NoError(t, os.WriteFile(path, keyring.Bytes(), 0o600))
+
+	return entity, path
+}
+
+// writeSignedTestChart packages the test chart and signs it with entity, if
+// it's set, like helm package --sign.
+func writeSignedTestChart(t *testing.T, entity *openpgp.Entity) string {
+	t.Helper()
+
+	testChart, err := loader.Load(writeTestChart(t))
+	require.NoError(t, err)
+
+	archive, err := chartutil.Save(testChart, t.TempDir())
+	require.NoError(t, err)
+
+	if entity != nil {
+		signature, err := (&provenance.Signatory{Entity: entity}).ClearSign(archive)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(archive+".prov", []byte(signature), 0o600))
+	}
+
+	return archive
+}
+
+func TestVerifyChartProvenance(t *testing.T) {
+	entity, keyring := writeTestKeyring(t, "Chart Signer")
+	_, otherKeyring := writeTestKeyring(t, "Someone Else")
+
+	archive := writeSignedTestChart(t, entity)
+
+	signer, err := verifyChartProvenance(archive, keyring)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Chart Signer <signer@example.com>"}, signer.Identities)
+	assert.Len(t, signer.Fingerprint, 40)
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", signer.ChartHash)
+
+	_, err = verifyChartProvenance(archive, otherKeyring)
+	assert.Error(t, err, "a chart signed by a key that isn't in the keyring is refused")
+
+	_, err = verifyChartProvenance(writeSignedTestChart(t, nil), keyring)
+	assert.ErrorContains(t, err, "could not load provenance file")
+
+	_, err = verifyChartProvenance(writeTestChart(t), keyring)
+	assert.ErrorContains(t, err, "unpacked charts cannot be verified")
+
+	file, err := os.OpenFile(archive, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = file.WriteString("tampered")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	_, err = verifyChartProvenance(archive, keyring)
+	assert.Error(t, err, "a chart changed after it was signed is refused")
+}
+
+func TestInstallReleaseVerifiesChart(t *testing.T) {
+	entity, keyring := writeTestKeyring(t, "Chart Signer")
+
+	install := func(h *Handler, chartPath string, verify bool) *httptest.ResponseRecorder {
+		body, err := json.Marshal(InstallRequest{
+			CommonInstallUpdateRequest: CommonInstallUpdateRequest{
+				Name:        "demo",
+				Namespace:   "default",
+				Description: "verified",
+				Chart:       chartPath,
+				Version:     "0.1.0",
+				Verify:      verify,
+			},
+		})
+		require.NoError(t, err)
+
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodPost,
+			"/clusters/test/helm/release/install", bytes.NewReader(body))
+		rr := httptest.NewRecorder()
+		h.InstallRelease(newTestClientConfig(t), rr, req)
+
+		return rr
+	}
+
+	h := &Handler{
+		Cache:        cache.New[interface{}](),
+		EnvSettings:  cli.New(),
+		Keyring:      keyring,
+		VerifyCharts: true,
+	}
+
+	rr := install(h, writeSignedTestChart(t, nil), false)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "could not load provenance file")
+
+	rr = install(h, writeSignedTestChart(t, entity), false)
+	require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+
+	// The fake API server can't store the release, so the install fails, but
+	// its status still has the signer.
+	var status *stat
+
+	require.Eventually(t, func() bool {
+		var err error
+
+		status, err = h.getReleaseStatus("install", "demo")
+
+		return err == nil && status.Status != processing
+	}, 10*time.Second, 10*time.Millisecond)
+
+	require.NotNil(t, status.Signer)
+	assert.Equal(t, []string{"Chart Signer <signer@example.com>"}, status.Signer.Identities)
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet,
+		"/clusters/test/helm/action/status?name=demo&action=install", nil)
+	rr = httptest.NewRecorder()
+	h.GetActionStatus(newTestClientConfig(t), rr, req)
+
+	var response struct {
+		Signer ChartSigner `json:"signer"`
+	}
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Equal(t, *status.Signer, response.Signer)
+
+	// A request can require verification when the server doesn't, but not
+	// without a keyring.
+	h = &Handler{Cache: cache.New[interface{}](), EnvSettings: cli.New()}
+
+	rr = install(h, writeSignedTestChart(t, entity), true)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), errNoKeyring.Error())
+}
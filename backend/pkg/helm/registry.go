@@ -0,0 +1,359 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/schema"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/registry"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+const logFieldRegistry = "registry"
+
+var errNotOCIChart = errors.New("chart must be an oci:// reference")
+
+// RegistryLoginRequest logs in to an OCI registry. The credentials are stored
+// in Helm's registry config file, or in the registry credential store of the
+// handler if it has one, and used for oci:// charts on that host.
+type RegistryLoginRequest struct {
+	// Host is the registry host, e.g. ghcr.io or localhost:5000.
+	Host     string `json:"host" validate:"required"`
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	// Insecure skips TLS certificate verification.
+	Insecure bool `json:"insecure"`
+	// PlainHTTP talks to the registry over HTTP instead of HTTPS.
+	PlainHTTP bool `json:"plainHTTP"`
+}
+
+func (req *RegistryLoginRequest) Validate() error {
+	validate := validator.New()
+	return validate.Struct(req)
+}
+
+type RegistryLogoutRequest struct {
+	Host string `json:"host" validate:"required"`
+}
+
+func (req *RegistryLogoutRequest) Validate() error {
+	validate := validator.New()
+	return validate.Struct(req)
+}
+
+type registryInfo struct {
+	Host string `json:"host"`
+}
+
+// ListRegistriesResponse lists the registries logged in to. Credentials are never returned.
+type ListRegistriesResponse struct {
+	Registries []registryInfo `json:"registries"`
+}
+
+type ListChartTagsRequest struct {
+	// Chart is an oci:// reference without a tag, e.g. oci://ghcr.io/org/charts/app.
+	Chart     string `json:"chart" validate:"required"`
+	PlainHTTP bool   `json:"plainHTTP"`
+}
+
+func (req *ListChartTagsRequest) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return err
+	}
+
+	if !registry.IsOCI(req.Chart) {
+		return errNotOCIChart
+	}
+
+	return nil
+}
+
+type ListChartTagsResponse struct {
+	// Tags are the semver tags of the chart, newest first.
+	Tags []string `json:"tags"`
+}
+
+// newRegistryClient returns a registry client using the credentials saved by RegistryLogin.
+func (h *Handler) newRegistryClient(plainHTTP bool) (*registry.Client, error) {
+	options := []registry.ClientOption{
+		registry.ClientOptCredentialsFile(h.EnvSettings.RegistryConfig),
+		registry.ClientOptWriter(io.Discard),
+		registry.ClientOptEnableCache(true),
+	}
+
+	if h.RegistryCredentialStore != nil {
+		options = append(options, registry.ClientOptAuthorizer(auth.Client{
+			Credential: h.registryCredential,
+			Cache:      auth.NewCache(),
+		}))
+	}
+
+	if plainHTTP {
+		options = append(options, registry.ClientOptPlainHTTP())
+	}
+
+	return registry.NewClient(options...)
+}
+
+// setRegistryClient sets the registry client the action clients created from
+// actionConfig use to pull oci:// charts.
+func (h *Handler) setRegistryClient(actionConfig *action.Configuration, plainHTTP bool) error {
+	registryClient, err := h.newRegistryClient(plainHTTP)
+	if err != nil {
+		return err
+	}
+
+	actionConfig.RegistryClient = registryClient
+
+	return nil
+}
+
+// RegistryLogin logs in to an OCI registry.
+func (h *Handler) RegistryLogin(w http.ResponseWriter, r *http.Request) {
+	var req RegistryLoginRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Log(logger.LevelError, nil, err, "parsing request for registry login")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		logger.Log(logger.LevelError, nil, err, "validating request for registry login")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if h.RegistryCredentialStore != nil {
+		h.storeRegistryLogin(w, r, &req)
+		return
+	}
+
+	registryClient, err := h.newRegistryClient(req.PlainHTTP)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRegistry: req.Host}, err, "creating registry client")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	err = registryClient.Login(req.Host,
+		registry.LoginOptBasicAuth(req.Username, req.Password),
+		registry.LoginOptInsecure(req.Insecure),
+		registry.LoginOptPlainText(req.PlainHTTP),
+	)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRegistry: req.Host}, err, "logging in to registry")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+
+		return
+	}
+
+	h.returnResponse(w, req.Host, http.StatusOK, "success")
+}
+
+// storeRegistryLogin logs in to an OCI registry with the registry credential
+// store, so the credentials never reach the registry config file.
+func (h *Handler) storeRegistryLogin(w http.ResponseWriter, r *http.Request, req *RegistryLoginRequest) {
+	host := registryHostOf(req.Host)
+
+	if err := checkRegistryLogin(r.Context(), host, req); err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRegistry: host}, err, "logging in to registry")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+
+		return
+	}
+
+	err := h.RegistryCredentialStore.SetCredentials(r.Context(), registryCredentialsName(host), RepositoryCredentials{
+		Username: req.Username,
+		Password: req.Password,
+	})
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRegistry: host}, err, "saving registry credentials")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	h.returnResponse(w, host, http.StatusOK, "success")
+}
+
+// RegistryLogout removes the saved credentials of an OCI registry.
+func (h *Handler) RegistryLogout(w http.ResponseWriter, r *http.Request) {
+	var req RegistryLogoutRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Log(logger.LevelError, nil, err, "parsing request for registry logout")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		logger.Log(logger.LevelError, nil, err, "validating request for registry logout")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	// Log out of the host the login was saved for, whatever form it is given in.
+	host := registryHostOf(req.Host)
+
+	hosts, err := h.registryHosts(r.Context())
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "reading registry config")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if !slices.Contains(hosts, host) {
+		http.Error(w, "not logged in to "+host, http.StatusNotFound)
+		return
+	}
+
+	if h.RegistryCredentialStore != nil {
+		err = h.RegistryCredentialStore.DeleteCredentials(r.Context(), registryCredentialsName(host))
+	} else {
+		var registryClient *registry.Client
+
+		registryClient, err = h.newRegistryClient(false)
+		if err == nil {
+			err = registryClient.Logout(host)
+		}
+	}
+
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRegistry: host}, err, "logging out of registry")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	h.returnResponse(w, host, http.StatusOK, "success")
+}
+
+// ListRegistries lists the registries with saved credentials.
+func (h *Handler) ListRegistries(w http.ResponseWriter, r *http.Request) {
+	hosts, err := h.registryHosts(r.Context())
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "reading registry config")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	response := ListRegistriesResponse{Registries: make([]registryInfo, 0, len(hosts))}
+	for _, host := range hosts {
+		response.Registries = append(response.Registries, registryInfo{Host: host})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Log(logger.LevelError, nil, err, "encoding response")
+	}
+}
+
+// ListChartTags lists the versions of an OCI chart.
+func (h *Handler) ListChartTags(w http.ResponseWriter, r *http.Request) {
+	var req ListChartTagsRequest
+
+	if err := schema.NewDecoder().Decode(&req, r.URL.Query()); err != nil {
+		logger.Log(logger.LevelError, nil, err, "parsing request for chart tags")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		logger.Log(logger.LevelError, nil, err, "validating request for chart tags")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	registryClient, err := h.newRegistryClient(req.PlainHTTP)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart}, err, "creating registry client")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	tags, err := registryClient.Tags(strings.TrimPrefix(req.Chart, registry.OCIScheme+"://"))
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart}, err, "listing chart tags")
+		http.Error(w, err.Error(), http.StatusBadGateway)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(ListChartTagsResponse{Tags: tags}); err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart}, err, "encoding response")
+	}
+}
+
+// listRegistryHosts returns the sorted hosts with credentials in a registry
+// config file, which uses the Docker config.json format.
+func listRegistryHosts(registryConfig string) ([]string, error) {
+	data, err := os.ReadFile(registryConfig)
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var config struct {
+		Auths map[string]json.RawMessage `json:"auths"`
+	}
+
+	if len(strings.TrimSpace(string(data))) > 0 {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, err
+		}
+	}
+
+	hosts := make([]string, 0, len(config.Auths))
+	for host := range config.Auths {
+		hosts = append(hosts, host)
+	}
+
+	sort.Strings(hosts)
+
+	return hosts, nil
+}
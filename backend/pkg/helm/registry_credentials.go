@@ -0,0 +1,113 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// registryCredentialsName returns the name the credentials of a registry host
+// are stored under. Names can't contain the : of a port, and hosts can't
+// contain the _ it is replaced with.
+func registryCredentialsName(host string) string {
+	return strings.ReplaceAll(host, ":", "_")
+}
+
+// registryHostOf returns the host a registry login is for, without the scheme
+// and path, like the helm CLI does, e.g. ghcr.io for oci://ghcr.io/org.
+func registryHostOf(host string) string {
+	for _, scheme := range []string{"oci://", "http://", "https://"} {
+		host = strings.TrimPrefix(host, scheme)
+	}
+
+	host, _, _ = strings.Cut(host, "/")
+
+	return host
+}
+
+// registryHosts returns the sorted hosts with saved credentials.
+func (h *Handler) registryHosts(ctx context.Context) ([]string, error) {
+	if h.RegistryCredentialStore == nil {
+		return listRegistryHosts(h.EnvSettings.RegistryConfig)
+	}
+
+	names, err := h.RegistryCredentialStore.ListCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make([]string, 0, len(names))
+	for _, name := range names {
+		hosts = append(hosts, strings.ReplaceAll(name, "_", ":"))
+	}
+
+	return hosts, nil
+}
+
+// registryCredential returns the credentials stored for a registry host, used
+// to authorize the requests of the registry clients.
+func (h *Handler) registryCredential(ctx context.Context, host string) (auth.Credential, error) {
+	credentials, err := h.RegistryCredentialStore.GetCredentials(ctx, registryCredentialsName(host))
+	if err != nil {
+		return auth.EmptyCredential, err
+	}
+
+	return auth.Credential{Username: credentials.Username, Password: credentials.Password}, nil
+}
+
+// checkRegistryLogin checks the credentials of a login request against the
+// registry, without saving them.
+func checkRegistryLogin(ctx context.Context, host string, req *RegistryLoginRequest) error {
+	reg, err := remote.NewRegistry(host)
+	if err != nil {
+		return err
+	}
+
+	client := &auth.Client{
+		Credential: auth.StaticCredential(host, auth.Credential{Username: req.Username, Password: req.Password}),
+	}
+
+	if req.Insecure {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec
+
+		client.Client = &http.Client{Transport: transport}
+	}
+
+	reg.PlainHTTP = req.PlainHTTP
+	reg.Client = client
+
+	// Like the helm CLI, try OAuth2 first for the registries that support it.
+	client.ForceAttemptOAuth2 = true
+
+	if err := reg.Ping(ctx); err != nil {
+		client.ForceAttemptOAuth2 = false
+
+		if err := reg.Ping(ctx); err != nil {
+			return fmt.Errorf("authenticating to %q: %w", host, err)
+		}
+	}
+
+	return nil
+}
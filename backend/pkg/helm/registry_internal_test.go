@@ -0,0 +1,229 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/distribution/distribution/v3/configuration"
+	_ "github.com/distribution/distribution/v3/registry/auth/htpasswd"
+	"github.com/distribution/distribution/v3/registry/handlers"
+	_ "github.com/distribution/distribution/v3/registry/storage/driver/inmemory"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	testRegistryUser     = "myuser"
+	testRegistryPassword = "mypass"
+)
+
+// startTestRegistry starts a local OCI registry over plain HTTP, with
+// testRegistryUser as the only user, and returns its host.
+func startTestRegistry(t *testing.T) string {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(testRegistryPassword), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	htpasswd := filepath.Join(t.TempDir(), "htpasswd")
+	require.NoError(t, os.WriteFile(htpasswd, []byte(testRegistryUser+":"+string(hash)+"\n"), 0o600))
+
+	// The registry logs every request, including expected auth failures and 404s.
+	logrus.SetLevel(logrus.FatalLevel)
+
+	config := &configuration.Configuration{}
+	config.Log.AccessLog.Disabled = true
+	config.Storage = configuration.Storage{"inmemory": configuration.Parameters{}}
+	config.Auth = configuration.Auth{
+		"htpasswd": configuration.Parameters{"realm": "localhost", "path": htpasswd},
+	}
+
+	server := httptest.NewServer(handlers.NewApp(context.Background(), config))
+	t.Cleanup(server.Close)
+
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+// pushTestChart pushes the test chart to the registry with the given version.
+func pushTestChart(t *testing.T, h *Handler, host, version string) {
+	t.Helper()
+
+	chart, err := loader.Load(writeTestChart(t))
+	require.NoError(t, err)
+
+	chart.Metadata.Version = version
+
+	archive, err := chartutil.Save(chart, t.TempDir())
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(archive)
+	require.NoError(t, err)
+
+	registryClient, err := h.newRegistryClient(true)
+	require.NoError(t, err)
+
+	_, err = registryClient.Push(data, host+"/charts/mychart:"+version)
+	require.NoError(t, err)
+}
+
+func listTestRegistries(t *testing.T, h *Handler) []registryInfo {
+	t.Helper()
+
+	rr := serveTestRequest(t, h.ListRegistries, http.MethodGet, "/registries", nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response ListRegistriesResponse
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+	return response.Registries
+}
+
+func TestRegistryLoginLogout(t *testing.T) {
+	host := startTestRegistry(t)
+	h := newTestHandler(t)
+
+	assert.Empty(t, listTestRegistries(t, h))
+
+	rr := serveTestRequest(t, h.RegistryLogin, http.MethodPost, "/registries/login", RegistryLoginRequest{
+		Host: host, Username: testRegistryUser, Password: "wrong", PlainHTTP: true,
+	})
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Empty(t, listTestRegistries(t, h))
+
+	rr = serveTestRequest(t, h.RegistryLogin, http.MethodPost, "/registries/login", RegistryLoginRequest{
+		Host: host, Username: testRegistryUser, Password: testRegistryPassword, PlainHTTP: true,
+	})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, []registryInfo{{Host: host}}, listTestRegistries(t, h))
+
+	rr = serveTestRequest(t, h.ListRegistries, http.MethodGet, "/registries", nil)
+	assert.NotContains(t, rr.Body.String(), testRegistryPassword)
+
+	rr = serveTestRequest(t, h.RegistryLogout, http.MethodPost, "/registries/logout", RegistryLogoutRequest{Host: host})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Empty(t, listTestRegistries(t, h))
+
+	rr = serveTestRequest(t, h.RegistryLogout, http.MethodPost, "/registries/logout", RegistryLogoutRequest{Host: host})
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = serveTestRequest(t, h.RegistryLogin, http.MethodPost, "/registries/login", RegistryLoginRequest{
+		Host: "oci://" + host, Username: testRegistryUser, Password: testRegistryPassword, PlainHTTP: true,
+	})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	rr = serveTestRequest(t, h.RegistryLogout, http.MethodPost, "/registries/logout",
+		RegistryLogoutRequest{Host: "oci://" + host})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Empty(t, listTestRegistries(t, h))
+}
+
+func TestRegistryLoginWithCredentialStore(t *testing.T) {
+	host := startTestRegistry(t)
+	h := newTestHandler(t)
+	client := fake.NewSimpleClientset()
+	h.RegistryCredentialStore = NewSecretCredentialStore(client, "headlamp", "registry-credentials")
+
+	rr := serveTestRequest(t, h.RegistryLogin, http.MethodPost, "/registries/login", RegistryLoginRequest{
+		Host: host, Username: testRegistryUser, Password: "wrong", PlainHTTP: true,
+	})
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	rr = serveTestRequest(t, h.RegistryLogin, http.MethodPost, "/registries/login", RegistryLoginRequest{
+		Host: "oci://" + host, Username: testRegistryUser, Password: testRegistryPassword, PlainHTTP: true,
+	})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, []registryInfo{{Host: host}}, listTestRegistries(t, h))
+
+	// The credentials are in the Secret, not in the registry config file.
+	assert.NoFileExists(t, h.EnvSettings.RegistryConfig)
+
+	secrets := client.CoreV1().Secrets("headlamp")
+
+	secret, err := secrets.Get(context.Background(), "registry-credentials", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, string(secret.Data[registryCredentialsName(host)]), testRegistryPassword)
+
+	// Pushing and listing tags authenticate with the stored credentials.
+	pushTestChart(t, h, host, "0.1.0")
+
+	rr = serveTestRequest(t, h.ListChartTags, http.MethodGet,
+		"/charts/tags?plainHTTP=true&chart=oci://"+host+"/charts/mychart", nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), "0.1.0")
+
+	rr = serveTestRequest(t, h.RegistryLogout, http.MethodPost, "/registries/logout",
+		RegistryLogoutRequest{Host: "oci://" + host})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Empty(t, listTestRegistries(t, h))
+}
+
+func TestListChartTagsAndInstallFromRegistry(t *testing.T) {
+	host := startTestRegistry(t)
+	h := newTestHandler(t)
+
+	rr := serveTestRequest(t, h.RegistryLogin, http.MethodPost, "/registries/login", RegistryLoginRequest{
+		Host: host, Username: testRegistryUser, Password: testRegistryPassword, PlainHTTP: true,
+	})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	pushTestChart(t, h, host, "0.1.0")
+	pushTestChart(t, h, host, "0.2.0")
+
+	chartRef := "oci://" + host + "/charts/mychart"
+
+	rr = serveTestRequest(t, h.ListChartTags, http.MethodGet, "/charts/tags?plainHTTP=true&chart="+chartRef, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var tags ListChartTagsResponse
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&tags))
+	assert.Equal(t, []string{"0.2.0", "0.1.0"}, tags.Tags)
+
+	body, err := json.Marshal(InstallRequest{
+		CommonInstallUpdateRequest: CommonInstallUpdateRequest{
+			Name:        "demo",
+			Namespace:   "default",
+			Description: "from registry",
+			Chart:       chartRef,
+			Version:     "0.2.0",
+			DryRun:      dryRunClient,
+			PlainHTTP:   true,
+		},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost,
+		"/clusters/test/helm/release/install", bytes.NewReader(body))
+	rr = httptest.NewRecorder()
+
+	h.InstallRelease(newTestClientConfig(t), rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response DryRunResponse
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	require.NotEmpty(t, response.Manifests)
+	assert.Equal(t, "Installed demo.\n", response.Notes)
+}
+
+func TestListChartTagsRequiresOCIChart(t *testing.T) {
+	h := newTestHandler(t)
+
+	rr := serveTestRequest(t, h.ListChartTags, http.MethodGet, "/charts/tags?chart=bitnami/nginx", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), errNotOCIChart.Error())
+}
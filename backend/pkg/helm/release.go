@@ -36,9 +36,11 @@ import (
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/downloader"
 	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage/driver"
 	authv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
@@ -65,6 +67,8 @@ const (
 	opUninstallRelease  = "uninstall_release"
 )
 
+var errUnauthorized = errors.New("user is not authorized to perform this operation")
+
 type ListReleaseRequest struct {
 	AllNamespaces *bool   `json:"allNamespaces,omitempty"`
 	Namespace     *string `json:"namespace,omitempty"`
@@ -392,7 +396,7 @@ func (h *Handler) UninstallRelease(clientConfig clientcmd.ClientConfig, w http.R
 	}
 
 	// check if release exists
-	_, err = actionConfig.Releases.Deployed(req.Name)
+	deployed, err := actionConfig.Releases.Deployed(req.Name)
 	if errors.Is(err, driver.ErrReleaseNotFound) {
 		logger.Log(logger.LevelError, map[string]string{logFieldReleaseName: req.Name, logFieldRequest: opUninstallRelease},
 			err, "release not found")
@@ -410,8 +414,13 @@ func (h *Handler) UninstallRelease(clientConfig clientcmd.ClientConfig, w http.R
 		return
 	}
 
+	record := h.newActionRecord("uninstall", req.Namespace, req.Name,
+		h.recordedUser(actionConfig, "uninstall", req.Name))
+	record.setChart(deployed)
+
 	go func(h *Handler) {
-		h.uninstallRelease(req, actionConfig)
+		err := h.uninstallRelease(req, actionConfig)
+		h.recordAction(record, err)
 	}(h)
 
 	response := map[string]string{
@@ -431,7 +440,7 @@ func (h *Handler) UninstallRelease(clientConfig clientcmd.ClientConfig, w http.R
 	}
 }
 
-func (h *Handler) uninstallRelease(req UninstallReleaseRequest, actionConfig *action.Configuration) {
+func (h *Handler) uninstallRelease(req UninstallReleaseRequest, actionConfig *action.Configuration) error {
 	// Get uninstall client
 	uninstallClient := action.NewUninstall(actionConfig)
 
@@ -446,17 +455,24 @@ func (h *Handler) uninstallRelease(req UninstallReleaseRequest, actionConfig *ac
 	}
 
 	h.setReleaseStatusSilent("uninstall", req.Name, status, err)
+
+	return err
 }
 
 type RollbackReleaseRequest struct {
 	Name      string `json:"name" validate:"required"`
 	Namespace string `json:"namespace" validate:"required"`
 	Revision  int    `json:"revision" validate:"required"`
+	ActionOptions
 }
 
 func (req *RollbackReleaseRequest) Validate() error {
 	validate := validator.New()
-	return validate.Struct(req)
+	if err := validate.Struct(req); err != nil {
+		return err
+	}
+
+	return req.ActionOptions.Validate("rollback")
 }
 
 func decodeRollbackReleaseRequest(r *http.Request) (RollbackReleaseRequest, error) {
@@ -498,7 +514,7 @@ func (h *Handler) RollbackRelease(clientConfig clientcmd.ClientConfig, w http.Re
 		return
 	}
 
-	err = h.setReleaseStatus("rollback", req.Name, processing, nil)
+	err = h.setReleaseProcessing("rollback", req.Name, req.ActionOptions, nil)
 	if err != nil {
 		logger.Log(logger.LevelError, nil, err, "setting status")
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -506,8 +522,19 @@ func (h *Handler) RollbackRelease(clientConfig clientcmd.ClientConfig, w http.Re
 		return
 	}
 
+	record := h.newActionRecord("rollback", req.Namespace, req.Name,
+		h.recordedUser(actionConfig, "rollback", req.Name))
+	if record != nil {
+		record.Revision = req.Revision
+
+		if target, err := actionConfig.Releases.Get(req.Name, req.Revision); err == nil {
+			record.setChart(target)
+		}
+	}
+
 	go func(h *Handler) {
-		h.rollbackRelease(req, actionConfig)
+		err := h.rollbackRelease(req, actionConfig)
+		h.recordAction(record, err)
 	}(h)
 
 	response := map[string]string{
@@ -526,9 +553,10 @@ func (h *Handler) RollbackRelease(clientConfig clientcmd.ClientConfig, w http.Re
 	}
 }
 
-func (h *Handler) rollbackRelease(req RollbackReleaseRequest, actionConfig *action.Configuration) {
+func (h *Handler) rollbackRelease(req RollbackReleaseRequest, actionConfig *action.Configuration) error {
 	rollbackClient := action.NewRollback(actionConfig)
 	rollbackClient.Version = req.Revision
+	req.ActionOptions.applyToRollback(rollbackClient)
 
 	status := success
 
@@ -541,6 +569,8 @@ func (h *Handler) rollbackRelease(req RollbackReleaseRequest, actionConfig *acti
 	}
 
 	h.setReleaseStatusSilent("rollback", req.Name, status, err)
+
+	return err
 }
 
 type CommonInstallUpdateRequest struct {
@@ -550,6 +580,25 @@ type CommonInstallUpdateRequest struct {
 	Values      string `json:"values"`
 	Chart       string `json:"chart" validate:"required"`
 	Version     string `json:"version" validate:"required"`
+	// DryRun renders the chart and returns what would be applied instead of
+	// installing or upgrading. Either "client" or "server".
+	DryRun string `json:"dryRun,omitempty" validate:"omitempty,oneof=client server"`
+	// PlainHTTP pulls oci:// charts over HTTP instead of HTTPS.
+	PlainHTTP bool `json:"plainHTTP,omitempty"`
+	// Verify refuses the chart unless its provenance file is signed by a key
+	// of the server keyring. Charts are always verified if the server requires it.
+	Verify bool `json:"verify,omitempty"`
+	ActionOptions
+	// PostRenderer changes the rendered manifests before they're applied.
+	PostRenderer *PostRendererRequest `json:"postRenderer,omitempty"`
+	// loadedChart is the chart of the request once it is loaded, from a
+	// repository, an upload or a local directory. It is used instead of
+	// locating Chart again.
+	loadedChart *chart.Chart
+	// postRenderer is the post-renderer of PostRenderer, once it's validated.
+	postRenderer postrender.PostRenderer
+	// signer is who signed the loaded chart, if its provenance was verified.
+	signer *ChartSigner
 }
 
 type InstallRequest struct {
@@ -560,7 +609,15 @@ type InstallRequest struct {
 
 func (req *InstallRequest) Validate() error {
 	validate := validator.New()
-	return validate.Struct(req)
+	if err := validate.Struct(req); err != nil {
+		return err
+	}
+
+	if err := req.PostRenderer.Validate(); err != nil {
+		return err
+	}
+
+	return req.ActionOptions.Validate("install")
 }
 
 func handleError(w http.ResponseWriter, releaseName string, err error, message string, status int) {
@@ -595,7 +652,17 @@ func (h *Handler) InstallRelease(clientConfig clientcmd.ClientConfig, w http.Res
 		return
 	}
 
-	err = req.Validate()
+	h.startInstall(clientConfig, w, req)
+}
+
+// startInstall validates an install request and starts installing the chart,
+// or renders it for a dry run.
+func (h *Handler) startInstall(clientConfig clientcmd.ClientConfig, w http.ResponseWriter, req InstallRequest) {
+	err := req.Validate()
+	if err == nil {
+		req.postRenderer, err = h.postRenderer(req.PostRenderer)
+	}
+
 	if err != nil {
 		logger.Log(logger.LevelError, nil, err, "validating request for install")
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -612,7 +679,29 @@ func (h *Handler) InstallRelease(clientConfig clientcmd.ClientConfig, w http.Res
 		return
 	}
 
-	err = h.setReleaseStatus("install", req.Name, processing, nil)
+	if err = h.setRegistryClient(actionConfig, req.PlainHTTP); err != nil {
+		handleError(w, req.Name, err, "creating registry client", http.StatusInternalServerError)
+		return
+	}
+
+	user, ok := verifyUser(actionConfig, req)
+	if !ok {
+		handleError(w, req.Name, errUnauthorized, "verifying user for install", http.StatusForbidden)
+		return
+	}
+
+	installClient := newInstallClient(req, actionConfig)
+	if !h.checkRequestValues(w, "install", &req.CommonInstallUpdateRequest,
+		installClient.ChartPathOptions, req.DependencyUpdate, nil) {
+		return
+	}
+
+	if req.DryRun != "" {
+		h.dryRunInstall(w, req, actionConfig)
+		return
+	}
+
+	err = h.setReleaseProcessing("install", req.Name, req.ActionOptions, req.signer)
 	if err != nil {
 		logger.Log(logger.LevelError, nil, err, "setting status")
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -620,14 +709,23 @@ func (h *Handler) InstallRelease(clientConfig clientcmd.ClientConfig, w http.Res
 		return
 	}
 
+	record := h.newActionRecord("install", req.Namespace, req.Name, user)
+	if record != nil {
+		record.Chart = req.Chart
+		record.Version = req.Version
+		record.ValuesHash = valuesHash(req.Values)
+	}
+
 	go func(h *Handler) {
-		h.installRelease(req, actionConfig)
+		err := h.installRelease(req, actionConfig)
+		h.recordAction(record, err)
 	}(h)
 
 	h.returnResponse(w, req.Name, http.StatusAccepted, "install request accepted")
 }
 
-// Returns the chart, and err, and if dependencyUpdate is true then we also update the chart dependencies.
+// Returns the chart, who signed it if chartPathOptions.Verify is set, and err,
+// and if dependencyUpdate is true then we also update the chart dependencies.
 func (h *Handler) getChart(
 	actionName string,
 	reqChart string,
@@ -635,27 +733,48 @@ func (h *Handler) getChart(
 	chartPathOptions action.ChartPathOptions,
 	dependencyUpdate bool,
 	settings *cli.EnvSettings,
-) (*chart.Chart, error) {
+) (*chart.Chart, *ChartSigner, error) {
+	chart, signer, failure, err := h.loadChart(reqChart, chartPathOptions, dependencyUpdate, settings)
+	if err != nil {
+		h.logActionState(zlog.Error(), err, actionName, reqChart, reqName, failed, failure)
+		return nil, nil, err
+	}
+
+	return chart, signer, nil
+}
+
+// loadChart locates and loads a chart like getChart, without logging or
+// setting the action status. If it fails, it also returns what failed.
+// Errors of the chart itself are chartRequestErrors.
+func (h *Handler) loadChart(
+	reqChart string,
+	chartPathOptions action.ChartPathOptions,
+	dependencyUpdate bool,
+	settings *cli.EnvSettings,
+) (*chart.Chart, *ChartSigner, string, error) {
 	// locate chart
-	chartPath, err := chartPathOptions.LocateChart(reqChart, settings)
+	chartPath, err := h.locateChart(chartPathOptions, reqChart)
 	if err != nil {
-		h.logActionState(zlog.Error(), err, actionName, reqChart, reqName, failed, "locating chart")
-		return nil, err
+		return nil, nil, "locating chart", chartRequestError{err: err}
+	}
+
+	var signer *ChartSigner
+
+	if chartPathOptions.Verify {
+		signer, err = verifyChartProvenance(chartPath, chartPathOptions.Keyring)
+		if err != nil {
+			return nil, nil, "verifying chart", chartRequestError{err: err}
+		}
 	}
 
 	// load chart
 	chart, err := loader.Load(chartPath)
 	if err != nil {
-		h.logActionState(zlog.Error(), err, actionName, reqChart, reqName, failed, "loading chart")
-		return nil, err
+		return nil, nil, "loading chart", chartRequestError{err: err}
 	}
 
-	// chart is installable only if it is of type application or empty
-	if chart.Metadata.Type != "" && chart.Metadata.Type != "application" {
-		typeErr := fmt.Errorf("chart type %q is not installable", chart.Metadata.Type)
-		h.logActionState(zlog.Error(), typeErr, actionName, reqChart, reqName, failed, "chart is not installable")
-
-		return nil, typeErr
+	if err = checkInstallable(chart); err != nil {
+		return nil, nil, "chart is not installable", chartRequestError{err: err}
 	}
 
 	// Update chart dependencies
@@ -673,93 +792,172 @@ func (h *Handler) getChart(
 
 			err = manager.Update()
 			if err != nil {
-				h.logActionState(zlog.Error(), err, actionName, reqChart, reqName, failed, "updating dependencies")
-				return nil, err
+				return nil, nil, "updating dependencies", err
 			}
 		}
 	}
 
-	return chart, nil
+	return chart, signer, "", nil
+}
+
+// getRequestChart returns the loaded chart of the request if it has one, or
+// locates and loads its chart like loadRequestChart.
+func (h *Handler) getRequestChart(
+	actionName string,
+	req CommonInstallUpdateRequest,
+	chartPathOptions action.ChartPathOptions,
+	dependencyUpdate bool,
+) (*chart.Chart, error) {
+	if err := h.loadRequestChart(actionName, &req, chartPathOptions, dependencyUpdate); err != nil {
+		return nil, err
+	}
+
+	return req.loadedChart, nil
+}
+
+// loadRequestChart locates and loads the chart of the request like getChart,
+// unless it is loaded, verifying its provenance if the request or the server
+// requires it.
+func (h *Handler) loadRequestChart(
+	actionName string,
+	req *CommonInstallUpdateRequest,
+	chartPathOptions action.ChartPathOptions,
+	dependencyUpdate bool,
+) error {
+	if req.loadedChart != nil {
+		return nil
+	}
+
+	chartPathOptions, err := h.verificationOptions(*req, chartPathOptions)
+	if err != nil {
+		return chartRequestError{err: err}
+	}
+
+	// Unlike getChart, this doesn't set the action status: a request that is
+	// rejected mustn't replace the status of an action running on the release.
+	chart, signer, failure, err := h.loadChart(req.Chart, chartPathOptions, dependencyUpdate, h.EnvSettings)
+	if err != nil {
+		zlog.Error().Err(err).
+			Str("chart", req.Chart).
+			Str("action", actionName).
+			Str("releaseName", req.Name).
+			Msg(failure)
+
+		return err
+	}
+
+	req.loadedChart, req.signer = chart, signer
+
+	return nil
+}
+
+// checkInstallable returns an error unless the chart is of type application or empty.
+func checkInstallable(chart *chart.Chart) error {
+	if chart.Metadata.Type != "" && chart.Metadata.Type != "application" {
+		return fmt.Errorf("chart type %q is not installable", chart.Metadata.Type)
+	}
+
+	return nil
 }
 
 // Verify the user has minimal privileges by performing a whoami check.
 // This prevents spurious downloads by ensuring basic authentication before proceeding.
 func VerifyUser(actionConfig *action.Configuration, req InstallRequest) bool {
-	restConfig, err := actionConfig.RESTClientGetter.ToRESTConfig()
+	_, ok := verifyUser(actionConfig, req)
+
+	return ok
+}
+
+// verifyUser is VerifyUser that also returns who the user is.
+func verifyUser(actionConfig *action.Configuration, req InstallRequest) (string, bool) {
+	user, err := actionUser(actionConfig)
 	if err != nil {
 		logger.Log(logger.LevelError,
 			map[string]string{logFieldChart: req.Chart, logFieldReleaseName: req.Name},
 			err, "getting chart")
 
-		return false
+		return "", false
 	}
 
-	cs, err := kubernetes.NewForConfig(restConfig)
+	return user, true
+}
+
+// actionUser returns who the cluster of an action config authenticates its
+// requests as, or an error if they're anonymous.
+func actionUser(actionConfig *action.Configuration) (string, error) {
+	if actionConfig.RESTClientGetter == nil {
+		return "", errors.New("no cluster to authenticate with")
+	}
+
+	restConfig, err := actionConfig.RESTClientGetter.ToRESTConfig()
 	if err != nil {
-		logger.Log(logger.LevelError,
-			map[string]string{logFieldChart: req.Chart, logFieldReleaseName: req.Name},
-			err, "getting chart")
+		return "", err
+	}
 
-		return false
+	cs, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return "", err
 	}
 
 	review, err := cs.AuthenticationV1().SelfSubjectReviews().Create(context.Background(),
 		&authv1.SelfSubjectReview{}, metav1.CreateOptions{})
 	if err != nil {
-		logger.Log(logger.LevelError,
-			map[string]string{logFieldChart: req.Chart, logFieldReleaseName: req.Name},
-			err, "getting chart")
+		return "", err
+	}
 
-		return false
+	if user := review.Status.UserInfo.Username; user != "" && user != "system:anonymous" {
+		return user, nil
 	}
 
-	if user := review.Status.UserInfo.Username; user == "" || user == "system:anonymous" {
-		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart, logFieldReleaseName: req.Name},
-			errors.New("insufficient privileges"), "getting chart: user is not authorized to perform this operation")
+	return "", fmt.Errorf("insufficient privileges: %w", errUnauthorized)
+}
 
-		return false
+// decodeValues decodes the base64 encoded YAML values of an install or upgrade request.
+func decodeValues(encoded string) (map[string]interface{}, error) {
+	decodedBytes, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+	if err = yaml.Unmarshal(decodedBytes, &values); err != nil {
+		return nil, err
 	}
 
-	return true
+	return values, nil
 }
 
-func (h *Handler) installRelease(req InstallRequest, actionConfig *action.Configuration) {
+func newInstallClient(req InstallRequest, actionConfig *action.Configuration) *action.Install {
 	installClient := action.NewInstall(actionConfig)
 	installClient.ReleaseName = req.Name
 	installClient.Namespace = req.Namespace
 	installClient.Description = req.Description
 	installClient.CreateNamespace = req.CreateNamespace
 	installClient.Version = req.Version
+	installClient.PlainHTTP = req.PlainHTTP
+	installClient.PostRenderer = req.postRenderer
+	req.ActionOptions.applyToInstall(installClient)
 
-	if !VerifyUser(actionConfig, req) {
-		return
-	}
+	return installClient
+}
 
-	chart, err := h.getChart("install", req.Chart, req.Name,
-		installClient.ChartPathOptions, req.DependencyUpdate, h.EnvSettings)
-	if err != nil {
-		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart, logFieldReleaseName: req.Name},
-			err, "getting chart")
+func (h *Handler) installRelease(req InstallRequest, actionConfig *action.Configuration) error {
+	installClient := newInstallClient(req, actionConfig)
 
-		return
+	chart, err := h.getRequestChart("install", req.CommonInstallUpdateRequest,
+		installClient.ChartPathOptions, req.DependencyUpdate)
+	if err != nil {
+		h.logActionState(zlog.Error(), err, "install", req.Chart, req.Name, failed, "getting chart")
+		return err
 	}
 
-	decodedBytes, err := base64.StdEncoding.DecodeString(req.Values)
+	values, err := decodeValues(req.Values)
 	if err != nil {
 		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart, logFieldReleaseName: req.Name},
 			err, "decoding values")
 		h.setReleaseStatusSilent("install", req.Name, failed, err)
 
-		return
-	}
-
-	values := make(map[string]interface{})
-	if err = yaml.Unmarshal(decodedBytes, &values); err != nil {
-		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart, logFieldReleaseName: req.Name},
-			err, "unmarshalling values")
-		h.setReleaseStatusSilent("install", req.Name, failed, err)
-
-		return
+		return err
 	}
 
 	if _, err = installClient.Run(chart, values); err != nil {
@@ -767,10 +965,12 @@ func (h *Handler) installRelease(req InstallRequest, actionConfig *action.Config
 			err, "installing chart")
 		h.setReleaseStatusSilent("install", req.Name, failed, err)
 
-		return
+		return err
 	}
 
 	h.setReleaseStatusSilent("install", req.Name, success, nil)
+
+	return nil
 }
 
 type UpgradeReleaseRequest struct {
@@ -780,7 +980,15 @@ type UpgradeReleaseRequest struct {
 
 func (req *UpgradeReleaseRequest) Validate() error {
 	validate := validator.New()
-	return validate.Struct(req)
+	if err := validate.Struct(req); err != nil {
+		return err
+	}
+
+	if err := req.PostRenderer.Validate(); err != nil {
+		return err
+	}
+
+	return req.ActionOptions.Validate("upgrade")
 }
 
 func (h *Handler) UpgradeRelease(clientConfig clientcmd.ClientConfig, w http.ResponseWriter, r *http.Request) {
@@ -793,7 +1001,17 @@ func (h *Handler) UpgradeRelease(clientConfig clientcmd.ClientConfig, w http.Res
 		return
 	}
 
-	err = req.Validate()
+	h.startUpgrade(clientConfig, w, req)
+}
+
+// startUpgrade validates an upgrade request and starts upgrading the release,
+// or renders the upgrade for a dry run.
+func (h *Handler) startUpgrade(clientConfig clientcmd.ClientConfig, w http.ResponseWriter, req UpgradeReleaseRequest) {
+	err := req.Validate()
+	if err == nil {
+		req.postRenderer, err = h.postRenderer(req.PostRenderer)
+	}
+
 	if err != nil {
 		handleError(w, req.Name, err, "validating request for upgrade release", http.StatusBadRequest)
 		return
@@ -808,21 +1026,57 @@ func (h *Handler) UpgradeRelease(clientConfig clientcmd.ClientConfig, w http.Res
 		return
 	}
 
+	if err = h.setRegistryClient(actionConfig, req.PlainHTTP); err != nil {
+		handleError(w, req.Name, err, "creating registry client", http.StatusInternalServerError)
+		return
+	}
+
 	// check if release exists
-	_, err = actionConfig.Releases.Deployed(req.Name)
+	current, err := actionConfig.Releases.Deployed(req.Name)
 	if errors.Is(err, driver.ErrReleaseNotFound) {
 		handleError(w, req.Name, err, "release not found", http.StatusNotFound)
 		return
 	}
 
-	err = h.setReleaseStatus("upgrade", req.Name, processing, nil)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if apierrors.IsForbidden(err) {
+			status = http.StatusForbidden
+		}
+
+		handleError(w, req.Name, err, "getting deployed release", status)
+
+		return
+	}
+
+	upgradeClient := newUpgradeClient(req, actionConfig)
+	if !h.checkRequestValues(w, "upgrade", &req.CommonInstallUpdateRequest,
+		upgradeClient.ChartPathOptions, true, current) {
+		return
+	}
+
+	if req.DryRun != "" {
+		h.dryRunUpgrade(w, req, actionConfig)
+		return
+	}
+
+	err = h.setReleaseProcessing("upgrade", req.Name, req.ActionOptions, req.signer)
 	if err != nil {
 		handleError(w, req.Name, err, "setting status", http.StatusInternalServerError)
 		return
 	}
 
+	record := h.newActionRecord("upgrade", req.Namespace, req.Name,
+		h.recordedUser(actionConfig, "upgrade", req.Name))
+	if record != nil {
+		record.Chart = req.Chart
+		record.Version = req.Version
+		record.ValuesHash = valuesHash(req.Values)
+	}
+
 	go func(h *Handler) {
-		h.upgradeRelease(req, actionConfig)
+		err := h.upgradeRelease(req, actionConfig)
+		h.recordAction(record, err)
 	}(h)
 
 	h.returnResponse(w, req.Name, http.StatusAccepted, "upgrade request accepted")
@@ -849,43 +1103,44 @@ func (h *Handler) logActionState(zlog *zerolog.Event,
 	h.setReleaseStatusSilent(action, releaseName, status, err)
 }
 
-func (h *Handler) upgradeRelease(req UpgradeReleaseRequest, actionConfig *action.Configuration) {
-	// find chart
+func newUpgradeClient(req UpgradeReleaseRequest, actionConfig *action.Configuration) *action.Upgrade {
 	upgradeClient := action.NewUpgrade(actionConfig)
 	upgradeClient.Namespace = req.Namespace
 	upgradeClient.Description = req.Description
 	upgradeClient.Version = req.Version
+	upgradeClient.PlainHTTP = req.PlainHTTP
+	upgradeClient.PostRenderer = req.postRenderer
+	req.ActionOptions.applyToUpgrade(upgradeClient)
 
-	chart, err := h.getChart("upgrade", req.Chart, req.Name, upgradeClient.ChartPathOptions, true, h.EnvSettings)
-	if err != nil {
-		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart, logFieldReleaseName: req.Name},
-			err, "getting chart")
-
-		return
-	}
+	return upgradeClient
+}
 
-	values := make(map[string]interface{})
+func (h *Handler) upgradeRelease(req UpgradeReleaseRequest, actionConfig *action.Configuration) error {
+	// find chart
+	upgradeClient := newUpgradeClient(req, actionConfig)
 
-	valuesStr, err := base64.StdEncoding.DecodeString(req.Values)
+	chart, err := h.getRequestChart("upgrade", req.CommonInstallUpdateRequest, upgradeClient.ChartPathOptions, true)
 	if err != nil {
-		h.logActionState(zlog.Error(), err, "upgrade", req.Chart, req.Name, failed, "values decoding failed")
-		return
+		h.logActionState(zlog.Error(), err, "upgrade", req.Chart, req.Name, failed, "getting chart")
+		return err
 	}
 
-	err = yaml.Unmarshal(valuesStr, &values)
+	values, err := decodeValues(req.Values)
 	if err != nil {
-		h.logActionState(zlog.Error(), err, "upgrade", req.Chart, req.Name, failed, "values un-marshalling failed")
-		return
+		h.logActionState(zlog.Error(), err, "upgrade", req.Chart, req.Name, failed, "values decoding failed")
+		return err
 	}
 
 	// Upgrade chart
 	_, err = upgradeClient.Run(req.Name, chart, values)
 	if err != nil {
 		h.logActionState(zlog.Error(), err, "upgrade", req.Chart, req.Name, failed, "chart upgrade failed")
-		return
+		return err
 	}
 
 	h.logActionState(zlog.Info(), nil, "upgrade", req.Chart, req.Name, success, "chart upgradeable is successful")
+
+	return nil
 }
 
 type ActionStatusRequest struct {
@@ -904,7 +1159,8 @@ func (a *ActionStatusRequest) Validate() error {
 		return err
 	}
 
-	if a.Action != "install" && a.Action != "upgrade" && a.Action != "uninstall" && a.Action != "rollback" {
+	if a.Action != "install" && a.Action != "upgrade" && a.Action != "uninstall" && a.Action != "rollback" &&
+		a.Action != actionTest {
 		return errors.New("invalid action")
 	}
 
@@ -938,10 +1194,22 @@ func (h *Handler) GetActionStatus(clientConfig clientcmd.ClientConfig, w http.Re
 		return
 	}
 
-	response := map[string]string{
+	response := map[string]interface{}{
 		"status": stat.Status,
 	}
 
+	if stat.Options != nil {
+		response["options"] = stat.Options
+	}
+
+	if stat.Tests != nil {
+		response["tests"] = stat.Tests
+	}
+
+	if stat.Signer != nil {
+		response["signer"] = stat.Signer
+	}
+
 	if stat.Status == success {
 		response["message"] = "action completed successfully"
 	}
@@ -0,0 +1,303 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/schema"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+)
+
+// Operation names of the release detail endpoints.
+const (
+	opGetReleaseManifest = "get_release_manifest"
+	opGetReleaseValues   = "get_release_values"
+	opGetReleaseNotes    = "get_release_notes"
+	opGetReleaseHooks    = "get_release_hooks"
+)
+
+// formatJSON selects JSON responses from the release detail endpoints, which default to YAML.
+const formatJSON = "json"
+
+// ReleaseDetailRequest selects a revision of a release, and the format of the response.
+type ReleaseDetailRequest struct {
+	Name      string `json:"name" validate:"required"`
+	Namespace string `json:"namespace" validate:"required"`
+	// Revision is the release revision. The latest revision is used when it is 0.
+	Revision int `json:"revision" validate:"gte=0"`
+	// Format is "yaml", the default, or "json".
+	Format string `json:"format" validate:"omitempty,oneof=yaml json"`
+}
+
+type ReleaseValuesRequest struct {
+	ReleaseDetailRequest
+	// All returns the computed values, the chart defaults merged with the user
+	// supplied values, like helm get values --all.
+	All bool `json:"all"`
+}
+
+// ReleaseNotes is the JSON format of the notes endpoint.
+type ReleaseNotes struct {
+	Notes string `json:"notes"`
+}
+
+// GetReleaseManifest returns the manifest of a release revision. In YAML it is
+// the manifest as stored by helm, in JSON a list of the resources.
+func (h *Handler) GetReleaseManifest(clientConfig clientcmd.ClientConfig, w http.ResponseWriter, r *http.Request) {
+	var req ReleaseDetailRequest
+
+	if rel, _, ok := h.getReleaseForDetail(clientConfig, w, r, &req, &req, opGetReleaseManifest); ok {
+		writeReleaseManifest(w, req, rel)
+	}
+}
+
+func writeReleaseManifest(w http.ResponseWriter, req ReleaseDetailRequest, rel *release.Release) {
+	if req.Format != formatJSON {
+		writeReleaseDetailText(w, req, opGetReleaseManifest, "application/yaml", rel.Manifest)
+		return
+	}
+
+	resources, err := manifestResources(rel.Manifest)
+	if err != nil {
+		handleError(w, req.Name, err, "parsing manifest", http.StatusInternalServerError)
+		return
+	}
+
+	writeReleaseDetailJSON(w, req, opGetReleaseManifest, resources)
+}
+
+// GetReleaseValues returns the user supplied values of a release revision, or all the computed values.
+func (h *Handler) GetReleaseValues(clientConfig clientcmd.ClientConfig, w http.ResponseWriter, r *http.Request) {
+	var req ReleaseValuesRequest
+
+	if rel, _, ok := h.getReleaseForDetail(clientConfig, w, r, &req, &req.ReleaseDetailRequest, opGetReleaseValues); ok {
+		writeReleaseValues(w, req, rel)
+	}
+}
+
+func writeReleaseValues(w http.ResponseWriter, req ReleaseValuesRequest, rel *release.Release) {
+	values := rel.Config
+	if req.All {
+		var err error
+
+		values, err = releaseComputedValues(rel)
+		if err != nil {
+			handleError(w, req.Name, err, "computing values", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+
+	if req.Format == formatJSON {
+		writeReleaseDetailJSON(w, req.ReleaseDetailRequest, opGetReleaseValues, values)
+		return
+	}
+
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		handleError(w, req.Name, err, "encoding values", http.StatusInternalServerError)
+		return
+	}
+
+	writeReleaseDetailText(w, req.ReleaseDetailRequest, opGetReleaseValues, "application/yaml", string(data))
+}
+
+// GetReleaseNotes returns the notes of a release revision, as text or in JSON.
+func (h *Handler) GetReleaseNotes(clientConfig clientcmd.ClientConfig, w http.ResponseWriter, r *http.Request) {
+	var req ReleaseDetailRequest
+
+	if rel, _, ok := h.getReleaseForDetail(clientConfig, w, r, &req, &req, opGetReleaseNotes); ok {
+		writeReleaseNotes(w, req, rel)
+	}
+}
+
+func writeReleaseNotes(w http.ResponseWriter, req ReleaseDetailRequest, rel *release.Release) {
+	notes := ""
+	if rel.Info != nil {
+		notes = rel.Info.Notes
+	}
+
+	if req.Format == formatJSON {
+		writeReleaseDetailJSON(w, req, opGetReleaseNotes, ReleaseNotes{Notes: notes})
+		return
+	}
+
+	writeReleaseDetailText(w, req, opGetReleaseNotes, "text/plain", notes)
+}
+
+// GetReleaseHooks returns the hooks of a release revision. In YAML they are
+// the hook manifests like helm get hooks, in JSON the hooks with their last run.
+func (h *Handler) GetReleaseHooks(clientConfig clientcmd.ClientConfig, w http.ResponseWriter, r *http.Request) {
+	var req ReleaseDetailRequest
+
+	if rel, _, ok := h.getReleaseForDetail(clientConfig, w, r, &req, &req, opGetReleaseHooks); ok {
+		writeReleaseHooks(w, req, rel)
+	}
+}
+
+func writeReleaseHooks(w http.ResponseWriter, req ReleaseDetailRequest, rel *release.Release) {
+	hooks := rel.Hooks
+	if hooks == nil {
+		hooks = []*release.Hook{}
+	}
+
+	if req.Format == formatJSON {
+		writeReleaseDetailJSON(w, req, opGetReleaseHooks, hooks)
+		return
+	}
+
+	var manifest strings.Builder
+	for _, hook := range hooks {
+		fmt.Fprintf(&manifest, "---\n# Source: %s\n%s\n", hook.Path, hook.Manifest)
+	}
+
+	writeReleaseDetailText(w, req, opGetReleaseHooks, "application/yaml", manifest.String())
+}
+
+// getReleaseForDetail decodes the query into target, which is or embeds req,
+// and returns the requested revision with the action config used to get it.
+// It writes the error response and returns false if that fails.
+func (h *Handler) getReleaseForDetail(
+	clientConfig clientcmd.ClientConfig,
+	w http.ResponseWriter,
+	r *http.Request,
+	target interface{},
+	req *ReleaseDetailRequest,
+	op string,
+) (*release.Release, *action.Configuration, bool) {
+	if err := schema.NewDecoder().Decode(target, r.URL.Query()); err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRequest: op}, err, "decoding request")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return nil, nil, false
+	}
+
+	if err := validator.New().Struct(target); err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRequest: op}, err, "validating request")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return nil, nil, false
+	}
+
+	actionConfig, err := NewActionConfig(clientConfig, req.Namespace)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRequest: op}, err, "creating action config")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return nil, nil, false
+	}
+
+	rel, err := getReleaseRevision(actionConfig, req.Name, req.Revision)
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		logger.Log(logger.LevelError, map[string]string{logFieldReleaseName: req.Name, logFieldRequest: op},
+			err, "release not found")
+		http.Error(w, err.Error(), http.StatusNotFound)
+
+		return nil, nil, false
+	}
+
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldReleaseName: req.Name, logFieldRequest: op},
+			err, "getting release")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return nil, nil, false
+	}
+
+	return rel, actionConfig, true
+}
+
+// getReleaseRevision returns a revision of a release, or the latest one if revision is 0.
+func getReleaseRevision(actionConfig *action.Configuration, name string, revision int) (*release.Release, error) {
+	getClient := action.NewGet(actionConfig)
+	getClient.Version = revision
+
+	return getClient.Run(name)
+}
+
+// releaseComputedValues returns the chart default values merged with the user supplied values.
+func releaseComputedValues(rel *release.Release) (map[string]interface{}, error) {
+	if rel.Chart == nil {
+		return rel.Config, nil
+	}
+
+	return chartutil.CoalesceValues(rel.Chart, rel.Config)
+}
+
+// manifestResources parses the documents of a manifest in order, skipping empty ones.
+func manifestResources(manifest string) ([]map[string]interface{}, error) {
+	docs := releaseutil.SplitManifests(manifest)
+
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	resources := make([]map[string]interface{}, 0, len(keys))
+
+	for _, key := range keys {
+		var resource map[string]interface{}
+		if err := yaml.Unmarshal([]byte(docs[key]), &resource); err != nil {
+			return nil, err
+		}
+
+		if len(resource) > 0 {
+			resources = append(resources, resource)
+		}
+	}
+
+	return resources, nil
+}
+
+func writeReleaseDetailText(w http.ResponseWriter, req ReleaseDetailRequest, op, contentType, body string) {
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write([]byte(body)); err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRequest: op, logFieldReleaseName: req.Name},
+			err, "writing response")
+	}
+}
+
+func writeReleaseDetailJSON(w http.ResponseWriter, req ReleaseDetailRequest, op string, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRequest: op, logFieldReleaseName: req.Name},
+			err, "encoding response")
+	}
+}
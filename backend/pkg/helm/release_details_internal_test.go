@@ -0,0 +1,149 @@
+package helm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gorilla/schema"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+const testReleaseManifest = "---\n# Source: demo/templates/cm.yaml\napiVersion: v1\nkind: ConfigMap\n" +
+	"metadata:\n  name: cm\n" +
+	"---\n# Source: demo/templates/svc.yaml\napiVersion: v1\nkind: Service\nmetadata:\n  name: svc\n"
+
+func newDetailTestRelease() *release.Release {
+	rel := newTestRelease("demo", 1, release.StatusDeployed, testReleaseManifest)
+	rel.Info.Notes = "Thanks for installing.\n"
+	rel.Config = map[string]interface{}{"replicas": 2}
+	rel.Chart = &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "demo", Version: "0.1.0"},
+		Values:   map[string]interface{}{"replicas": 1, "image": "nginx"},
+	}
+	rel.Hooks = []*release.Hook{{
+		Name:     "demo-test",
+		Kind:     "Pod",
+		Path:     "demo/templates/test.yaml",
+		Manifest: "apiVersion: v1\nkind: Pod\nmetadata:\n  name: demo-test",
+		Events:   []release.HookEvent{release.HookTest},
+	}}
+
+	return rel
+}
+
+func TestWriteReleaseManifest(t *testing.T) {
+	rel := newDetailTestRelease()
+
+	rr := httptest.NewRecorder()
+	writeReleaseManifest(rr, ReleaseDetailRequest{Name: "demo"}, rel)
+	assert.Equal(t, "application/yaml; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, testReleaseManifest, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	writeReleaseManifest(rr, ReleaseDetailRequest{Name: "demo", Format: formatJSON}, rel)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var resources []map[string]interface{}
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resources))
+	require.Len(t, resources, 2)
+	assert.Equal(t, "ConfigMap", resources[0]["kind"])
+	assert.Equal(t, "Service", resources[1]["kind"])
+}
+
+func TestWriteReleaseValues(t *testing.T) {
+	rel := newDetailTestRelease()
+
+	rr := httptest.NewRecorder()
+	writeReleaseValues(rr, ReleaseValuesRequest{}, rel)
+	assert.Equal(t, "replicas: 2\n", rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	writeReleaseValues(rr, ReleaseValuesRequest{All: true}, rel)
+	assert.Equal(t, "image: nginx\nreplicas: 2\n", rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	writeReleaseValues(rr, ReleaseValuesRequest{ReleaseDetailRequest: ReleaseDetailRequest{Format: formatJSON}}, rel)
+	assert.JSONEq(t, `{"replicas": 2}`, rr.Body.String())
+
+	rel.Config = nil
+	rr = httptest.NewRecorder()
+	writeReleaseValues(rr, ReleaseValuesRequest{ReleaseDetailRequest: ReleaseDetailRequest{Format: formatJSON}}, rel)
+	assert.JSONEq(t, `{}`, rr.Body.String())
+}
+
+func TestWriteReleaseNotesAndHooks(t *testing.T) {
+	rel := newDetailTestRelease()
+
+	rr := httptest.NewRecorder()
+	writeReleaseNotes(rr, ReleaseDetailRequest{}, rel)
+	assert.Equal(t, "text/plain; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "Thanks for installing.\n", rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	writeReleaseNotes(rr, ReleaseDetailRequest{Format: formatJSON}, rel)
+	assert.JSONEq(t, `{"notes": "Thanks for installing.\n"}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	writeReleaseHooks(rr, ReleaseDetailRequest{}, rel)
+	assert.Equal(t, "---\n# Source: demo/templates/test.yaml\napiVersion: v1\nkind: Pod\nmetadata:\n  name: demo-test\n",
+		rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	writeReleaseHooks(rr, ReleaseDetailRequest{Format: formatJSON}, rel)
+
+	var hooks []release.Hook
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&hooks))
+	require.Len(t, hooks, 1)
+	assert.Equal(t, "demo-test", hooks[0].Name)
+	assert.Equal(t, []release.HookEvent{release.HookTest}, hooks[0].Events)
+}
+
+func TestGetReleaseRevision(t *testing.T) {
+	actionConfig := newMemoryActionConfig(t)
+	require.NoError(t, actionConfig.Releases.Create(newTestRelease("demo", 1, release.StatusSuperseded, "first")))
+	require.NoError(t, actionConfig.Releases.Create(newTestRelease("demo", 2, release.StatusDeployed, "second")))
+
+	rel, err := getReleaseRevision(actionConfig, "demo", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "second", rel.Manifest)
+
+	rel, err = getReleaseRevision(actionConfig, "demo", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "first", rel.Manifest)
+
+	_, err = getReleaseRevision(actionConfig, "demo", 3)
+	assert.ErrorIs(t, err, driver.ErrReleaseNotFound)
+}
+
+func TestReleaseValuesRequestDecoding(t *testing.T) {
+	var req ReleaseValuesRequest
+
+	query := url.Values{"name": {"demo"}, "namespace": {"default"}, "revision": {"2"}, "format": {"json"}, "all": {"true"}}
+	require.NoError(t, schema.NewDecoder().Decode(&req, query))
+	assert.Equal(t, ReleaseValuesRequest{
+		ReleaseDetailRequest: ReleaseDetailRequest{Name: "demo", Namespace: "default", Revision: 2, Format: formatJSON},
+		All:                  true,
+	}, req)
+}
+
+func TestGetReleaseManifestRejectsUnknownFormat(t *testing.T) {
+	h := &Handler{Cache: cache.New[interface{}]()}
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet,
+		"/clusters/test/helm/releases/manifest?name=demo&namespace=default&format=xml", nil)
+	rr := httptest.NewRecorder()
+
+	h.GetReleaseManifest(nil, rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -59,7 +59,7 @@ func TestGetChart_InvalidType(t *testing.T) {
 	require.NoError(t, err)
 
 	opts := action.ChartPathOptions{}
-	loadedChart, err := h.getChart("install", chartDir, "test-release", opts, false, h.EnvSettings)
+	loadedChart, _, err := h.getChart("install", chartDir, "test-release", opts, false, h.EnvSettings)
 
 	assert.Nil(t, loadedChart)
 	require.Error(t, err)
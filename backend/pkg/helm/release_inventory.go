@@ -0,0 +1,371 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/schema"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// inventoryConcurrency is how many clusters are listed at the same time.
+	inventoryConcurrency = 8
+	// defaultInventoryTimeout is how long listing the releases of a cluster may take.
+	defaultInventoryTimeout = 15 * time.Second
+	// maxInventoryTimeout is the longest per cluster timeout a request may ask for.
+	maxInventoryTimeout = 2 * time.Minute
+
+	opReleaseInventory = "release_inventory"
+)
+
+// InventoryCluster is a cluster whose releases are listed in the release inventory.
+type InventoryCluster struct {
+	Name         string
+	ClientConfig clientcmd.ClientConfig
+	// Err is why the cluster can't be listed, e.g. its context is broken.
+	Err error
+}
+
+type ReleaseInventoryRequest struct {
+	// Clusters are the names of the clusters to list, all of them when empty.
+	// Each value may be a comma separated list of names.
+	Clusters []string `json:"clusters,omitempty"`
+	// Namespace limits the releases to a namespace, all of them when empty.
+	Namespace *string `json:"namespace,omitempty"`
+	// Chart limits the releases to those of the chart with this name.
+	Chart *string `json:"chart,omitempty"`
+	// Filter is a regular expression the release names must match.
+	Filter *string `json:"filter,omitempty"`
+	// All includes the releases in every status, not only the deployed and failed ones.
+	All *bool `json:"all,omitempty"`
+	// Timeout is how many seconds listing the releases of a cluster may take.
+	Timeout *int `json:"timeout,omitempty"`
+}
+
+func (req *ReleaseInventoryRequest) Validate() error {
+	if req.Timeout != nil && (*req.Timeout <= 0 || time.Duration(*req.Timeout)*time.Second > maxInventoryTimeout) {
+		return fmt.Errorf("timeout must be between 1 and %d seconds", int(maxInventoryTimeout.Seconds()))
+	}
+
+	return nil
+}
+
+// clusterNames returns the requested cluster names, without duplicates.
+func (req *ReleaseInventoryRequest) clusterNames() []string {
+	var names []string
+
+	for _, value := range req.Clusters {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
+
+func (req *ReleaseInventoryRequest) timeout() time.Duration {
+	if req.Timeout == nil {
+		return defaultInventoryTimeout
+	}
+
+	return time.Duration(*req.Timeout) * time.Second
+}
+
+// ReleaseInventoryEntry is a release in the release inventory.
+type ReleaseInventoryEntry struct {
+	Cluster      string    `json:"cluster"`
+	Namespace    string    `json:"namespace"`
+	Release      string    `json:"release"`
+	Revision     int       `json:"revision"`
+	Chart        string    `json:"chart"`
+	ChartVersion string    `json:"chartVersion"`
+	AppVersion   string    `json:"appVersion"`
+	Status       string    `json:"status"`
+	Updated      time.Time `json:"updated"`
+}
+
+// ClusterInventoryError is why the releases of a cluster are missing from the inventory.
+type ClusterInventoryError struct {
+	Cluster string `json:"cluster"`
+	Error   string `json:"error"`
+}
+
+type ReleaseInventoryResponse struct {
+	Releases []ReleaseInventoryEntry `json:"releases"`
+	// Errors are about the clusters whose releases couldn't be listed.
+	Errors []ClusterInventoryError `json:"errors,omitempty"`
+}
+
+// ListReleaseInventory lists the releases of several clusters, returning
+// them in one table. A cluster failing to list its releases doesn't fail the
+// request, it is reported in the errors.
+func (h *Handler) ListReleaseInventory(clusters []InventoryCluster, w http.ResponseWriter, r *http.Request) {
+	var req ReleaseInventoryRequest
+
+	decoder := schema.NewDecoder()
+
+	err := decoder.Decode(&req, r.URL.Query())
+	if err == nil {
+		err = req.Validate()
+	}
+
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRequest: opReleaseInventory},
+			err, "parsing request")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	response := releaseInventory(r.Context(), selectInventoryClusters(clusters, req.clusterNames()), req)
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRequest: opReleaseInventory},
+			err, "encoding response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// selectInventoryClusters returns the clusters with the given names, or all
+// of them without names. Names without a cluster are returned as clusters
+// that failed.
+func selectInventoryClusters(clusters []InventoryCluster, names []string) []InventoryCluster {
+	if len(names) == 0 {
+		return clusters
+	}
+
+	selected := make([]InventoryCluster, 0, len(names))
+
+	for _, name := range names {
+		i := slices.IndexFunc(clusters, func(cluster InventoryCluster) bool { return cluster.Name == name })
+		if i < 0 {
+			selected = append(selected, InventoryCluster{Name: name, Err: fmt.Errorf("cluster %q not found", name)})
+			continue
+		}
+
+		selected = append(selected, clusters[i])
+	}
+
+	return selected
+}
+
+// releaseInventory lists the releases of the clusters, inventoryConcurrency
+// clusters at a time.
+func releaseInventory(
+	ctx context.Context,
+	clusters []InventoryCluster,
+	req ReleaseInventoryRequest,
+) ReleaseInventoryResponse {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		response = ReleaseInventoryResponse{Releases: []ReleaseInventoryEntry{}}
+		slots    = make(chan struct{}, inventoryConcurrency)
+	)
+
+	for _, cluster := range clusters {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			entries, err := listClusterReleases(ctx, cluster, req, slots)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				logger.Log(logger.LevelWarn, map[string]string{logFieldRequest: opReleaseInventory, "cluster": cluster.Name},
+					err, "listing cluster releases")
+
+				response.Errors = append(response.Errors, ClusterInventoryError{Cluster: cluster.Name, Error: err.Error()})
+
+				return
+			}
+
+			response.Releases = append(response.Releases, entries...)
+		}()
+	}
+
+	wg.Wait()
+
+	slices.SortFunc(response.Releases, func(a, b ReleaseInventoryEntry) int {
+		return cmp.Or(
+			cmp.Compare(a.Cluster, b.Cluster),
+			cmp.Compare(a.Namespace, b.Namespace),
+			cmp.Compare(a.Release, b.Release),
+		)
+	})
+	slices.SortFunc(response.Errors, func(a, b ClusterInventoryError) int {
+		return cmp.Compare(a.Cluster, b.Cluster)
+	})
+
+	return response
+}
+
+// listClusterReleases lists the releases of a cluster once it gets one of the
+// slots, giving up after the timeout of the request. The slot is only freed
+// once the listing returns, even if it was given up on, so that the listings
+// still running never exceed the slots.
+func listClusterReleases(
+	ctx context.Context,
+	cluster InventoryCluster,
+	req ReleaseInventoryRequest,
+	slots chan struct{},
+) ([]ReleaseInventoryEntry, error) {
+	if cluster.Err != nil {
+		return nil, cluster.Err
+	}
+
+	slots <- struct{}{}
+
+	timeout := req.timeout()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		releases []*release.Release
+		err      error
+	}
+
+	// The requests to the cluster time out on their own, so the listing
+	// doesn't outlive the deadline for long when it's abandoned.
+	results := make(chan result, 1)
+
+	go func() {
+		defer func() { <-slots }()
+
+		releases, err := listInventoryReleases(timeoutClientConfig{cluster.ClientConfig, timeout}, req)
+		results <- result{releases, err}
+	}()
+
+	var res result
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("listing releases: %w after %s", ctx.Err(), timeout)
+	case res = <-results:
+	}
+
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	entries := make([]ReleaseInventoryEntry, 0, len(res.releases))
+
+	for _, rel := range res.releases {
+		entry := ReleaseInventoryEntry{
+			Cluster:   cluster.Name,
+			Namespace: rel.Namespace,
+			Release:   rel.Name,
+			Revision:  rel.Version,
+		}
+
+		if rel.Chart != nil && rel.Chart.Metadata != nil {
+			entry.Chart = rel.Chart.Metadata.Name
+			entry.ChartVersion = rel.Chart.Metadata.Version
+			entry.AppVersion = rel.Chart.Metadata.AppVersion
+		}
+
+		if req.Chart != nil && *req.Chart != "" && entry.Chart != *req.Chart {
+			continue
+		}
+
+		if rel.Info != nil {
+			entry.Status = rel.Info.Status.String()
+			entry.Updated = rel.Info.LastDeployed.Time
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func listInventoryReleases(
+	clientConfig clientcmd.ClientConfig,
+	req ReleaseInventoryRequest,
+) ([]*release.Release, error) {
+	namespace := ""
+	if req.Namespace != nil {
+		namespace = *req.Namespace
+	}
+
+	actionConfig, err := NewActionConfig(clientConfig, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	allNamespaces := namespace == ""
+
+	return getReleases(ListReleaseRequest{
+		AllNamespaces: &allNamespaces,
+		Namespace:     req.Namespace,
+		Filter:        req.Filter,
+		All:           req.All,
+	}, actionConfig)
+}
+
+// timeoutClientConfig sets a timeout on the requests made with a client config.
+type timeoutClientConfig struct {
+	base    clientcmd.ClientConfig
+	timeout time.Duration
+}
+
+func (c timeoutClientConfig) RawConfig() (api.Config, error) {
+	return c.base.RawConfig()
+}
+
+func (c timeoutClientConfig) ClientConfig() (*rest.Config, error) {
+	config, err := c.base.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	config = rest.CopyConfig(config)
+	config.Timeout = c.timeout
+
+	return config, nil
+}
+
+func (c timeoutClientConfig) Namespace() (string, bool, error) {
+	return c.base.Namespace()
+}
+
+func (c timeoutClientConfig) ConfigAccess() clientcmd.ConfigAccess {
+	return c.base.ConfigAccess()
+}
@@ -0,0 +1,187 @@
+package helm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// newInventoryTestCluster returns a client config for a fake API server that
+// lists the releases stored in Secrets by helm.
+func newInventoryTestCluster(t *testing.T, releases ...*release.Release) clientcmd.ClientConfig {
+	t.Helper()
+
+	client := fake.NewSimpleClientset()
+
+	for _, rel := range releases {
+		secrets := driver.NewSecrets(client.CoreV1().Secrets(rel.Namespace))
+		require.NoError(t, secrets.Create("sh.helm.release.v1."+rel.Name+".v1", rel))
+	}
+
+	secrets, err := client.CoreV1().Secrets("").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+
+	list := corev1.SecretList{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "SecretList"}, Items: secrets.Items}
+
+	return newTestClientConfigFor(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/version":
+			_ = json.NewEncoder(w).Encode(version.Info{Major: "1", Minor: "33", GitVersion: "v1.33.0"})
+		case "/api/v1/secrets":
+			_ = json.NewEncoder(w).Encode(list)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func newInventoryTestRelease(name, namespace, chartName, version string) *release.Release {
+	return &release.Release{
+		Name:      name,
+		Namespace: namespace,
+		Version:   1,
+		Info:      &release.Info{Status: release.StatusDeployed},
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: chartName, Version: version, AppVersion: "1.0"}},
+	}
+}
+
+func TestListReleaseInventory(t *testing.T) {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+
+	clusters := []InventoryCluster{
+		{
+			Name: "prod",
+			ClientConfig: newInventoryTestCluster(t,
+				newInventoryTestRelease("web", "apps", "nginx", "1.2.0"),
+				newInventoryTestRelease("cache", "apps", "redis", "17.0.0"),
+			),
+		},
+		{
+			Name:         "dev",
+			ClientConfig: newInventoryTestCluster(t, newInventoryTestRelease("web", "default", "nginx", "1.3.0")),
+		},
+		{
+			Name: "slow",
+			ClientConfig: newTestClientConfigFor(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-done:
+				}
+			})),
+		},
+		{Name: "broken", Err: assert.AnError},
+	}
+
+	h := &Handler{}
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet,
+		"/helm/releases/inventory?clusters=prod,dev&clusters=slow,broken,missing&chart=nginx&timeout=1", nil)
+	rr := httptest.NewRecorder()
+	h.ListReleaseInventory(clusters, rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response ReleaseInventoryResponse
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+	require.Len(t, response.Releases, 2)
+	assert.Equal(t, ReleaseInventoryEntry{
+		Cluster:      "dev",
+		Namespace:    "default",
+		Release:      "web",
+		Revision:     1,
+		Chart:        "nginx",
+		ChartVersion: "1.3.0",
+		AppVersion:   "1.0",
+		Status:       "deployed",
+	}, response.Releases[0])
+	assert.Equal(t, "prod", response.Releases[1].Cluster)
+	assert.Equal(t, "1.2.0", response.Releases[1].ChartVersion)
+
+	require.Len(t, response.Errors, 3)
+	assert.Equal(t, "broken", response.Errors[0].Cluster)
+	assert.Equal(t, "missing", response.Errors[1].Cluster)
+	assert.Contains(t, response.Errors[1].Error, "not found")
+	assert.Equal(t, "slow", response.Errors[2].Cluster)
+}
+
+func TestListReleaseInventoryAllClusters(t *testing.T) {
+	clusters := []InventoryCluster{
+		{Name: "a", ClientConfig: newInventoryTestCluster(t, newInventoryTestRelease("web", "apps", "nginx", "1.2.0"))},
+		{Name: "b", ClientConfig: newInventoryTestCluster(t, newInventoryTestRelease("db", "data", "postgres", "12.0.0"))},
+	}
+
+	response := releaseInventory(context.Background(), selectInventoryClusters(clusters, nil), ReleaseInventoryRequest{})
+	require.Len(t, response.Releases, 2)
+	assert.Empty(t, response.Errors)
+	assert.Equal(t, "postgres", response.Releases[1].Chart)
+}
+
+// blockingClientConfig is the client config of a cluster that hangs until
+// unblock is closed.
+type blockingClientConfig struct {
+	base    clientcmd.ClientConfig
+	unblock chan struct{}
+}
+
+func (c blockingClientConfig) RawConfig() (api.Config, error) {
+	return c.base.RawConfig()
+}
+
+func (c blockingClientConfig) Namespace() (string, bool, error) {
+	return c.base.Namespace()
+}
+
+func (c blockingClientConfig) ConfigAccess() clientcmd.ConfigAccess {
+	return c.base.ConfigAccess()
+}
+
+func (c blockingClientConfig) ClientConfig() (*rest.Config, error) {
+	<-c.unblock
+	return nil, errors.New("cluster unreachable")
+}
+
+func TestListClusterReleasesHoldsSlotUntilListingReturns(t *testing.T) {
+	clientConfig := blockingClientConfig{base: newTestClientConfig(t), unblock: make(chan struct{})}
+	cluster := InventoryCluster{Name: "slow", ClientConfig: clientConfig}
+	timeout := 1
+	slots := make(chan struct{}, 1)
+
+	_, err := listClusterReleases(context.Background(), cluster, ReleaseInventoryRequest{Timeout: &timeout}, slots)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Len(t, slots, 1, "the slot is held while the listing runs")
+
+	close(clientConfig.unblock)
+
+	require.Eventually(t, func() bool { return len(slots) == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestReleaseInventoryRequestValidate(t *testing.T) {
+	zero, tooLong := 0, 3600
+
+	assert.Error(t, (&ReleaseInventoryRequest{Timeout: &zero}).Validate())
+	assert.Error(t, (&ReleaseInventoryRequest{Timeout: &tooLong}).Validate())
+	assert.NoError(t, (&ReleaseInventoryRequest{}).Validate())
+	assert.Equal(t, []string{"a", "b", "c"},
+		(&ReleaseInventoryRequest{Clusters: []string{"a, b", "", "a,c"}}).clusterNames())
+}
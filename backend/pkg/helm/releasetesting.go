@@ -0,0 +1,231 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	actionTest     = "test"
+	opTestRelease  = "test_release"
+	maxTestLogSize = 1 << 20
+)
+
+type TestReleaseRequest struct {
+	Name      string `json:"name" validate:"required"`
+	Namespace string `json:"namespace" validate:"required"`
+	// Timeout is how long to wait for each test hook, as a duration such as "5m". Defaults to 5m.
+	Timeout string `json:"timeout,omitempty"`
+}
+
+func (req *TestReleaseRequest) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return err
+	}
+
+	return ActionOptions{Timeout: req.Timeout}.Validate(actionTest)
+}
+
+// ReleaseTestResult is the outcome of one test hook of a release.
+type ReleaseTestResult struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// Phase is Succeeded, Failed, Running or Unknown if the hook didn't run.
+	Phase       string     `json:"phase"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	// Logs are the logs of a test pod, truncated to the first 1MiB.
+	Logs string `json:"logs,omitempty"`
+	// LogsError is why the logs could not be read, e.g. because the pod was
+	// deleted by its hook delete policy.
+	LogsError string `json:"logsError,omitempty"`
+}
+
+// podLogsFunc returns the logs of a pod in the release namespace.
+type podLogsFunc func(podName string) (string, error)
+
+// TestRelease runs the test hooks of a release, like helm test. The results are
+// returned by GetActionStatus with the test action.
+func (h *Handler) TestRelease(clientConfig clientcmd.ClientConfig, w http.ResponseWriter, r *http.Request) {
+	var req TestReleaseRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, req.Name, err, "parsing request for test release", http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		handleError(w, req.Name, err, "validating request for test release", http.StatusBadRequest)
+		return
+	}
+
+	actionConfig, err := NewActionConfig(clientConfig, req.Namespace)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldRequest: opTestRelease},
+			err, "creating action config")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	_, err = actionConfig.Releases.Last(req.Name)
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		handleError(w, req.Name, err, "release not found", http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		status := http.StatusInternalServerError
+		if apierrors.IsForbidden(err) {
+			status = http.StatusForbidden
+		}
+
+		handleError(w, req.Name, err, "getting release", status)
+
+		return
+	}
+
+	if err = h.setReleaseStatus(actionTest, req.Name, processing, nil); err != nil {
+		handleError(w, req.Name, err, "setting status", http.StatusInternalServerError)
+		return
+	}
+
+	go func(h *Handler) {
+		h.testRelease(req, actionConfig, newPodLogsFunc(actionConfig, req.Namespace))
+	}(h)
+
+	h.returnResponse(w, req.Name, http.StatusAccepted, "test request accepted")
+}
+
+// testRelease runs the test hooks and records their results in the status of the test action.
+func (h *Handler) testRelease(req TestReleaseRequest, actionConfig *action.Configuration, podLogs podLogsFunc) {
+	testClient := action.NewReleaseTesting(actionConfig)
+	testClient.Namespace = req.Namespace
+	testClient.Timeout = ActionOptions{Timeout: req.Timeout}.timeout()
+
+	rel, err := testClient.Run(req.Name)
+
+	status := success
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldReleaseName: req.Name, logFieldRequest: opTestRelease},
+			err, "testing release")
+
+		status = failed
+	}
+
+	result := stat{Status: status}
+
+	if err != nil {
+		errString := err.Error()
+		result.Err = &errString
+	}
+
+	if rel != nil {
+		result.Tests = releaseTestResults(rel, podLogs)
+	}
+
+	if cacheErr := h.storeReleaseStat(actionTest, req.Name, result); cacheErr != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldReleaseName: req.Name, "status": status},
+			cacheErr, "unable to set status")
+	}
+}
+
+// releaseTestResults returns the result of each test hook of the release, with
+// the logs of the test pods that ran.
+func releaseTestResults(rel *release.Release, podLogs podLogsFunc) []ReleaseTestResult {
+	results := []ReleaseTestResult{}
+
+	for _, hook := range rel.Hooks {
+		if !slices.Contains(hook.Events, release.HookTest) {
+			continue
+		}
+
+		result := ReleaseTestResult{
+			Name:  hook.Name,
+			Kind:  hook.Kind,
+			Phase: release.HookPhaseUnknown.String(),
+		}
+
+		if hook.LastRun.Phase != "" {
+			result.Phase = hook.LastRun.Phase.String()
+		}
+
+		if !hook.LastRun.StartedAt.IsZero() {
+			startedAt := hook.LastRun.StartedAt.Time
+			result.StartedAt = &startedAt
+		}
+
+		if !hook.LastRun.CompletedAt.IsZero() {
+			completedAt := hook.LastRun.CompletedAt.Time
+			result.CompletedAt = &completedAt
+		}
+
+		if hook.Kind == "Pod" && result.Phase != release.HookPhaseUnknown.String() {
+			logs, err := podLogs(hook.Name)
+			if err != nil {
+				result.LogsError = err.Error()
+			}
+
+			result.Logs = logs
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+func newPodLogsFunc(actionConfig *action.Configuration, namespace string) podLogsFunc {
+	return func(podName string) (string, error) {
+		clientset, err := actionConfig.KubernetesClientSet()
+		if err != nil {
+			return "", err
+		}
+
+		limit := int64(maxTestLogSize)
+
+		stream, err := clientset.CoreV1().Pods(namespace).
+			GetLogs(podName, &corev1.PodLogOptions{LimitBytes: &limit}).
+			Stream(context.Background())
+		if err != nil {
+			return "", err
+		}
+
+		defer stream.Close()
+
+		logs, err := io.ReadAll(stream)
+
+		return string(logs), err
+	}
+}
@@ -0,0 +1,157 @@
+package helm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// newTestingRelease returns a deployed release with two test pods and an install hook.
+func newTestingRelease() *release.Release {
+	rel := newTestRelease("demo", 1, release.StatusDeployed, "")
+
+	for _, name := range []string{"demo-test-a", "demo-test-b"} {
+		rel.Hooks = append(rel.Hooks, &release.Hook{
+			Name:     name,
+			Kind:     "Pod",
+			Path:     "demo/templates/" + name + ".yaml",
+			Manifest: "apiVersion: v1\nkind: Pod\nmetadata:\n  name: " + name,
+			Events:   []release.HookEvent{release.HookTest},
+		})
+	}
+
+	rel.Hooks = append(rel.Hooks, &release.Hook{
+		Name:     "demo-migrate",
+		Kind:     "Job",
+		Path:     "demo/templates/migrate.yaml",
+		Manifest: "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: demo-migrate",
+		Events:   []release.HookEvent{release.HookPreInstall},
+	})
+
+	return rel
+}
+
+func fakePodLogs(podName string) (string, error) {
+	if podName == "demo-test-b" {
+		return "", errors.New("pods \"demo-test-b\" not found")
+	}
+
+	return "ok from " + podName + "\n", nil
+}
+
+func getTestActionStatus(t *testing.T, h *Handler) (string, []ReleaseTestResult) {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet,
+		"/clusters/test/helm/action/status?action=test&name=demo", nil)
+	rr := httptest.NewRecorder()
+	h.GetActionStatus(nil, rr, req)
+	require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+
+	var response struct {
+		Status string              `json:"status"`
+		Tests  []ReleaseTestResult `json:"tests"`
+	}
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+	return response.Status, response.Tests
+}
+
+func TestTestRelease(t *testing.T) {
+	actionConfig := newMemoryActionConfig(t)
+	require.NoError(t, actionConfig.Releases.Create(newTestingRelease()))
+
+	h := &Handler{Cache: cache.New[interface{}]()}
+	h.testRelease(TestReleaseRequest{Name: "demo", Namespace: "default"}, actionConfig, fakePodLogs)
+
+	status, tests := getTestActionStatus(t, h)
+	assert.Equal(t, success, status)
+	require.Len(t, tests, 2)
+
+	assert.Equal(t, "demo-test-a", tests[0].Name)
+	assert.Equal(t, "Pod", tests[0].Kind)
+	assert.Equal(t, "Succeeded", tests[0].Phase)
+	assert.NotNil(t, tests[0].StartedAt)
+	assert.NotNil(t, tests[0].CompletedAt)
+	assert.Equal(t, "ok from demo-test-a\n", tests[0].Logs)
+
+	assert.Equal(t, "Succeeded", tests[1].Phase)
+	assert.Empty(t, tests[1].Logs)
+	assert.Contains(t, tests[1].LogsError, "not found")
+}
+
+func TestTestReleaseFailure(t *testing.T) {
+	actionConfig := newMemoryActionConfig(t)
+	actionConfig.KubeClient = &kubefake.FailingKubeClient{
+		PrintingKubeClient:   kubefake.PrintingKubeClient{Out: io.Discard},
+		WatchUntilReadyError: errors.New("pod demo-test-a failed"),
+	}
+	require.NoError(t, actionConfig.Releases.Create(newTestingRelease()))
+
+	h := &Handler{Cache: cache.New[interface{}]()}
+	h.testRelease(TestReleaseRequest{Name: "demo", Namespace: "default"}, actionConfig, fakePodLogs)
+
+	status, tests := getTestActionStatus(t, h)
+	assert.Equal(t, failed, status)
+	require.Len(t, tests, 2)
+	assert.Equal(t, "Failed", tests[0].Phase)
+	assert.Equal(t, "ok from demo-test-a\n", tests[0].Logs)
+	// Helm stops at the first failing test.
+	assert.Equal(t, "Unknown", tests[1].Phase)
+	assert.Empty(t, tests[1].LogsError)
+}
+
+func TestTestReleaseReturnsReleaseError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		reason     string
+		wantStatus int
+	}{
+		{name: "forbidden", status: http.StatusForbidden, reason: "Forbidden", wantStatus: http.StatusForbidden},
+		{
+			name: "server_error", status: http.StatusInternalServerError, reason: "InternalError",
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientConfig := newTestClientConfigFor(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				_, _ = fmt.Fprintf(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":%q,`+
+					`"code":%d,"message":"listing secrets failed"}`, tt.reason, tt.status)
+			}))
+
+			h := newTestHandler(t)
+			rr := serveTestRequest(t, func(w http.ResponseWriter, r *http.Request) {
+				h.TestRelease(clientConfig, w, r)
+			}, http.MethodPost, "/clusters/test/helm/releases/test", TestReleaseRequest{Name: "demo", Namespace: "default"})
+
+			assert.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+			assert.Contains(t, rr.Body.String(), "listing secrets failed")
+
+			_, err := h.getReleaseStatus(actionTest, "demo")
+			assert.Error(t, err, "the test doesn't start")
+		})
+	}
+}
+
+func TestTestReleaseRequestValidate(t *testing.T) {
+	assert.NoError(t, (&TestReleaseRequest{Name: "demo", Namespace: "default", Timeout: "2m"}).Validate())
+	assert.Error(t, (&TestReleaseRequest{Name: "demo"}).Validate())
+	assert.Error(t, (&TestReleaseRequest{Name: "demo", Namespace: "default", Timeout: "later"}).Validate())
+}
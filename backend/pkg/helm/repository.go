@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -31,7 +32,6 @@ import (
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
 
 	"helm.sh/helm/v3/pkg/cli"
-	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/repo"
 )
 
@@ -47,15 +47,24 @@ var (
 
 // CertFile, KeyFile, and CAFile are intentionally omitted from this struct.
 // Accepting filesystem paths from HTTP clients would allow arbitrary local
-// file reads on the server. These values are preserved from the existing
-// repo entry only (server-side state).
+// file reads on the server. Certificates are sent as PEM data instead, and
+// paths are preserved from the existing repo entry only (server-side state).
+//
+// Credential fields that are nil keep their stored value, and an empty
+// string removes it.
 type AddUpdateRepoRequest struct {
 	Name                  string  `json:"name"`
 	URL                   string  `json:"url"`
 	Username              *string `json:"username"`
 	Password              *string `json:"password"`
+	BearerToken           *string `json:"bearerToken"`
 	InsecureSkipTLSverify *bool   `json:"insecureSkipTLSverify"`
 	PassCredentialsAll    *bool   `json:"passCredentialsAll"`
+	// CertData and KeyData are the PEM encoded client certificate and key.
+	CertData *string `json:"certData"`
+	KeyData  *string `json:"keyData"`
+	// CAData is the PEM encoded CA bundle used to verify the repository.
+	CAData *string `json:"caData"`
 }
 
 func (r AddUpdateRepoRequest) Validate() error {
@@ -63,11 +72,15 @@ func (r AddUpdateRepoRequest) Validate() error {
 		return errors.New("name is required")
 	}
 
+	if err := validateRepositoryName(r.Name); err != nil {
+		return err
+	}
+
 	if strings.TrimSpace(r.URL) == "" {
 		return errors.New("url is required")
 	}
 
-	return nil
+	return r.validateCertificates()
 }
 
 func createFileIfNotThere(fileName string) error {
@@ -116,17 +129,10 @@ func ensureRepositoryFileLocked(locked bool, err error) error {
 
 const timeoutForLock = 30 * time.Second
 
-// applyRequestFields applies non-nil fields from request onto entry,
-// leaving existing entry values unchanged for any field not set in the request.
+// applyRequestFields applies the non-nil TLS and credential passing fields
+// from request onto entry, leaving existing entry values unchanged for any
+// field not set in the request. Credentials are applied by saveCredentials.
 func applyRequestFields(entry *repo.Entry, request AddUpdateRepoRequest) {
-	if request.Username != nil {
-		entry.Username = *request.Username
-	}
-
-	if request.Password != nil {
-		entry.Password = *request.Password
-	}
-
 	if request.InsecureSkipTLSverify != nil {
 		entry.InsecureSkipTLSverify = *request.InsecureSkipTLSverify
 	}
@@ -138,7 +144,9 @@ func applyRequestFields(entry *repo.Entry, request AddUpdateRepoRequest) {
 
 // addRepository adds a repository with the given request fields to the helm config.
 // Returns an error if the repository cannot be created or its index downloaded.
-func addRepository(request AddUpdateRepoRequest, settings *cli.EnvSettings) error {
+func (h *Handler) addRepository(ctx context.Context, request AddUpdateRepoRequest) error {
+	settings := h.EnvSettings
+
 	err := createFileIfNotThere(settings.RepositoryConfig)
 	if err != nil {
 		logger.Log(logger.LevelError, nil, err, "creating empty RepositoryConfig file")
@@ -181,15 +189,18 @@ func addRepository(request AddUpdateRepoRequest, settings *cli.EnvSettings) erro
 
 	applyRequestFields(newRepo, request)
 
-	r, err := repo.NewChartRepository(newRepo, getter.All(settings))
+	credentials, err := h.requestCredentials(ctx, newRepo, request)
 	if err != nil {
-		logger.Log(logger.LevelError, nil, err, "creating chart repository")
+		logger.Log(logger.LevelError, map[string]string{"repository": request.Name}, err, "reading credentials")
 		return err
 	}
 
-	_, err = r.DownloadIndexFile()
-	if err != nil {
-		logger.Log(logger.LevelError, nil, err, "downloading index file")
+	if err = h.downloadIndexFile(newRepo, credentials); err != nil {
+		return err
+	}
+
+	if err = h.saveCredentials(ctx, newRepo, request, credentials); err != nil {
+		logger.Log(logger.LevelError, map[string]string{"repository": request.Name}, err, "saving credentials")
 		return err
 	}
 
@@ -204,6 +215,36 @@ func addRepository(request AddUpdateRepoRequest, settings *cli.EnvSettings) erro
 	return nil
 }
 
+// downloadIndexFile downloads the index of a repository to the repository
+// cache, with its credentials.
+func (h *Handler) downloadIndexFile(entry *repo.Entry, credentials RepositoryCredentials) error {
+	authEntry, cleanup, err := withCredentials(entry, credentials)
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "writing repository certificates")
+		return err
+	}
+
+	defer cleanup()
+
+	r, err := repo.NewChartRepository(authEntry, h.repositoryGetters(authEntry, credentials.BearerToken))
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "creating chart repository")
+		return err
+	}
+
+	if h.RepositoryCache != "" {
+		r.CachePath = h.RepositoryCache
+	}
+
+	_, err = r.DownloadIndexFile()
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "downloading index file")
+		return err
+	}
+
+	return nil
+}
+
 func (h *Handler) AddRepo(w http.ResponseWriter, r *http.Request) {
 	var request AddUpdateRepoRequest
 
@@ -222,12 +263,16 @@ func (h *Handler) AddRepo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = addRepository(request, h.EnvSettings)
+	err = h.addRepository(r.Context(), request)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if h.Refresher != nil {
+		h.Refresher.Forget(request.Name)
+	}
+
 	response := map[string]string{
 		"message": "success",
 	}
@@ -249,9 +294,21 @@ func (h *Handler) AddRepo(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// repositoryInfo describes a repository without its credentials, only which
+// kinds it has.
 type repositoryInfo struct {
-	Name string `json:"name"`
-	URL  string `json:"url"`
+	Name                  string `json:"name"`
+	URL                   string `json:"url"`
+	BasicAuth             bool   `json:"basicAuth"`
+	BearerToken           bool   `json:"bearerToken"`
+	ClientCert            bool   `json:"clientCert"`
+	CustomCA              bool   `json:"customCA"`
+	InsecureSkipTLSverify bool   `json:"insecureSkipTLSverify"`
+	PassCredentialsAll    bool   `json:"passCredentialsAll"`
+	// LastUpdated is when the index was last downloaded, if it was.
+	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
+	// LastError is the error of the last background refresh of the index, if it failed.
+	LastError string `json:"lastError,omitempty"`
 }
 
 type ListRepoResponse struct {
@@ -266,7 +323,9 @@ func createFullPath(p string) (*os.File, error) {
 	return os.Create(p) //nolint:gosec
 }
 
-func listRepositories(settings *cli.EnvSettings) ([]repositoryInfo, error) {
+func (h *Handler) listRepositories(ctx context.Context) ([]repositoryInfo, error) {
+	settings := h.EnvSettings
+
 	err := createFileIfNotThere(settings.RepositoryConfig)
 	if err != nil {
 		logger.Log(logger.LevelError, nil, err, "creating empty RepositoryConfig file")
@@ -281,18 +340,39 @@ func listRepositories(settings *cli.EnvSettings) ([]repositoryInfo, error) {
 
 	repositories := make([]repositoryInfo, 0, len(repoFile.Repositories))
 
-	for _, repo := range repoFile.Repositories {
-		repositories = append(repositories, repositoryInfo{
-			Name: repo.Name,
-			URL:  repo.URL,
-		})
+	for _, entry := range repoFile.Repositories {
+		credentials, err := h.storedCredentials(ctx, entry)
+		if err != nil {
+			logger.Log(logger.LevelError, map[string]string{"repository": entry.Name}, err, "reading credentials")
+			return nil, err
+		}
+
+		info := repositoryInfo{
+			Name:                  entry.Name,
+			URL:                   redactURL(entry.URL),
+			BasicAuth:             credentials.Username != "" || credentials.Password != "",
+			BearerToken:           credentials.BearerToken != "",
+			ClientCert:            credentials.CertData != "" || entry.CertFile != "",
+			CustomCA:              credentials.CAData != "" || entry.CAFile != "",
+			InsecureSkipTLSverify: entry.InsecureSkipTLSverify,
+			PassCredentialsAll:    entry.PassCredentialsAll,
+		}
+
+		status := h.refreshStatus(entry.Name)
+		if !status.LastUpdated.IsZero() {
+			info.LastUpdated = &status.LastUpdated
+		}
+
+		info.LastError = status.LastError
+
+		repositories = append(repositories, info)
 	}
 
 	return repositories, nil
 }
 
 func (h *Handler) ListRepo(w http.ResponseWriter, r *http.Request) {
-	repositories, err := listRepositories(h.EnvSettings)
+	repositories, err := h.listRepositories(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -319,6 +399,19 @@ func (h *Handler) ListRepo(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// redactURL removes the user info, which may contain a password, from a
+// repository URL. URLs that can't be parsed are redacted entirely.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	u.User = nil
+
+	return u.String()
+}
+
 func RemoveRepository(name string, settings *cli.EnvSettings) error {
 	err := createFileIfNotThere(settings.RepositoryConfig)
 	if err != nil {
@@ -382,6 +475,17 @@ func (h *Handler) RemoveRepo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.Refresher != nil {
+		h.Refresher.Forget(name)
+	}
+
+	if err = h.deleteCredentials(r.Context(), name); err != nil {
+		logger.Log(logger.LevelError, map[string]string{"repository": name}, err, "deleting credentials")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -399,13 +503,17 @@ func copyExistingRepoFields(updated, existing *repo.Entry) {
 // It returns errRepositoryNotFound if the named repository does not exist.
 // Callers that need create-or-update behaviour should use AddRepo instead.
 func UpdateRepository(name, url string, settings *cli.EnvSettings) error {
-	return updateRepositoryWithRequest(AddUpdateRepoRequest{
+	h := &Handler{EnvSettings: settings}
+
+	return h.updateRepositoryWithRequest(context.Background(), AddUpdateRepoRequest{
 		Name: name,
 		URL:  url,
-	}, settings)
+	})
 }
 
-func updateRepositoryWithRequest(request AddUpdateRepoRequest, settings *cli.EnvSettings) error {
+func (h *Handler) updateRepositoryWithRequest(ctx context.Context, request AddUpdateRepoRequest) error {
+	settings := h.EnvSettings
+
 	err := createFileIfNotThere(settings.RepositoryConfig)
 	if err != nil {
 		logger.Log(logger.LevelError, nil, err, "creating empty RepositoryConfig file")
@@ -454,6 +562,17 @@ func updateRepositoryWithRequest(request AddUpdateRepoRequest, settings *cli.Env
 	copyExistingRepoFields(updated, existing)
 
 	applyRequestFields(updated, request)
+
+	credentials, err := h.requestCredentials(ctx, updated, request)
+	if err == nil {
+		err = h.saveCredentials(ctx, updated, request, credentials)
+	}
+
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{"repository": request.Name}, err, "saving credentials")
+		return err
+	}
+
 	repoFile.Update(updated)
 
 	err = repoFile.WriteFile(settings.RepositoryConfig, defaultNewConfigFileMode)
@@ -483,7 +602,7 @@ func (h *Handler) UpdateRepository(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = updateRepositoryWithRequest(request, h.EnvSettings)
+	err = h.updateRepositoryWithRequest(r.Context(), request)
 	if err != nil {
 		if errors.Is(err, errRepositoryNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -0,0 +1,522 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+const (
+	// credentialsDirName is the directory next to repositories.yaml where the
+	// certificates and tokens of repositories are kept without a credential store.
+	credentialsDirName  = "repository-credentials"
+	certFileName        = "cert.pem"
+	keyFileName         = "key.pem"
+	caFileName          = "ca.pem"
+	bearerTokenFileName = "token"
+	credentialsDirMode  = 0o700
+	credentialsFileMode = 0o600
+	// repositoryRequestTimeout is the timeout of requests with a bearer token, the same as helm's.
+	repositoryRequestTimeout = 120 * time.Second
+)
+
+// RepositoryCredentials are the secrets used to access a chart repository.
+// The certificate, key and CA are PEM encoded.
+type RepositoryCredentials struct {
+	Username    string `json:"username,omitempty"`
+	Password    string `json:"password,omitempty"`
+	BearerToken string `json:"bearerToken,omitempty"`
+	CertData    string `json:"certData,omitempty"`
+	KeyData     string `json:"keyData,omitempty"`
+	CAData      string `json:"caData,omitempty"`
+}
+
+// RepositoryCredentialStore keeps repository credentials out of repositories.yaml.
+type RepositoryCredentialStore interface {
+	// GetCredentials returns the credentials of a repository, which are empty if it has none.
+	GetCredentials(ctx context.Context, repoName string) (RepositoryCredentials, error)
+	SetCredentials(ctx context.Context, repoName string, credentials RepositoryCredentials) error
+	// DeleteCredentials deletes the credentials of a repository, if it has any.
+	DeleteCredentials(ctx context.Context, repoName string) error
+	// ListCredentials returns the names of the repositories with credentials, sorted.
+	ListCredentials(ctx context.Context) ([]string, error)
+}
+
+func validateRepositoryName(name string) error {
+	// Like the helm CLI, names can't contain a / as charts are referenced as repo/name.
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("repository name %q is invalid", name)
+	}
+
+	return nil
+}
+
+// validateCertificates checks that the certificates of the request are valid
+// PEM, and that the client certificate and key are set together.
+func (r AddUpdateRepoRequest) validateCertificates() error {
+	certData, keyData := "", ""
+
+	if r.CertData != nil {
+		certData = *r.CertData
+	}
+
+	if r.KeyData != nil {
+		keyData = *r.KeyData
+	}
+
+	if (certData == "") != (keyData == "") {
+		return errors.New("certData and keyData must be set together")
+	}
+
+	if certData != "" {
+		if _, err := tls.X509KeyPair([]byte(certData), []byte(keyData)); err != nil {
+			return fmt.Errorf("invalid client certificate: %w", err)
+		}
+	}
+
+	if r.CAData != nil && *r.CAData != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(*r.CAData)) {
+		return errors.New("invalid caData: no PEM encoded certificates found")
+	}
+
+	return nil
+}
+
+// credentialsDir returns the directory of the certificates and token of a
+// repository when there's no credential store.
+func (h *Handler) credentialsDir(repoName string) (string, error) {
+	if err := validateRepositoryName(repoName); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(filepath.Dir(h.RepositoryConfig), credentialsDirName, repoName), nil
+}
+
+// storedCredentials returns the credentials of a repository that helm doesn't
+// read from repositories.yaml itself: all of them with a credential store, or
+// the bearer token otherwise. A username and password still in the entry,
+// e.g. added by the helm CLI, are used when none are stored.
+func (h *Handler) storedCredentials(ctx context.Context, entry *repo.Entry) (RepositoryCredentials, error) {
+	var credentials RepositoryCredentials
+
+	if h.CredentialStore != nil {
+		stored, err := h.CredentialStore.GetCredentials(ctx, entry.Name)
+		if err != nil {
+			return credentials, err
+		}
+
+		credentials = stored
+	} else {
+		dir, err := h.credentialsDir(entry.Name)
+		if err != nil {
+			return credentials, err
+		}
+
+		token, err := os.ReadFile(filepath.Join(dir, bearerTokenFileName))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return credentials, err
+		}
+
+		credentials.BearerToken = string(token)
+	}
+
+	if credentials.Username == "" && credentials.Password == "" {
+		credentials.Username, credentials.Password = entry.Username, entry.Password
+	}
+
+	return credentials, nil
+}
+
+// requestCredentials returns the stored credentials of a repository with
+// those set in the request applied.
+func (h *Handler) requestCredentials(
+	ctx context.Context,
+	entry *repo.Entry,
+	request AddUpdateRepoRequest,
+) (RepositoryCredentials, error) {
+	credentials, err := h.storedCredentials(ctx, entry)
+	if err != nil {
+		return credentials, err
+	}
+
+	for _, field := range []struct {
+		value  *string
+		target *string
+	}{
+		{request.Username, &credentials.Username},
+		{request.Password, &credentials.Password},
+		{request.BearerToken, &credentials.BearerToken},
+		{request.CertData, &credentials.CertData},
+		{request.KeyData, &credentials.KeyData},
+		{request.CAData, &credentials.CAData},
+	} {
+		if field.value != nil {
+			*field.target = *field.value
+		}
+	}
+
+	return credentials, nil
+}
+
+// saveCredentials stores the credentials of a repository. With a credential
+// store, they're removed from the entry. Otherwise they're kept like the helm
+// CLI does, with the username and password in the entry, and the certificates
+// of the request in files next to repositories.yaml that the entry refers to.
+// The bearer token, which the helm CLI doesn't support, is kept there too.
+func (h *Handler) saveCredentials(
+	ctx context.Context,
+	entry *repo.Entry,
+	request AddUpdateRepoRequest,
+	credentials RepositoryCredentials,
+) error {
+	files := []struct {
+		name string
+		data *string
+		path *string
+	}{
+		{certFileName, request.CertData, &entry.CertFile},
+		{keyFileName, request.KeyData, &entry.KeyFile},
+		{caFileName, request.CAData, &entry.CAFile},
+		{bearerTokenFileName, request.BearerToken, nil},
+	}
+
+	// Certificates in the request replace those the entry refers to.
+	for _, file := range files {
+		if file.data != nil && file.path != nil {
+			*file.path = ""
+		}
+	}
+
+	if h.CredentialStore != nil {
+		entry.Username, entry.Password = "", ""
+
+		if credentials == (RepositoryCredentials{}) {
+			return h.CredentialStore.DeleteCredentials(ctx, entry.Name)
+		}
+
+		return h.CredentialStore.SetCredentials(ctx, entry.Name, credentials)
+	}
+
+	entry.Username, entry.Password = credentials.Username, credentials.Password
+
+	dir, err := h.credentialsDir(entry.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.data == nil {
+			continue
+		}
+
+		path := filepath.Join(dir, file.name)
+
+		if *file.data == "" {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+
+			continue
+		}
+
+		if err := writeCredentialsFile(path, *file.data); err != nil {
+			return err
+		}
+
+		if file.path != nil {
+			*file.path = path
+		}
+	}
+
+	return nil
+}
+
+// deleteCredentials deletes the stored credentials of a removed repository.
+func (h *Handler) deleteCredentials(ctx context.Context, repoName string) error {
+	if h.CredentialStore != nil {
+		return h.CredentialStore.DeleteCredentials(ctx, repoName)
+	}
+
+	dir, err := h.credentialsDir(repoName)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(dir)
+}
+
+func writeCredentialsFile(path, data string) error {
+	if err := os.MkdirAll(filepath.Dir(path), credentialsDirMode); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, []byte(data), credentialsFileMode)
+}
+
+// withCredentials returns a copy of entry that helm can use to access the
+// repository with credentials. Certificates are written to a temporary
+// directory, which cleanup removes.
+func withCredentials(entry *repo.Entry, credentials RepositoryCredentials) (*repo.Entry, func(), error) {
+	authEntry := *entry
+	authEntry.Username, authEntry.Password = credentials.Username, credentials.Password
+
+	files := []struct {
+		name string
+		data string
+		path *string
+	}{
+		{certFileName, credentials.CertData, &authEntry.CertFile},
+		{keyFileName, credentials.KeyData, &authEntry.KeyFile},
+		{caFileName, credentials.CAData, &authEntry.CAFile},
+	}
+
+	if credentials.CertData == "" && credentials.KeyData == "" && credentials.CAData == "" {
+		return &authEntry, func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "headlamp-helm-repository-")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cleanup := func() {
+		_ = os.RemoveAll(dir)
+	}
+
+	for _, file := range files {
+		if file.data == "" {
+			continue
+		}
+
+		*file.path = filepath.Join(dir, file.name)
+
+		if err := os.WriteFile(*file.path, []byte(file.data), credentialsFileMode); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+
+	return &authEntry, cleanup, nil
+}
+
+// chartRepository returns the repository entry of a repo/name chart, or nil
+// if the chart isn't one, e.g. when it's an oci:// reference or a local path.
+func (h *Handler) chartRepository(chartName string) *repo.Entry {
+	repoName, _, ok := strings.Cut(chartName, "/")
+	if !ok || registry.IsOCI(chartName) {
+		return nil
+	}
+
+	if _, err := os.Stat(chartName); err == nil {
+		return nil
+	}
+
+	repoFile, err := repo.LoadFile(h.RepositoryConfig)
+	if err != nil {
+		return nil
+	}
+
+	return repoFile.Get(repoName)
+}
+
+// locateChart downloads a chart like ChartPathOptions.LocateChart, with the
+// credentials of its repository that aren't in repositories.yaml.
+func (h *Handler) locateChart(chartPathOptions action.ChartPathOptions, chartName string) (string, error) {
+	entry := h.chartRepository(chartName)
+	if entry == nil {
+		return chartPathOptions.LocateChart(chartName, h.EnvSettings)
+	}
+
+	credentials, err := h.storedCredentials(context.Background(), entry)
+	if err != nil {
+		return "", fmt.Errorf("reading credentials of repository %s: %w", entry.Name, err)
+	}
+
+	authEntry, cleanup, err := withCredentials(entry, credentials)
+	if err != nil {
+		return "", err
+	}
+
+	defer cleanup()
+
+	chartPathOptions.Username, chartPathOptions.Password = authEntry.Username, authEntry.Password
+	chartPathOptions.CertFile, chartPathOptions.KeyFile = authEntry.CertFile, authEntry.KeyFile
+	chartPathOptions.CaFile = authEntry.CAFile
+	chartPathOptions.InsecureSkipTLSverify = authEntry.InsecureSkipTLSverify
+	chartPathOptions.PassCredentialsAll = authEntry.PassCredentialsAll
+
+	if credentials.BearerToken == "" {
+		return chartPathOptions.LocateChart(chartName, h.EnvSettings)
+	}
+
+	chartDownloader := downloader.ChartDownloader{
+		Out:              io.Discard,
+		Keyring:          chartPathOptions.Keyring,
+		Getters:          h.repositoryGetters(authEntry, credentials.BearerToken),
+		RepositoryConfig: h.RepositoryConfig,
+		RepositoryCache:  h.RepositoryCache,
+	}
+
+	if chartPathOptions.Verify {
+		chartDownloader.Verify = downloader.VerifyAlways
+	}
+
+	if err := os.MkdirAll(h.RepositoryCache, defaultNewConfigFolderMode); err != nil {
+		return "", err
+	}
+
+	fileName, _, err := chartDownloader.DownloadTo(chartName, strings.TrimSpace(chartPathOptions.Version),
+		h.RepositoryCache)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Abs(fileName)
+}
+
+// repositoryGetters returns the getters used to access a repository, which
+// send its bearer token, if any.
+func (h *Handler) repositoryGetters(entry *repo.Entry, bearerToken string) getter.Providers {
+	providers := getter.All(h.EnvSettings)
+	if bearerToken == "" {
+		return providers
+	}
+
+	bearerProvider := getter.Provider{
+		Schemes: []string{"http", "https"},
+		New: func(options ...getter.Option) (getter.Getter, error) {
+			return &bearerGetter{entry: entry, token: bearerToken, options: options}, nil
+		},
+	}
+
+	// Providers are looked up in order, so this one takes precedence over helm's.
+	return append(getter.Providers{bearerProvider}, providers...)
+}
+
+// bearerGetter gets files from a repository with a bearer token, which helm's
+// HTTP getter doesn't support. Like basic auth credentials, the token is only
+// sent to the host of the repository, unless PassCredentialsAll is set.
+type bearerGetter struct {
+	entry   *repo.Entry
+	token   string
+	options []getter.Option
+}
+
+func (g *bearerGetter) Get(href string, options ...getter.Option) (*bytes.Buffer, error) {
+	repoURL, err := url.Parse(g.entry.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := url.Parse(href)
+	if err != nil {
+		return nil, err
+	}
+
+	if !g.entry.PassCredentialsAll && (repoURL.Scheme != target.Scheme || repoURL.Host != target.Host) {
+		httpGetter, err := getter.NewHTTPGetter(slices.Concat(g.options, options)...)
+		if err != nil {
+			return nil, err
+		}
+
+		return httpGetter.Get(href)
+	}
+
+	client, err := newRepositoryHTTPClient(g.entry)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, href, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+g.token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s : %s", href, resp.Status)
+	}
+
+	buf := &bytes.Buffer{}
+	_, err = io.Copy(buf, resp.Body)
+
+	return buf, err
+}
+
+// newRepositoryHTTPClient returns a client with the TLS settings of a repository entry.
+func newRepositoryHTTPClient(entry *repo.Entry) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: entry.InsecureSkipTLSverify, //nolint:gosec
+	}
+
+	if entry.CertFile != "" && entry.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(entry.CertFile, entry.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if entry.CAFile != "" {
+		caData, err := os.ReadFile(entry.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", entry.CAFile)
+		}
+	}
+
+	return &http.Client{
+		Timeout: repositoryRequestTimeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}, nil
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// maxRefreshBackoffIntervals caps the backoff of a repository whose index
+// fails to download, in refresh intervals.
+const maxRefreshBackoffIntervals = 16
+
+// RepositoryRefresher periodically downloads the indexes of the configured
+// repositories. A repository whose index fails to download is retried after
+// twice as many intervals as the last time, up to maxRefreshBackoffIntervals.
+type RepositoryRefresher struct {
+	handler  *Handler
+	interval time.Duration
+
+	mu     sync.Mutex
+	states map[string]*repositoryRefreshState
+}
+
+type repositoryRefreshState struct {
+	lastUpdated time.Time
+	lastError   string
+	failures    int
+	nextRefresh time.Time
+}
+
+// RepositoryRefreshStatus is the result of the last refresh of a repository index.
+type RepositoryRefreshStatus struct {
+	// LastUpdated is when the index was last downloaded successfully.
+	LastUpdated time.Time
+	// LastError is the error of the last refresh, if it failed.
+	LastError string
+}
+
+// NewRepositoryRefresher returns a refresher of the repositories configured in
+// the settings of handler, using its credential store.
+func NewRepositoryRefresher(handler *Handler, interval time.Duration) *RepositoryRefresher {
+	return &RepositoryRefresher{
+		handler:  handler,
+		interval: interval,
+		states:   map[string]*repositoryRefreshState{},
+	}
+}
+
+// Run refreshes the repositories right away and then every interval, until ctx is done.
+func (r *RepositoryRefresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.Refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh downloads the indexes of the repositories that are due for a refresh.
+func (r *RepositoryRefresher) Refresh(ctx context.Context) {
+	repoFile, err := repo.LoadFile(r.handler.RepositoryConfig)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Log(logger.LevelError, nil, err, "reading repo file to refresh repositories")
+		}
+
+		return
+	}
+
+	for _, entry := range repoFile.Repositories {
+		if ctx.Err() != nil {
+			return
+		}
+
+		now := time.Now()
+		if !r.due(entry.Name, now) {
+			continue
+		}
+
+		credentials, err := r.handler.storedCredentials(ctx, entry)
+		if err == nil {
+			err = r.handler.downloadIndexFile(entry, credentials)
+		}
+
+		if err != nil {
+			logger.Log(logger.LevelWarn, map[string]string{"repository": entry.Name}, err, "refreshing repository index")
+		}
+
+		r.record(entry.Name, now, err)
+	}
+}
+
+func (r *RepositoryRefresher) due(repoName string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.states[repoName]
+
+	return !ok || !now.Before(state.nextRefresh)
+}
+
+// record records the result of a refresh started at attempted.
+func (r *RepositoryRefresher) record(repoName string, attempted time.Time, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.states[repoName]
+	if !ok {
+		state = &repositoryRefreshState{}
+		r.states[repoName] = state
+	}
+
+	if err == nil {
+		state.lastUpdated = attempted
+		state.lastError = ""
+		state.failures = 0
+		state.nextRefresh = attempted.Add(r.interval)
+
+		return
+	}
+
+	state.lastError = err.Error()
+	state.failures++
+	state.nextRefresh = attempted.Add(r.backoff(state.failures))
+}
+
+// backoff returns how long to wait before refreshing a repository again after failures.
+func (r *RepositoryRefresher) backoff(failures int) time.Duration {
+	return time.Duration(min(1<<min(failures, 5), maxRefreshBackoffIntervals)) * r.interval
+}
+
+// Forget drops the refresh state of a repository, e.g. after it was added
+// again or removed.
+func (r *RepositoryRefresher) Forget(repoName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.states, repoName)
+}
+
+// Status returns the result of the last refresh of a repository, if it was refreshed.
+func (r *RepositoryRefresher) Status(repoName string) (RepositoryRefreshStatus, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.states[repoName]
+	if !ok {
+		return RepositoryRefreshStatus{}, false
+	}
+
+	return RepositoryRefreshStatus{LastUpdated: state.lastUpdated, LastError: state.lastError}, true
+}
+
+// refreshStatus returns when the index of a repository was last downloaded,
+// and the error of its last refresh. Without a successful refresh, the index
+// was last downloaded when its cached file was written.
+func (h *Handler) refreshStatus(repoName string) RepositoryRefreshStatus {
+	var status RepositoryRefreshStatus
+
+	if h.Refresher != nil {
+		status, _ = h.Refresher.Status(repoName)
+	}
+
+	if status.LastUpdated.IsZero() {
+		info, err := os.Stat(filepath.Join(h.RepositoryCache, helmpath.CacheIndexFile(repoName)))
+		if err == nil {
+			status.LastUpdated = info.ModTime()
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,74 @@
+package helm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func TestRepositoryRefresherBackoff(t *testing.T) {
+	r := NewRepositoryRefresher(&Handler{}, time.Minute)
+
+	assert.Equal(t, 2*time.Minute, r.backoff(1))
+	assert.Equal(t, 8*time.Minute, r.backoff(3))
+	assert.Equal(t, 16*time.Minute, r.backoff(4))
+	assert.Equal(t, 16*time.Minute, r.backoff(100))
+}
+
+func TestRepositoryRefresher(t *testing.T) {
+	server := newPrivateRepoServer(t, httptest.NewServer)
+	h := newTestHandler(t)
+
+	repoFile := repo.NewFile()
+	repoFile.Add(
+		&repo.Entry{Name: "private", URL: server.URL, Username: "testuser", Password: "testpass"},
+		&repo.Entry{Name: "broken", URL: server.URL + "/missing"},
+	)
+	require.NoError(t, repoFile.WriteFile(h.RepositoryConfig, 0o644))
+
+	h.Refresher = NewRepositoryRefresher(h, time.Hour)
+	h.Refresher.Refresh(context.Background())
+
+	status, ok := h.Refresher.Status("private")
+	require.True(t, ok)
+	assert.Empty(t, status.LastError)
+	assert.WithinDuration(t, time.Now(), status.LastUpdated, time.Minute)
+
+	status, ok = h.Refresher.Status("broken")
+	require.True(t, ok)
+	assert.Contains(t, status.LastError, "401 Unauthorized")
+	assert.True(t, status.LastUpdated.IsZero())
+	assert.False(t, h.Refresher.due("broken", time.Now().Add(time.Hour)), "broken repository should back off")
+	assert.True(t, h.Refresher.due("broken", time.Now().Add(2*time.Hour)))
+
+	response, _ := listTestRepositories(t, h)
+	require.Len(t, response.Repositories, 2)
+
+	for _, info := range response.Repositories {
+		if info.Name == "broken" {
+			assert.Nil(t, info.LastUpdated)
+			assert.NotEmpty(t, info.LastError)
+		} else {
+			assert.NotNil(t, info.LastUpdated)
+			assert.Empty(t, info.LastError)
+		}
+	}
+
+	rr := serveTestRequest(t, h.ListCharts, http.MethodGet, "/charts", nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var charts ListAllChartsResponse
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&charts))
+	require.Len(t, charts.Charts, 1)
+	assert.Equal(t, "private/mychart", charts.Charts[0].Name)
+	require.Len(t, charts.Warnings, 1)
+	assert.Equal(t, "broken", charts.Warnings[0].Repository)
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// secretCredentialStore keeps the credentials of all repositories in one
+// Secret, as JSON under the repository name.
+type secretCredentialStore struct {
+	secrets corev1client.SecretInterface
+	name    string
+}
+
+// NewSecretCredentialStore returns a store that keeps repository credentials
+// in the Secret with the given name and namespace, creating it when needed.
+func NewSecretCredentialStore(client kubernetes.Interface, namespace, name string) RepositoryCredentialStore {
+	return &secretCredentialStore{
+		secrets: client.CoreV1().Secrets(namespace),
+		name:    name,
+	}
+}
+
+func secretKey(repoName string) (string, error) {
+	if errs := validation.IsConfigMapKey(repoName); len(errs) > 0 {
+		return "", fmt.Errorf("repository name %q can't be stored in a secret: %s", repoName, strings.Join(errs, "; "))
+	}
+
+	return repoName, nil
+}
+
+func (s *secretCredentialStore) GetCredentials(ctx context.Context, repoName string) (RepositoryCredentials, error) {
+	var credentials RepositoryCredentials
+
+	key, err := secretKey(repoName)
+	if err != nil {
+		return credentials, err
+	}
+
+	secret, err := s.secrets.Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return credentials, nil
+	}
+
+	if err != nil {
+		return credentials, err
+	}
+
+	data, ok := secret.Data[key]
+	if !ok {
+		return credentials, nil
+	}
+
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return credentials, fmt.Errorf("decoding credentials of repository %s: %w", repoName, err)
+	}
+
+	return credentials, nil
+}
+
+func (s *secretCredentialStore) SetCredentials(
+	ctx context.Context,
+	repoName string,
+	credentials RepositoryCredentials,
+) error {
+	key, err := secretKey(repoName)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	return s.update(ctx, func(secret *corev1.Secret) bool {
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+
+		secret.Data[key] = data
+
+		return true
+	})
+}
+
+func (s *secretCredentialStore) DeleteCredentials(ctx context.Context, repoName string) error {
+	key, err := secretKey(repoName)
+	if err != nil {
+		return err
+	}
+
+	return s.update(ctx, func(secret *corev1.Secret) bool {
+		if _, ok := secret.Data[key]; !ok {
+			return false
+		}
+
+		delete(secret.Data, key)
+
+		return true
+	})
+}
+
+func (s *secretCredentialStore) ListCredentials(ctx context.Context) ([]string, error) {
+	secret, err := s.secrets.Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return []string{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(secret.Data))
+	for name := range secret.Data {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// update applies change to the Secret, creating it if it doesn't exist, and
+// retries on conflicts. change returns false if the Secret is unchanged.
+func (s *secretCredentialStore) update(ctx context.Context, change func(secret *corev1.Secret) bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := s.secrets.Get(ctx, s.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   s.name,
+					Labels: map[string]string{"app.kubernetes.io/managed-by": "headlamp"},
+				},
+				Type: corev1.SecretTypeOpaque,
+			}
+
+			if !change(secret) {
+				return nil
+			}
+
+			_, err = s.secrets.Create(ctx, secret, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				return apierrors.NewConflict(corev1.Resource("secrets"), s.name, err)
+			}
+
+			return err
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !change(secret) {
+			return nil
+		}
+
+		_, err = s.secrets.Update(ctx, secret, metav1.UpdateOptions{})
+
+		return err
+	})
+}
@@ -0,0 +1,366 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// valuesSchemaURL is the URL the values schema is compiled as, the same as
+// helm, so relative references resolve the same way.
+const valuesSchemaURL = "file:///values.schema.json"
+
+// ValuesError is a value that doesn't match the values schema of a chart.
+type ValuesError struct {
+	// Path is a JSON pointer to the value, e.g. /image/tag. Values of
+	// subcharts are under the subchart name, e.g. /redis/port.
+	Path string `json:"path"`
+	// Chart is the name of the chart or subchart whose schema the value doesn't match.
+	Chart   string `json:"chart"`
+	Message string `json:"message"`
+}
+
+type ValidateValuesRequest struct {
+	ChartFileRequest
+	// Values are the base64 encoded YAML values, as in install and upgrade requests.
+	Values string `json:"values"`
+}
+
+func (req *ValidateValuesRequest) Validate() error {
+	validate := validator.New()
+	return validate.Struct(req)
+}
+
+type ValidateValuesResponse struct {
+	Valid  bool          `json:"valid"`
+	Errors []ValuesError `json:"errors"`
+}
+
+// ValidateValues validates values against the values.schema.json of a chart
+// version and its subcharts, after merging them with the chart defaults like
+// helm does before installing.
+func (h *Handler) ValidateValues(w http.ResponseWriter, r *http.Request) {
+	var req ValidateValuesRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Log(logger.LevelError, nil, err, "parsing request for values validation")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		logger.Log(logger.LevelError, nil, err, "validating request for values validation")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	values, err := decodeValues(req.Values)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart}, err, "decoding values")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	loadedChart, err := h.loadChartVersion(req.ChartFileRequest)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart}, err, "loading chart")
+		http.Error(w, err.Error(), chartErrorStatus(err))
+
+		return
+	}
+
+	valuesErrors, err := validateValues(loadedChart, values)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart}, err, "validating values")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	writeValuesValidation(w, req.Chart, http.StatusOK, valuesErrors)
+}
+
+// checkRequestValues loads the chart of an install or upgrade request, and
+// checks its values against the chart's schema before any background work
+// starts. For upgrades, current is the deployed release whose values may be
+// reused. It writes the error response, 400 with the schema errors if the
+// values are invalid, and returns false if the check fails.
+func (h *Handler) checkRequestValues(
+	w http.ResponseWriter,
+	actionName string,
+	req *CommonInstallUpdateRequest,
+	chartPathOptions action.ChartPathOptions,
+	dependencyUpdate bool,
+	current *release.Release,
+) bool {
+	values, err := decodeValues(req.Values)
+	if err != nil {
+		handleError(w, req.Name, err, "decoding values", http.StatusBadRequest)
+		return false
+	}
+
+	if req.DryRun != "" {
+		actionName = actionDryRun
+	}
+
+	if err := h.loadRequestChart(actionName, req, chartPathOptions, dependencyUpdate); err != nil {
+		handleError(w, req.Name, err, "getting chart", chartErrorStatus(err))
+		return false
+	}
+
+	loadedChart := req.loadedChart
+
+	if current != nil {
+		values = upgradeValues(req.ActionOptions, current, values)
+	}
+
+	valuesErrors, err := validateValues(loadedChart, values)
+	if err != nil {
+		handleError(w, req.Name, err, "validating values", http.StatusBadRequest)
+		return false
+	}
+
+	if len(valuesErrors) > 0 {
+		logger.Log(logger.LevelError, map[string]string{logFieldChart: req.Chart, logFieldReleaseName: req.Name},
+			errors.New(valuesErrors[0].Message), "values don't match the chart schema")
+		writeValuesValidation(w, req.Name, http.StatusBadRequest, valuesErrors)
+
+		return false
+	}
+
+	return true
+}
+
+// upgradeValues returns the values an upgrade uses, like helm: the values of
+// the current release are reused when ReuseValues is set, or when the
+// request has no values and ResetValues isn't set.
+func upgradeValues(
+	options ActionOptions,
+	current *release.Release,
+	values map[string]interface{},
+) map[string]interface{} {
+	switch {
+	case options.ResetValues:
+		return values
+	case options.ReuseValues:
+		return chartutil.CoalesceTables(values, current.Config)
+	case len(values) == 0 && len(current.Config) > 0:
+		return current.Config
+	}
+
+	return values
+}
+
+// validateValues merges values with the chart defaults and returns the
+// values that don't match the schemas of the chart and its subcharts.
+func validateValues(c *chart.Chart, values map[string]interface{}) ([]ValuesError, error) {
+	coalesced, err := chartutil.CoalesceValues(c, values)
+	if err != nil {
+		return nil, err
+	}
+
+	valuesErrors := []ValuesError{}
+	if err := appendSchemaErrors(&valuesErrors, c, coalesced, ""); err != nil {
+		return nil, err
+	}
+
+	return valuesErrors, nil
+}
+
+// appendSchemaErrors validates the values at path against the schema of c,
+// and the values of its subcharts against theirs, like chartutil.ValidateAgainstSchema.
+func appendSchemaErrors(valuesErrors *[]ValuesError, c *chart.Chart, values map[string]interface{}, path string) error {
+	if len(c.Schema) > 0 {
+		if err := appendChartSchemaErrors(valuesErrors, c, values, path); err != nil {
+			return err
+		}
+	}
+
+	for _, subchart := range c.Dependencies() {
+		raw, ok := values[subchart.Name()]
+		if !ok || raw == nil {
+			continue
+		}
+
+		subchartPath := path + "/" + escapeJSONPointer(subchart.Name())
+
+		subchartValues, ok := raw.(map[string]interface{})
+		if !ok {
+			*valuesErrors = append(*valuesErrors, ValuesError{
+				Path:    subchartPath,
+				Chart:   c.Name(),
+				Message: fmt.Sprintf("invalid type for values: expected object, got %T", raw),
+			})
+
+			continue
+		}
+
+		if err := appendSchemaErrors(valuesErrors, subchart, subchartValues, subchartPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// appendChartSchemaErrors validates values against the schema of c only. If
+// the schema can't be compiled, the error is reported for the whole values.
+func appendChartSchemaErrors(
+	valuesErrors *[]ValuesError,
+	c *chart.Chart,
+	values map[string]interface{},
+	path string,
+) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("unable to validate schema: %s", r)
+		}
+	}()
+
+	schema, err := newValuesSchema(c.Schema)
+	if err != nil {
+		*valuesErrors = append(*valuesErrors, ValuesError{
+			Path:    path,
+			Chart:   c.Name(),
+			Message: "invalid values schema: " + strings.TrimSpace(err.Error()),
+		})
+
+		return nil
+	}
+
+	var validationErr *jsonschema.ValidationError
+
+	err = schema.Validate(values)
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+
+	printer := message.NewPrinter(language.English)
+
+	for _, cause := range leafValidationErrors(validationErr) {
+		*valuesErrors = append(*valuesErrors, ValuesError{
+			Path:    path + jsonPointer(cause.InstanceLocation),
+			Chart:   c.Name(),
+			Message: cause.ErrorKind.LocalizedString(printer),
+		})
+	}
+
+	return nil
+}
+
+// schemaRefLoader loads the https references of values schemas. It only
+// connects to public addresses, without a proxy, so that the schema of a chart
+// can't reach the internal network of the server.
+var schemaRefLoader = (*chartutil.HTTPURLLoader)(&http.Client{
+	Timeout: 15 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: 10 * time.Second, Control: dialPublicAddress}).DialContext,
+	},
+})
+
+// dialPublicAddress refuses connections to loopback, link-local, private and
+// other non-public addresses.
+func dialPublicAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return fmt.Errorf("values schema references can't be loaded from %s", host)
+	}
+
+	return nil
+}
+
+// newValuesSchema compiles a values schema. Unlike helm, references are only
+// loaded over https from public addresses, and never from files on the server.
+func newValuesSchema(schemaJSON []byte) (*jsonschema.Schema, error) {
+	schema, err := jsonschema.UnmarshalJSON(bytes.NewReader(schemaJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.UseLoader(jsonschema.SchemeURLLoader{"https": schemaRefLoader})
+
+	if err := compiler.AddResource(valuesSchemaURL, schema); err != nil {
+		return nil, err
+	}
+
+	return compiler.Compile(valuesSchemaURL)
+}
+
+// leafValidationErrors returns the errors without causes, which are the
+// actual schema violations rather than groups of them.
+func leafValidationErrors(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+
+	leaves := []*jsonschema.ValidationError{}
+	for _, cause := range err.Causes {
+		leaves = append(leaves, leafValidationErrors(cause)...)
+	}
+
+	return leaves
+}
+
+func jsonPointer(tokens []string) string {
+	var pointer strings.Builder
+	for _, token := range tokens {
+		pointer.WriteString("/" + escapeJSONPointer(token))
+	}
+
+	return pointer.String()
+}
+
+func escapeJSONPointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+func writeValuesValidation(w http.ResponseWriter, name string, status int, valuesErrors []ValuesError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	response := ValidateValuesResponse{Valid: len(valuesErrors) == 0, Errors: valuesErrors}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Log(logger.LevelError, map[string]string{logFieldReleaseName: name}, err, "encoding response")
+	}
+}
@@ -0,0 +1,246 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func newSchemaTestChart(t *testing.T) *chart.Chart {
+	t.Helper()
+
+	parent, err := loader.Load(writeTestChart(t))
+	require.NoError(t, err)
+
+	parent.Schema = []byte(`{
+		"type": "object",
+		"properties": {
+			"replicas": {"type": "integer", "minimum": 1},
+			"image": {"type": "string"}
+		}
+	}`)
+
+	redis := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "redis", Version: "1.0.0"},
+		Values:   map[string]interface{}{"port": 6379},
+		Raw:      []*chart.File{{Name: chartutil.ValuesfileName, Data: []byte("port: 6379\n")}},
+		Schema:   []byte(`{"type": "object", "properties": {"port": {"type": "integer"}}, "required": ["port"]}`),
+	}
+	parent.AddDependency(redis)
+
+	return parent
+}
+
+func TestValidateValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]interface{}
+		want    []ValuesError
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			values: map[string]interface{}{},
+			want:   []ValuesError{},
+		},
+		{
+			name: "invalid values",
+			values: map[string]interface{}{
+				"replicas": 0,
+				"image":    map[string]interface{}{"tag": "latest"},
+				"redis":    map[string]interface{}{"port": "6379"},
+			},
+			want: []ValuesError{
+				{Path: "/image", Chart: "mychart", Message: "got object, want string"},
+				{Path: "/replicas", Chart: "mychart", Message: "minimum: got 0, want 1"},
+				{Path: "/redis/port", Chart: "redis", Message: "got string, want integer"},
+			},
+		},
+		{
+			name:    "subchart values not an object",
+			values:  map[string]interface{}{"redis": "none"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valuesErrors, err := validateValues(newSchemaTestChart(t), tt.values)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, valuesErrors)
+		})
+	}
+}
+
+func TestValidateValuesSchemaReferences(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "secret.json")
+	require.NoError(t, os.WriteFile(secret, []byte(`{"type": "string"}`), 0o600))
+
+	requested := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		_, _ = w.Write([]byte(`{"type": "string"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	refs := []string{"file://" + secret, "secret.json", strings.Replace(server.URL, "https", "http", 1), server.URL}
+
+	for _, ref := range refs {
+		t.Run(ref, func(t *testing.T) {
+			c, err := loader.Load(writeTestChart(t))
+			require.NoError(t, err)
+
+			c.Schema = []byte(`{"type": "object", "properties": {"image": {"$ref": "` + ref + `"}}}`)
+
+			valuesErrors, err := validateValues(c, map[string]interface{}{})
+			require.NoError(t, err)
+			require.Len(t, valuesErrors, 1)
+			assert.Contains(t, valuesErrors[0].Message, "invalid values schema")
+		})
+	}
+
+	assert.False(t, requested, "a schema reference reached a local server")
+}
+
+func TestUpgradeValues(t *testing.T) {
+	current := &release.Release{Config: map[string]interface{}{"replicas": 3, "image": "nginx"}}
+
+	assert.Equal(t, current.Config, upgradeValues(ActionOptions{}, current, map[string]interface{}{}))
+	assert.Equal(t, map[string]interface{}{"replicas": 5},
+		upgradeValues(ActionOptions{}, current, map[string]interface{}{"replicas": 5}))
+	assert.Equal(t, map[string]interface{}{"replicas": 5, "image": "nginx"},
+		upgradeValues(ActionOptions{ReuseValues: true}, current, map[string]interface{}{"replicas": 5}))
+	assert.Equal(t, map[string]interface{}{},
+		upgradeValues(ActionOptions{ResetValues: true}, current, map[string]interface{}{}))
+}
+
+func TestValidateValuesEndpoint(t *testing.T) {
+	h := newChartRepoTestHandler(t)
+
+	validate := func(values string) ValidateValuesResponse {
+		t.Helper()
+
+		body, err := json.Marshal(ValidateValuesRequest{
+			ChartFileRequest: ChartFileRequest{Chart: "local/mychart", Version: "0.2.0"},
+			Values:           base64.StdEncoding.EncodeToString([]byte(values)),
+		})
+		require.NoError(t, err)
+
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodPost,
+			"/charts/values/validate", bytes.NewReader(body))
+		rr := httptest.NewRecorder()
+
+		h.ValidateValues(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var response ValidateValuesResponse
+
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+		return response
+	}
+
+	assert.Equal(t, ValidateValuesResponse{Valid: true, Errors: []ValuesError{}}, validate("replicas: 2\n"))
+	assert.Equal(t, ValidateValuesResponse{
+		Errors: []ValuesError{{Path: "/replicas", Chart: "mychart", Message: "got string, want integer"}},
+	}, validate("replicas: two\n"))
+}
+
+func TestInstallRejectsInvalidValues(t *testing.T) {
+	schemaChart := newSchemaTestChart(t)
+
+	archive, err := chartutil.Save(schemaChart, t.TempDir())
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(archive)
+	require.NoError(t, err)
+
+	req := newLocalChartInstallRequest()
+	req.DryRun = ""
+	req.Values = base64.StdEncoding.EncodeToString([]byte("replicas: two\n"))
+
+	h := newTestHandler(t)
+	rr := httptest.NewRecorder()
+
+	h.InstallLocalChart(newTestClientConfig(t), rr, newUploadRequest(t, req, "mychart-0.1.0.tgz", data))
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+
+	var response ValidateValuesResponse
+
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Equal(t, []ValuesError{{Path: "/replicas", Chart: "mychart", Message: "got string, want integer"}},
+		response.Errors)
+
+	_, err = h.getReleaseStatus("install", req.Name)
+	assert.Error(t, err, "no install should have started")
+}
+
+func TestRejectedInstallKeepsRunningActionStatus(t *testing.T) {
+	h := newTestHandler(t)
+	require.NoError(t, h.setReleaseProcessing("install", "demo", ActionOptions{}, nil))
+
+	req := newLocalChartInstallRequest()
+	req.DryRun = ""
+	req.Chart = "/nonexistent/mychart"
+	req.Version = "0.1.0"
+
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	h.InstallRelease(newTestClientConfig(t), rr, httptest.NewRequestWithContext(context.Background(),
+		http.MethodPost, "/clusters/test/helm/release/install", bytes.NewReader(body)))
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+
+	status, err := h.getReleaseStatus("install", "demo")
+	require.NoError(t, err)
+	assert.Equal(t, processing, status.Status)
+}
+
+func TestUpgradeReturnsDeployedReleaseError(t *testing.T) {
+	clientConfig := newTestClientConfigFor(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveSelfSubjectReview(w, r) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"Forbidden",` +
+			`"code":403,"message":"secrets is forbidden"}`))
+	}))
+
+	req := newLocalChartInstallRequest()
+	req.DryRun = ""
+	req.Chart = writeTestChart(t)
+	req.Version = "0.1.0"
+
+	body, err := json.Marshal(UpgradeReleaseRequest{CommonInstallUpdateRequest: req.CommonInstallUpdateRequest})
+	require.NoError(t, err)
+
+	h := newTestHandler(t)
+	rr := httptest.NewRecorder()
+
+	h.UpgradeRelease(clientConfig, rr, httptest.NewRequestWithContext(context.Background(),
+		http.MethodPut, "/clusters/test/helm/releases/upgrade", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusForbidden, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), "secrets is forbidden")
+}
@@ -0,0 +1,502 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+)
+
+const (
+	definitionsFileMode = 0o600
+	definitionsDirMode  = 0o700
+)
+
+var errDefinitionsDisabled = errors.New("port forward persistence is disabled")
+
+// Definition is what's needed to start a port forward again, as it's saved
+// across restarts and exported.
+type Definition struct {
+	ID               string `json:"id"`
+	Cluster          string `json:"cluster"`
+	Namespace        string `json:"namespace"`
+	Pod              string `json:"pod,omitempty"`
+	Service          string `json:"service,omitempty"`
+	ServiceNamespace string `json:"serviceNamespace,omitempty"`
+	WorkloadKind     string `json:"workloadKind,omitempty"`
+	WorkloadName     string `json:"workloadName,omitempty"`
+	Selector         string `json:"selector,omitempty"`
+	Port             string `json:"port,omitempty"`
+	TargetPort       string `json:"targetPort"`
+	Label            string `json:"label,omitempty"`
+	// AutoStart starts the port forward when the backend starts. Otherwise
+	// it's restored stopped.
+	AutoStart bool `json:"autoStart,omitempty"`
+	// IdleTimeout stops the port forward once it's idle for that long.
+	IdleTimeout string `json:"idleTimeout,omitempty"`
+}
+
+func (d Definition) Validate() error {
+	if d.Cluster == "" {
+		return errors.New("cluster is required")
+	}
+
+	req := d.request()
+
+	return req.Validate()
+}
+
+// request returns the request that starts the port forward.
+func (d Definition) request() portForwardRequest {
+	return portForwardRequest{
+		ID:               d.ID,
+		Namespace:        d.Namespace,
+		Pod:              d.Pod,
+		Service:          d.Service,
+		ServiceNamespace: d.ServiceNamespace,
+		WorkloadKind:     d.WorkloadKind,
+		WorkloadName:     d.WorkloadName,
+		Selector:         d.Selector,
+		TargetPort:       d.TargetPort,
+		Port:             d.Port,
+		Label:            d.Label,
+		AutoStart:        &d.AutoStart,
+		IdleTimeout:      d.IdleTimeout,
+	}
+}
+
+// definitionOf returns the definition of a port forward request on a cluster.
+func definitionOf(p portForwardRequest, cluster string) Definition {
+	return Definition{
+		ID:               p.ID,
+		Cluster:          cluster,
+		Namespace:        p.Namespace,
+		Pod:              p.Pod,
+		Service:          p.Service,
+		ServiceNamespace: p.ServiceNamespace,
+		WorkloadKind:     p.WorkloadKind,
+		WorkloadName:     p.WorkloadName,
+		Selector:         p.Selector,
+		Port:             p.Port,
+		TargetPort:       p.TargetPort,
+		Label:            p.Label,
+		AutoStart:        p.AutoStart != nil && *p.AutoStart,
+		IdleTimeout:      p.IdleTimeout,
+	}
+}
+
+// DefinitionStore keeps the definitions of the port forwards in a JSON file.
+// A nil store keeps nothing.
+type DefinitionStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewDefinitionStore returns a store of port forward definitions kept in the file at path.
+func NewDefinitionStore(path string) *DefinitionStore {
+	return &DefinitionStore{path: path}
+}
+
+// List returns the saved definitions.
+func (s *DefinitionStore) List() ([]Definition, error) {
+	if s == nil {
+		return []Definition{}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read()
+}
+
+// get returns the saved definition of a port forward, and whether it's saved.
+func (s *DefinitionStore) get(cluster, id string) (Definition, bool, error) {
+	saved, err := s.List()
+	if err != nil {
+		return Definition{}, false, err
+	}
+
+	i := slices.IndexFunc(saved, func(d Definition) bool { return d.Cluster == cluster && d.ID == id })
+	if i < 0 {
+		return Definition{}, false, nil
+	}
+
+	return saved[i], true, nil
+}
+
+// put saves definitions, replacing those with the same cluster and ID.
+func (s *DefinitionStore) put(definitions ...Definition) error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	for _, definition := range definitions {
+		i := slices.IndexFunc(saved, func(d Definition) bool {
+			return d.Cluster == definition.Cluster && d.ID == definition.ID
+		})
+		if i >= 0 {
+			saved[i] = definition
+		} else {
+			saved = append(saved, definition)
+		}
+	}
+
+	return s.write(saved)
+}
+
+// remove deletes the definition of a port forward, if it's saved.
+func (s *DefinitionStore) remove(cluster, id string) error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	kept := slices.DeleteFunc(saved, func(d Definition) bool {
+		return d.Cluster == cluster && d.ID == id
+	})
+
+	return s.write(kept)
+}
+
+func (s *DefinitionStore) read() ([]Definition, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Definition{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := []Definition{}
+	if err := json.Unmarshal(data, &definitions); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", s.path, err)
+	}
+
+	return definitions, nil
+}
+
+// write replaces the file with the definitions, through a temporary file so
+// that a crash doesn't leave it half written.
+func (s *DefinitionStore) write(definitions []Definition) error {
+	data, err := json.MarshalIndent(definitions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), definitionsDirMode); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+
+	_, err = tmp.Write(data)
+	if err = errors.Join(err, tmp.Chmod(definitionsFileMode), tmp.Close()); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// saveDefinition saves the definition of a port forward that started, unless
+// it's on a cluster only known to the request, e.g. a stateless cluster,
+// which can't be restored.
+func saveDefinition(definitions *DefinitionStore, p portForwardRequest, contextKey, requestClusterName string) {
+	if contextKey != requestClusterName {
+		return
+	}
+
+	if err := definitions.put(definitionOf(p, requestClusterName)); err != nil {
+		logger.Log(logger.LevelError, map[string]string{"cluster": contextKey, "id": p.ID},
+			err, "saving portforward definition")
+	}
+}
+
+// mergeSavedDefinition fills the label and the auto-start that a request to
+// start a saved port forward leaves out, e.g. to resume it, from its saved
+// definition, so that saving the request again doesn't reset them.
+func mergeSavedDefinition(definitions *DefinitionStore, p *portForwardRequest, cluster string) {
+	saved, ok, err := definitions.get(cluster, p.ID)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{"cluster": cluster, "id": p.ID},
+			err, "reading portforward definition")
+	}
+
+	if !ok {
+		return
+	}
+
+	if p.Label == "" {
+		p.Label = saved.Label
+	}
+
+	if p.AutoStart == nil {
+		p.AutoStart = &saved.AutoStart
+	}
+}
+
+// RestorePortForwards adds the saved port forwards to the cache, stopped so
+// that they can be resumed, and starts those with AutoStart in the background.
+func RestorePortForwards(
+	kubeConfigStore kubeconfig.ContextStore,
+	cache cache.Cache[interface{}],
+	definitions *DefinitionStore,
+	metrics *telemetry.Metrics,
+) error {
+	saved, err := definitions.List()
+	if err != nil {
+		return err
+	}
+
+	for _, definition := range saved {
+		restoreDefinition(kubeConfigStore, cache, metrics, definition, "")
+	}
+
+	return nil
+}
+
+// restoreDefinition adds a port forward to the cache, stopped, and starts it
+// in the background with token if it has AutoStart.
+func restoreDefinition(
+	kubeConfigStore kubeconfig.ContextStore,
+	cache cache.Cache[interface{}],
+	metrics *telemetry.Metrics,
+	definition Definition,
+	token string,
+) {
+	req := definition.request()
+	pfDetails := newPortForward(&req, definition.Cluster, definition.Cluster)
+	pfDetails.Status = STOPPED
+
+	portforwardstore(cache, *pfDetails)
+
+	if !definition.AutoStart {
+		return
+	}
+
+	done, ok := claimPortForwardStart(definition.Cluster, definition.ID)
+	if !ok {
+		logger.Log(logger.LevelWarn, map[string]string{"cluster": definition.Cluster, "id": definition.ID},
+			nil, "restored portforward is already starting")
+
+		return
+	}
+
+	go func() {
+		defer done()
+
+		err := startDefinition(kubeConfigStore, cache, metrics, req, token, definition.Cluster)
+		if err == nil {
+			return
+		}
+
+		logger.Log(logger.LevelError, map[string]string{"cluster": definition.Cluster, "id": definition.ID},
+			err, "starting restored portforward")
+
+		if stored, getErr := getPortForwardByID(cache, definition.Cluster, definition.ID); getErr == nil &&
+			stored.Status == STOPPED && stored.Error == "" {
+			stored.Error = err.Error()
+			portforwardstore(cache, stored)
+		}
+	}()
+}
+
+// startDefinition starts a saved port forward with token, or with the
+// credentials of the kubeconfig of its cluster if token is empty.
+func startDefinition(
+	kubeConfigStore kubeconfig.ContextStore,
+	cache cache.Cache[interface{}],
+	metrics *telemetry.Metrics,
+	req portForwardRequest,
+	token string,
+	cluster string,
+) error {
+	kContext, err := kubeConfigStore.GetContext(cluster)
+	if err != nil {
+		return err
+	}
+
+	return startPortForward(kContext, cache, metrics, &req, token, cluster, cluster)
+}
+
+// ExportPortForwards handles a request for the saved port forward definitions,
+// of the cluster query parameter if it's set.
+func ExportPortForwards(definitions *DefinitionStore, w http.ResponseWriter, r *http.Request) {
+	if definitions == nil {
+		http.Error(w, errDefinitionsDisabled.Error(), http.StatusNotFound)
+		return
+	}
+
+	saved, err := definitions.List()
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "listing portforward definitions")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if cluster := r.URL.Query().Get("cluster"); cluster != "" {
+		saved = slices.DeleteFunc(saved, func(d Definition) bool { return d.Cluster != cluster })
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(saved); err != nil {
+		logger.Log(logger.LevelError, nil, err, "writing json payload to response")
+		http.Error(w, "failed to write json payload "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ImportPortForwards handles a request to import port forward definitions, a
+// JSON list like ExportPortForwards returns. They're saved and restored like
+// at startup, replacing the stopped port forwards with the same IDs, but
+// started with the token of the request for their cluster.
+func ImportPortForwards(
+	kubeConfigStore kubeconfig.ContextStore,
+	cache cache.Cache[interface{}],
+	definitions *DefinitionStore,
+	metrics *telemetry.Metrics,
+	w http.ResponseWriter, r *http.Request,
+) {
+	if definitions == nil {
+		http.Error(w, errDefinitionsDisabled.Error(), http.StatusNotFound)
+		return
+	}
+
+	var imported []Definition
+
+	if err := json.NewDecoder(r.Body).Decode(&imported); err != nil {
+		logger.Log(logger.LevelError, nil, err, "decoding portforward definitions")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	tokens := make([]string, len(imported))
+
+	for i := range imported {
+		if imported[i].ID == "" {
+			imported[i].ID = uuid.New().String()
+		}
+
+		status, err := checkImportedDefinition(kubeConfigStore, cache, imported[i])
+		if err == nil {
+			tokens[i], err = auth.GetTokenFromCookie(r, imported[i].Cluster)
+			status = http.StatusBadRequest
+		}
+
+		if err != nil {
+			logger.Log(logger.LevelError, map[string]string{"id": imported[i].ID}, err, "validating portforward definition")
+			http.Error(w, fmt.Sprintf("invalid port forward %d: %s", i, err), status)
+
+			return
+		}
+	}
+
+	// Like StartPortForward, give a free local port to the definitions without one.
+	for i := range imported {
+		if imported[i].Port != "" {
+			continue
+		}
+
+		freePort, err := getFreePort()
+		if err != nil {
+			logger.Log(logger.LevelError, map[string]string{"id": imported[i].ID}, err, "getting free port")
+			http.Error(w, "can't find any available port "+err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		imported[i].Port = strconv.Itoa(freePort)
+	}
+
+	if err := definitions.put(imported...); err != nil {
+		logger.Log(logger.LevelError, nil, err, "saving portforward definitions")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	for i, definition := range imported {
+		restoreDefinition(kubeConfigStore, cache, metrics, definition, tokens[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(imported); err != nil {
+		logger.Log(logger.LevelError, nil, err, "writing json payload to response")
+		http.Error(w, "failed to write json payload "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// checkImportedDefinition returns an error, and the response status, if a
+// definition is invalid, is for an unknown cluster or replaces a running port forward.
+func checkImportedDefinition(
+	kubeConfigStore kubeconfig.ContextStore,
+	cache cache.Cache[interface{}],
+	definition Definition,
+) (int, error) {
+	if err := definition.Validate(); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	if _, err := kubeConfigStore.GetContext(definition.Cluster); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("cluster %s not found", definition.Cluster)
+	}
+
+	if existing, err := getPortForwardByID(cache, definition.Cluster, definition.ID); err == nil &&
+		existing.isActive() {
+		return http.StatusConflict, errors.New("portforward with this ID is already running")
+	}
+
+	return http.StatusOK, nil
+}
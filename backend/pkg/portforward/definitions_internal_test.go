@@ -0,0 +1,309 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func newTestDefinition(id string) Definition {
+	return Definition{
+		ID:         id,
+		Cluster:    "cluster",
+		Namespace:  "default",
+		Pod:        "web-0",
+		Port:       "8080",
+		TargetPort: "80",
+		Label:      "web " + id,
+	}
+}
+
+func TestDefinitionStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "port-forwards.json")
+	store := NewDefinitionStore(path)
+
+	saved, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, saved)
+
+	require.NoError(t, store.put(newTestDefinition("a"), newTestDefinition("b")))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(definitionsFileMode), info.Mode().Perm())
+
+	updated := newTestDefinition("a")
+	updated.AutoStart = true
+	require.NoError(t, store.put(updated))
+	require.NoError(t, store.remove("cluster", "b"))
+	require.NoError(t, store.remove("cluster", "missing"))
+
+	saved, err = NewDefinitionStore(path).List()
+	require.NoError(t, err)
+	assert.Equal(t, []Definition{updated}, saved)
+
+	var nilStore *DefinitionStore
+
+	require.NoError(t, nilStore.put(updated), "a nil store keeps nothing")
+}
+
+func TestRestorePortForwards(t *testing.T) {
+	c := cache.New[interface{}]()
+	store := NewDefinitionStore(filepath.Join(t.TempDir(), "port-forwards.json"))
+	require.NoError(t, store.put(newTestDefinition("a")))
+
+	require.NoError(t, RestorePortForwards(kubeconfig.NewContextStore(), c, store, nil))
+
+	restored, err := getPortForwardByID(c, "cluster", "a")
+	require.NoError(t, err)
+	assert.Equal(t, STOPPED, restored.Status)
+	assert.Equal(t, "8080", restored.Port)
+	assert.Equal(t, "web a", restored.Label)
+}
+
+func TestStopOrDeletePortForwardRemovesDefinition(t *testing.T) {
+	c := cache.New[interface{}]()
+	store := NewDefinitionStore(filepath.Join(t.TempDir(), "port-forwards.json"))
+	require.NoError(t, store.put(newTestDefinition("a"), newTestDefinition("b")))
+	require.NoError(t, RestorePortForwards(kubeconfig.NewContextStore(), c, store, nil))
+
+	for _, request := range []map[string]interface{}{
+		{"id": "a", "stopOrDelete": true},
+		{"id": "b", "stopOrDelete": false},
+	} {
+		payload, err := json.Marshal(request)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequestWithContext(context.Background(), http.MethodDelete, "/portforward",
+			bytes.NewReader(payload))
+		r = mux.SetURLVars(r, map[string]string{"clusterName": "cluster"})
+
+		StopOrDeletePortForward(c, store, "cluster", w, r)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	saved, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, saved, 1, "stopped port forwards are kept, deleted ones aren't")
+	assert.Equal(t, "a", saved[0].ID)
+}
+
+func TestImportExportPortForwards(t *testing.T) {
+	c := cache.New[interface{}]()
+	store := NewDefinitionStore(filepath.Join(t.TempDir(), "port-forwards.json"))
+
+	kubeConfigStore := kubeconfig.NewContextStore()
+	require.NoError(t, kubeConfigStore.AddContext(&kubeconfig.Context{
+		Name:        "cluster",
+		KubeContext: &api.Context{Cluster: "cluster"},
+		Cluster:     &api.Cluster{Server: "https://127.0.0.1:6443"},
+	}))
+
+	importDefinitions := func(definitions ...Definition) *httptest.ResponseRecorder {
+		body, err := json.Marshal(definitions)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/portforwards/import",
+			bytes.NewReader(body))
+		ImportPortForwards(kubeConfigStore, c, store, nil, w, r)
+
+		return w
+	}
+
+	withoutID := newTestDefinition("")
+	withoutID.Port = ""
+	w := importDefinitions(newTestDefinition("a"), withoutID)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var imported []Definition
+
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&imported))
+	require.Len(t, imported, 2)
+	assert.NotEmpty(t, imported[1].ID, "an ID is given to definitions without one")
+	assert.Equal(t, "8080", imported[0].Port)
+	assert.NotEmpty(t, imported[1].Port, "a free port is given to definitions without one")
+
+	restored, err := getPortForwardByID(c, "cluster", "a")
+	require.NoError(t, err)
+	assert.Equal(t, STOPPED, restored.Status)
+
+	unknownCluster := newTestDefinition("c")
+	unknownCluster.Cluster = "missing"
+	assert.Equal(t, http.StatusBadRequest, importDefinitions(unknownCluster).Code)
+
+	invalid := newTestDefinition("d")
+	invalid.TargetPort = ""
+	assert.Equal(t, http.StatusBadRequest, importDefinitions(invalid).Code)
+
+	restored.Status = RUNNING
+	portforwardstore(c, restored)
+	assert.Equal(t, http.StatusConflict, importDefinitions(newTestDefinition("a")).Code)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/portforwards/export?cluster=cluster", nil)
+	ExportPortForwards(store, w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var exported []Definition
+
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&exported))
+	assert.Equal(t, imported, exported)
+
+	w = httptest.NewRecorder()
+	ExportPortForwards(nil, w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestImportPortForwardsStartsWithRequestToken(t *testing.T) {
+	authorizations := make(chan string, 10)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case authorizations <- r.Header.Get("Authorization"):
+		default:
+		}
+
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	t.Cleanup(server.Close)
+
+	kubeConfigStore := kubeconfig.NewContextStore()
+	require.NoError(t, kubeConfigStore.AddContext(&kubeconfig.Context{
+		Name:        "cluster",
+		KubeContext: &api.Context{Cluster: "cluster"},
+		Cluster:     &api.Cluster{Server: server.URL},
+	}))
+
+	autoStart := newTestDefinition("a")
+	autoStart.AutoStart = true
+
+	body, err := json.Marshal([]Definition{autoStart})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/portforwards/import",
+		bytes.NewReader(body))
+	r.AddCookie(&http.Cookie{Name: "headlamp-auth-cluster.0", Value: "user-token"})
+
+	store := NewDefinitionStore(filepath.Join(t.TempDir(), "port-forwards.json"))
+	ImportPortForwards(kubeConfigStore, cache.New[interface{}](), store, nil, w, r)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	select {
+	case authorization := <-authorizations:
+		assert.Equal(t, "Bearer user-token", authorization)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the imported port forward wasn't started")
+	}
+}
+
+func TestRestoreDefinitionClaimsStart(t *testing.T) {
+	requested := make(chan struct{})
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case requested <- struct{}{}:
+			<-release
+		default:
+		}
+
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	t.Cleanup(server.Close)
+
+	kubeConfigStore := kubeconfig.NewContextStore()
+	require.NoError(t, kubeConfigStore.AddContext(&kubeconfig.Context{
+		Name:        "cluster",
+		KubeContext: &api.Context{Cluster: "cluster"},
+		Cluster:     &api.Cluster{Server: server.URL},
+	}))
+
+	autoStart := newTestDefinition("claimed")
+	autoStart.AutoStart = true
+
+	c := cache.New[interface{}]()
+	restoreDefinition(kubeConfigStore, c, nil, autoStart, "")
+
+	select {
+	case <-requested:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the restored port forward wasn't started")
+	}
+
+	_, ok := claimPortForwardStart("cluster", "claimed")
+	assert.False(t, ok, "the port forward is starting")
+
+	// Restoring it again meanwhile doesn't start it twice.
+	restoreDefinition(kubeConfigStore, c, nil, autoStart, "")
+	close(release)
+
+	require.Eventually(t, func() bool {
+		done, ok := claimPortForwardStart("cluster", "claimed")
+		if ok {
+			done()
+		}
+
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestMergeSavedDefinition(t *testing.T) {
+	store := NewDefinitionStore(filepath.Join(t.TempDir(), "port-forwards.json"))
+
+	saved := newTestDefinition("a")
+	saved.AutoStart = true
+	require.NoError(t, store.put(saved))
+
+	resumed := newTestDefinition("a").request()
+	resumed.Label = ""
+	resumed.AutoStart = nil
+	mergeSavedDefinition(store, &resumed, "cluster")
+	assert.Equal(t, "web a", resumed.Label)
+	require.NotNil(t, resumed.AutoStart)
+	assert.True(t, *resumed.AutoStart, "resuming keeps the saved auto-start")
+
+	disabled := false
+	changed := newTestDefinition("a").request()
+	changed.Label = "renamed"
+	changed.AutoStart = &disabled
+	mergeSavedDefinition(store, &changed, "cluster")
+	assert.Equal(t, "renamed", changed.Label)
+	assert.False(t, *changed.AutoStart)
+
+	unsaved := newTestDefinition("b").request()
+	unsaved.AutoStart = nil
+	mergeSavedDefinition(store, &unsaved, "cluster")
+	assert.Nil(t, unsaved.AutoStart)
+}
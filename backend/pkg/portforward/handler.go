@@ -37,6 +37,7 @@ import (
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
 	authv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -51,6 +52,9 @@ import (
 const (
 	RUNNING = "Running"
 	STOPPED = "Stopped"
+	// RECONNECTING is the status of a port forward that lost its pod and is
+	// connecting to a pod again.
+	RECONNECTING = "Reconnecting"
 )
 
 const (
@@ -66,8 +70,23 @@ type portForwardRequest struct {
 	Pod              string `json:"pod"`
 	Service          string `json:"service"`
 	ServiceNamespace string `json:"serviceNamespace"`
-	TargetPort       string `json:"targetPort"`
-	Port             string `json:"port"`
+	// WorkloadKind and WorkloadName are a Deployment, StatefulSet, DaemonSet or
+	// ReplicaSet to forward to, and Selector a label selector of the pods to
+	// forward to, instead of a pod or a service.
+	WorkloadKind string `json:"workloadKind,omitempty"`
+	WorkloadName string `json:"workloadName,omitempty"`
+	Selector     string `json:"selector,omitempty"`
+	TargetPort   string `json:"targetPort"`
+	Port         string `json:"port"`
+	// Label is a name the user gives the port forward. If it's empty, the
+	// label of the saved port forward with the same ID is kept.
+	Label string `json:"label"`
+	// AutoStart starts the saved port forward when the backend starts. If
+	// it's not set, the saved port forward with the same ID keeps its own.
+	AutoStart *bool `json:"autoStart,omitempty"`
+	// IdleTimeout, e.g. "30m", stops the port forward once it has had no
+	// connection and no traffic for that long. It never stops if it's empty.
+	IdleTimeout string `json:"idleTimeout,omitempty"`
 }
 
 func (p *portForwardRequest) Validate() error {
@@ -75,17 +94,78 @@ func (p *portForwardRequest) Validate() error {
 		return fmt.Errorf("namespace is required")
 	}
 
-	if p.Pod == "" {
-		return fmt.Errorf("pod name is required")
+	if err := p.validateTarget(); err != nil {
+		return err
+	}
+
+	if p.ServiceNamespace != "" && p.ServiceNamespace != p.Namespace {
+		return fmt.Errorf("serviceNamespace must be the namespace of the pod")
 	}
 
 	if p.TargetPort == "" {
 		return fmt.Errorf("targetPort is required")
 	}
 
+	if _, err := p.idleTimeout(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// validateTarget checks that the request targets a pod, or a service, a
+// workload or a selector whose pods it picks, and sets the kind of its workload.
+func (p *portForwardRequest) validateTarget() error {
+	targets := 0
+
+	for _, target := range []string{p.Service, p.WorkloadKind + p.WorkloadName, p.Selector} {
+		if target != "" {
+			targets++
+		}
+	}
+
+	if targets > 1 {
+		return fmt.Errorf("only one of service, workload and selector can be set")
+	}
+
+	if p.Pod == "" && targets == 0 {
+		return fmt.Errorf("pod name is required")
+	}
+
+	if p.WorkloadKind != "" || p.WorkloadName != "" {
+		kind, ok := workloadKind(p.WorkloadKind)
+		if !ok {
+			return fmt.Errorf("workloadKind must be Deployment, StatefulSet, DaemonSet or ReplicaSet")
+		}
+
+		if p.WorkloadName == "" {
+			return fmt.Errorf("workloadName is required")
+		}
+
+		p.WorkloadKind = kind
+	}
+
+	if p.Selector != "" {
+		return validateSelector(p.Selector)
+	}
+
+	return nil
+}
+
+// idleTimeout returns the parsed IdleTimeout, 0 if there's none.
+func (p *portForwardRequest) idleTimeout() (time.Duration, error) {
+	if p.IdleTimeout == "" {
+		return 0, nil
+	}
+
+	timeout, err := time.ParseDuration(p.IdleTimeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("idleTimeout must be a positive duration, e.g. 30m")
+	}
+
+	return timeout, nil
+}
+
 type portForward struct {
 	mu               *sync.Mutex
 	ID               string `json:"id"`
@@ -93,6 +173,9 @@ type portForward struct {
 	Pod              string `json:"pod"`
 	Service          string `json:"service"`
 	ServiceNamespace string `json:"serviceNamespace"`
+	WorkloadKind     string `json:"workloadKind,omitempty"`
+	WorkloadName     string `json:"workloadName,omitempty"`
+	Selector         string `json:"selector,omitempty"`
 	Namespace        string `json:"namespace"`
 	Cluster          string `json:"cluster"`
 	cacheKey         string `json:"-"`
@@ -100,6 +183,17 @@ type portForward struct {
 	TargetPort       string `json:"targetPort"`
 	Status           string `json:"status"`
 	Error            string `json:"error"`
+	// Reconnects is how many times a port-forward to a service, a workload or
+	// a selector reconnected to a pod after losing its connection. Pod is the
+	// pod it's connected to.
+	Reconnects int    `json:"reconnects"`
+	Label      string `json:"label"`
+	AutoStart  bool   `json:"autoStart"`
+	// IdleTimeout is the idle timeout of the request, and idleTimeout its value.
+	IdleTimeout string `json:"idleTimeout,omitempty"`
+	idleTimeout time.Duration
+	// traffic counts the connections and bytes of the running port forward.
+	traffic *trafficMeter
 }
 
 // setStatusAndSnapshot updates the Status and Error fields and returns a
@@ -119,6 +213,61 @@ func (pf *portForward) setStatusAndSnapshot(status, errMsg string) portForward {
 	return *pf
 }
 
+// isActive returns whether the port-forward is running or reconnecting.
+func (pf *portForward) isActive() bool {
+	return pf.Status == RUNNING || pf.Status == RECONNECTING
+}
+
+// currentPod returns the pod the port-forward is connected to.
+func (pf *portForward) currentPod() string {
+	if pf.mu != nil {
+		pf.mu.Lock()
+		defer pf.mu.Unlock()
+	}
+
+	return pf.Pod
+}
+
+// setReconnected records that the port-forward reconnected to a pod and
+// returns a snapshot of the struct.
+func (pf *portForward) setReconnected(pod string) portForward {
+	if pf.mu != nil {
+		pf.mu.Lock()
+		defer pf.mu.Unlock()
+	}
+
+	pf.Pod = pod
+	pf.Reconnects++
+	pf.Status = RUNNING
+	pf.Error = ""
+
+	return *pf
+}
+
+// target describes what the port-forward forwards to, e.g. "service web".
+func (pf *portForward) target() string {
+	switch {
+	case pf.Service != "":
+		return "service " + pf.Service
+	case pf.WorkloadName != "":
+		return strings.ToLower(pf.WorkloadKind) + " " + pf.WorkloadName
+	case pf.Selector != "":
+		return "pods matching " + pf.Selector
+	default:
+		return "pod " + pf.Pod
+	}
+}
+
+// closed returns whether the port-forward was stopped.
+func (pf *portForward) closed() bool {
+	select {
+	case <-pf.closeChan:
+		return true
+	default:
+		return false
+	}
+}
+
 func getFreePort() (int, error) {
 	addr, err := net.ResolveTCPAddr("tcp", "localhost:0")
 	if err != nil {
@@ -135,10 +284,25 @@ func getFreePort() (int, error) {
 	return l.Addr().(*net.TCPAddr).Port, nil
 }
 
-// StartPortForward handles the port forward request.
+// claimPortForwardStart marks a port forward as starting, so that it isn't
+// started twice at once. It returns the function to call once it's started,
+// or false if it's already starting.
+func claimPortForwardStart(contextKey, id string) (func(), bool) {
+	inFlightKey := strings.Join([]string{contextKey, id}, "\x00")
+	if _, loaded := inFlightPortForwards.LoadOrStore(inFlightKey, struct{}{}); loaded {
+		return nil, false
+	}
+
+	return func() { inFlightPortForwards.Delete(inFlightKey) }, true
+}
+
+// StartPortForward handles the port forward request. The definition of the
+// port forward is saved in definitions once it's started.
 //
 //nolint:funlen
 func StartPortForward(kubeConfigStore kubeconfig.ContextStore, cache cache.Cache[interface{}],
+	definitions *DefinitionStore,
+	metrics *telemetry.Metrics,
 	unsafeUseServiceAccountToken bool,
 	contextKey string,
 	w http.ResponseWriter, r *http.Request,
@@ -161,7 +325,7 @@ func StartPortForward(kubeConfigStore kubeconfig.ContextStore, cache cache.Cache
 	// Ensure we don't orphan an existing port-forward by overwriting its cache entry.
 	// This check happens before any resource allocation or blocking code so duplicates short-circuit
 	// deterministically and avoid unnecessary listener churn.
-	if existingPF, err := getPortForwardByID(cache, contextKey, p.ID); err == nil && existingPF.Status == RUNNING {
+	if existingPF, err := getPortForwardByID(cache, contextKey, p.ID); err == nil && existingPF.isActive() {
 		//nolint:goconst
 		logger.Log(logger.LevelError, map[string]string{"cluster": contextKey, "id": p.ID},
 			nil, "portforward ID already exists")
@@ -171,8 +335,8 @@ func StartPortForward(kubeConfigStore kubeconfig.ContextStore, cache cache.Cache
 	}
 
 	// Reject duplicates before any resource-consuming work (port alloc, kubeconfig lookup).
-	inFlightKey := strings.Join([]string{contextKey, p.ID}, "\x00")
-	if _, loaded := inFlightPortForwards.LoadOrStore(inFlightKey, struct{}{}); loaded {
+	done, ok := claimPortForwardStart(contextKey, p.ID)
+	if !ok {
 		logger.Log(logger.LevelError, map[string]string{"cluster": contextKey, "id": p.ID},
 			nil, "portforward ID is already starting")
 		http.Error(w, "portforward with this ID is already starting", http.StatusConflict)
@@ -180,7 +344,7 @@ func StartPortForward(kubeConfigStore kubeconfig.ContextStore, cache cache.Cache
 		return
 	}
 
-	defer inFlightPortForwards.Delete(inFlightKey)
+	defer done()
 
 	if err := p.Validate(); err != nil {
 		logger.Log(logger.LevelError, nil, err, "validating portforward payload")
@@ -189,6 +353,10 @@ func StartPortForward(kubeConfigStore kubeconfig.ContextStore, cache cache.Cache
 		return
 	}
 
+	if contextKey == requestClusterName {
+		mergeSavedDefinition(definitions, &p, requestClusterName)
+	}
+
 	if p.Port == "" {
 		freePort, err := getFreePort()
 		if err != nil || freePort == 0 {
@@ -216,7 +384,7 @@ func StartPortForward(kubeConfigStore kubeconfig.ContextStore, cache cache.Cache
 		token, _ = auth.GetTokenFromCookie(r, requestClusterName)
 	}
 
-	err = startPortForward(kContext, cache, p, token, contextKey, requestClusterName)
+	err = startPortForward(kContext, cache, metrics, &p, token, contextKey, requestClusterName)
 	if err != nil {
 		logger.Log(logger.LevelError, nil, err, "starting portforward")
 
@@ -225,6 +393,8 @@ func StartPortForward(kubeConfigStore kubeconfig.ContextStore, cache cache.Cache
 		return
 	}
 
+	saveDefinition(definitions, p, contextKey, requestClusterName)
+
 	w.Header().Set("Content-Type", "application/json")
 
 	if err = json.NewEncoder(w).Encode(p); err != nil {
@@ -235,10 +405,13 @@ func StartPortForward(kubeConfigStore kubeconfig.ContextStore, cache cache.Cache
 	}
 }
 
+// errAccessDenied is returned when the user may not port-forward to a pod.
+var errAccessDenied = errors.New("access denied")
+
 // checkPortForwardPermission checks if the current user has permission to create pods/portforward.
 // It uses SelfSubjectAccessReview to verify RBAC permissions for the specified namespace and pod.
 // Returns an error if permission is denied or if the permission check fails.
-func checkPortForwardPermission(clientset *kubernetes.Clientset, namespace, podName string) error {
+func checkPortForwardPermission(clientset kubernetes.Interface, namespace, podName string) error {
 	ctx := context.Background()
 
 	// Create a SelfSubjectAccessReview to check permissions
@@ -266,7 +439,7 @@ func checkPortForwardPermission(clientset *kubernetes.Clientset, namespace, podN
 			reason = result.Status.Reason
 		}
 
-		return fmt.Errorf("access denied: %s", reason)
+		return fmt.Errorf("%w: %s", errAccessDenied, reason)
 	}
 
 	return nil
@@ -363,8 +536,9 @@ func buildPortForwardURL(host, namespace, podName, targetPort string) (*url.URL,
 
 // initPortForwarder sets up the SPDY dialer and creates a new port forwarder.
 // It requires a REST config, namespace, pod name, and the port mapping string (e.g., "8080:80").
+// The traffic of the forwarded connections is counted by meter, unless it's nil.
 // It returns the port forwarder instance, stop/ready channels, output/error buffers, or an error.
-func initPortForwarder(rConf *rest.Config, namespace, podName, portMapping, targetPort string) (
+func initPortForwarder(rConf *rest.Config, namespace, podName, portMapping, targetPort string, meter *trafficMeter) (
 	*portforward.PortForwarder, chan struct{}, chan struct{}, *bytes.Buffer, *bytes.Buffer, error,
 ) {
 	roundTripper, upgrader, err := spdy.RoundTripperFor(rConf)
@@ -379,6 +553,9 @@ func initPortForwarder(rConf *rest.Config, namespace, podName, portMapping, targ
 
 	// Path-routed proxies use WebSocket first; direct API endpoints use SPDY.
 	dialer := buildPortForwardDialer(rConf, fullURL, upgrader, roundTripper)
+	if meter != nil {
+		dialer = &meteredDialer{Dialer: dialer, meter: meter}
+	}
 
 	stopChan, readyChan := make(chan struct{}), make(chan struct{}, 1)
 	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
@@ -411,7 +588,7 @@ func safeCloseChan(ch chan struct{}) {
 // to stop by closing its stopChan and updates its status in the cache.
 // It stops when the associated port-forward's closeChan is closed.
 func monitorPodAndManagePortForward(
-	clientset *kubernetes.Clientset,
+	clientset kubernetes.Interface,
 	cache cache.Cache[interface{}],
 	pfDetails *portForward,
 ) {
@@ -472,6 +649,10 @@ func handlePortForwardSuccess(
 	pfSnapshot := pfDetails.setStatusAndSnapshot(RUNNING, "")
 	portforwardstore(cache, pfSnapshot)
 	logger.Log(logger.LevelInfo, logParams, nil, "Port forward ready and running.")
+
+	if pfDetails.idleTimeout > 0 && pfDetails.traffic != nil {
+		go watchIdleTimeout(cache, pfDetails)
+	}
 }
 
 // handlePortForwardReadiness waits for the port forward to be ready, handling potential
@@ -597,7 +778,7 @@ func forwardPortsAsync(
 // then handles its readiness, and if ready, starts another goroutine to
 // monitor the target pod's status.
 func runAndMonitorPortForward(
-	clientset *kubernetes.Clientset,
+	clientset kubernetes.Interface,
 	cache cache.Cache[interface{}],
 	pfDetails *portForward,
 	forwarder *portforward.PortForwarder,
@@ -623,14 +804,41 @@ func runAndMonitorPortForward(
 
 // startPortForward starts a port forward. This is the internal function that was refactored.
 // It sets up Kubernetes clients, initializes the port forwarder, and manages its lifecycle.
-func startPortForward(kContext *kubeconfig.Context, cache cache.Cache[interface{}],
-	p portForwardRequest, token string, clusterName string, requestClusterName string,
+// A port forward to a service, a workload or a selector reconnects to another
+// of their pods when its pod goes away, and is started on a ready one of them
+// if p has no pod.
+// Its traffic is recorded in metrics, if they're enabled.
+func startPortForward(kContext *kubeconfig.Context, cache cache.Cache[interface{}], metrics *telemetry.Metrics,
+	p *portForwardRequest, token string, clusterName string, requestClusterName string,
 ) error {
 	clientset, rConf, err := getKubeClientAndConfig(kContext, token)
 	if err != nil {
 		return fmt.Errorf("failed to setup Kubernetes client/config: %w", err)
 	}
 
+	if p.Service != "" || p.WorkloadName != "" || p.Selector != "" {
+		pfDetails := newPortForward(p, clusterName, requestClusterName)
+		pfDetails.closeChan = make(chan struct{})
+		pfDetails.traffic = newTrafficMeter(metrics, pfDetails)
+
+		reconnector := newWorkloadReconnector(clientset, rConf, pfDetails)
+		if p.Service != "" {
+			reconnector = newServiceReconnector(clientset, rConf, pfDetails)
+		}
+
+		if pfDetails.Pod == "" {
+			pfDetails.Pod, err = reconnector.resolvePod(context.Background())
+			if err != nil {
+				return fmt.Errorf("failed to find a pod of %s: %w", pfDetails.target(), err)
+			}
+		}
+
+		err = startReconnectingPortForward(cache, pfDetails, reconnector)
+		p.Pod = pfDetails.currentPod()
+
+		return err
+	}
+
 	// Check RBAC permissions before attempting port forward
 	err = checkPortForwardPermission(clientset, p.Namespace, p.Pod)
 	if err != nil {
@@ -638,6 +846,8 @@ func startPortForward(kContext *kubeconfig.Context, cache cache.Cache[interface{
 	}
 
 	portMapping := p.Port + ":" + p.TargetPort
+	pfDetails := newPortForward(p, clusterName, requestClusterName)
+	pfDetails.traffic = newTrafficMeter(metrics, pfDetails)
 
 	var (
 		forwarder           *portforward.PortForwarder
@@ -647,7 +857,7 @@ func startPortForward(kContext *kubeconfig.Context, cache cache.Cache[interface{
 	)
 
 	forwarder, stopChan, readyChan, outBuffer, errOut, errInit = initPortForwarder(
-		rConf, p.Namespace, p.Pod, portMapping, p.TargetPort,
+		rConf, p.Namespace, p.Pod, portMapping, p.TargetPort, pfDetails.traffic,
 	)
 	if errInit != nil {
 		return fmt.Errorf("failed to initialize port forwarder: %w", errInit)
@@ -655,26 +865,40 @@ func startPortForward(kContext *kubeconfig.Context, cache cache.Cache[interface{
 
 	_ = outBuffer // Avoid unused variable error if outBuffer isn't used directly later
 
-	pfDetails := &portForward{
+	pfDetails.closeChan = stopChan
+
+	return runAndMonitorPortForward(clientset, cache, pfDetails, forwarder, readyChan, errOut)
+}
+
+// newPortForward returns the running port forward of a request, without its
+// closeChan and traffic meter.
+func newPortForward(p *portForwardRequest, clusterName string, requestClusterName string) *portForward {
+	idleTimeout, _ := p.idleTimeout()
+
+	return &portForward{
 		mu:               &sync.Mutex{},
 		ID:               p.ID,
-		closeChan:        stopChan,
 		Pod:              p.Pod,
 		Cluster:          requestClusterName,
 		cacheKey:         clusterName,
 		Namespace:        p.Namespace,
 		Service:          p.Service,
 		ServiceNamespace: p.ServiceNamespace,
+		WorkloadKind:     p.WorkloadKind,
+		WorkloadName:     p.WorkloadName,
+		Selector:         p.Selector,
 		TargetPort:       p.TargetPort,
 		Status:           RUNNING,
 		Port:             p.Port,
 		Error:            "",
+		Label:            p.Label,
+		AutoStart:        p.AutoStart != nil && *p.AutoStart,
+		IdleTimeout:      p.IdleTimeout,
+		idleTimeout:      idleTimeout,
 	}
-
-	return runAndMonitorPortForward(clientset, cache, pfDetails, forwarder, readyChan, errOut)
 }
 
-func checkIfPodIsRunning(clientset *kubernetes.Clientset, namespace string, pod string) error {
+func checkIfPodIsRunning(clientset kubernetes.Interface, namespace string, pod string) error {
 	ctx := context.Background()
 
 	p, err := clientset.CoreV1().Pods(namespace).Get(ctx, pod, v1.GetOptions{})
@@ -703,8 +927,9 @@ func (r *stopOrDeletePortForwardRequest) Validate() error {
 	return nil
 }
 
-// StopOrDeletePortForward handles stop or delete port forward request.
-func StopOrDeletePortForward(cache cache.Cache[interface{}], contextKey string,
+// StopOrDeletePortForward handles stop or delete port forward request. A
+// deleted port forward is removed from definitions, a stopped one is kept.
+func StopOrDeletePortForward(cache cache.Cache[interface{}], definitions *DefinitionStore, contextKey string,
 	w http.ResponseWriter, r *http.Request,
 ) {
 	var p stopOrDeletePortForwardRequest
@@ -725,6 +950,10 @@ func StopOrDeletePortForward(cache cache.Cache[interface{}], contextKey string,
 	}
 
 	err = stopOrDeletePortForward(cache, contextKey, p.ID, p.StopOrDelete)
+	if err == nil && !p.StopOrDelete {
+		err = definitions.remove(contextKey, p.ID)
+	}
+
 	if err == nil {
 		if _, err := w.Write([]byte("stopped")); err != nil {
 			logger.Log(logger.LevelError, nil, err, "writing response")
@@ -790,19 +1019,29 @@ func GetPortForwardByID(cache cache.Cache[interface{}], contextKey string,
 	}
 
 	type payload struct {
-		ID        string `json:"id"`
-		Pod       string `json:"pod"`
-		Service   string `json:"service"`
-		Cluster   string `json:"cluster"`
-		Namespace string `json:"namespace"`
+		ID           string `json:"id"`
+		Pod          string `json:"pod"`
+		Service      string `json:"service"`
+		WorkloadKind string `json:"workloadKind,omitempty"`
+		WorkloadName string `json:"workloadName,omitempty"`
+		Selector     string `json:"selector,omitempty"`
+		Cluster      string `json:"cluster"`
+		Namespace    string `json:"namespace"`
+		IdleTimeout  string `json:"idleTimeout,omitempty"`
+		trafficStats
 	}
 
 	portForwardStruct := payload{
-		ID:        p.ID,
-		Pod:       p.Pod,
-		Namespace: p.Namespace,
-		Cluster:   cluster,
-		Service:   p.Service,
+		ID:           p.ID,
+		Pod:          p.Pod,
+		Namespace:    p.Namespace,
+		Cluster:      cluster,
+		Service:      p.Service,
+		WorkloadKind: p.WorkloadKind,
+		WorkloadName: p.WorkloadName,
+		Selector:     p.Selector,
+		IdleTimeout:  p.IdleTimeout,
+		trafficStats: p.traffic.stats(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -133,7 +133,7 @@ func TestStartPortForward(t *testing.T) {
 	req.Body = io.NopCloser(bytes.NewReader(jsonReq))
 	req.Header.Set("Content-Type", "application/json")
 
-	portforward.StartPortForward(kubeConfigStore, ch, false, minikubeName, resp, req)
+	portforward.StartPortForward(kubeConfigStore, ch, nil, nil, false, minikubeName, resp, req)
 
 	res := resp.Result()
 
@@ -204,7 +204,7 @@ func TestStartPortForward(t *testing.T) {
 	stopReq.Header.Set("Content-Type", "application/json")
 	stopReq = mux.SetURLVars(stopReq, map[string]string{"clusterName": minikubeName})
 
-	portforward.StopOrDeletePortForward(ch, minikubeName, stopResp, stopReq)
+	portforward.StopOrDeletePortForward(ch, nil, minikubeName, stopResp, stopReq)
 
 	stopRes := stopResp.Result()
 
@@ -315,7 +315,7 @@ func TestStartPortForward(t *testing.T) {
 	deleteReq.Header.Set("Content-Type", "application/json")
 	deleteReq = mux.SetURLVars(deleteReq, map[string]string{"clusterName": minikubeName})
 
-	portforward.StopOrDeletePortForward(ch, minikubeName, deleteResp, deleteReq)
+	portforward.StopOrDeletePortForward(ch, nil, minikubeName, deleteResp, deleteReq)
 
 	deleteRes := deleteResp.Result()
 
@@ -191,7 +191,7 @@ func TestStopOrDeletePortForward_InvalidJSON(t *testing.T) {
 		"clusterName": "test-cluster",
 	})
 
-	portforward.StopOrDeletePortForward(ch, "test-cluster", w, r)
+	portforward.StopOrDeletePortForward(ch, nil, "test-cluster", w, r)
 
 	res := w.Result()
 
@@ -245,7 +245,7 @@ func TestStopOrDeletePortForward_ErrorCases(t *testing.T) {
 				"clusterName": "test-cluster",
 			})
 
-			portforward.StopOrDeletePortForward(ch, "test-cluster", w, r)
+			portforward.StopOrDeletePortForward(ch, nil, "test-cluster", w, r)
 
 			res := w.Result()
 
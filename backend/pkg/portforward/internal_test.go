@@ -716,7 +716,7 @@ func TestStopOrDeletePortForwardHandler_UserIDKeyIsolation(t *testing.T) {
 	r := httptest.NewRequestWithContext(context.Background(), http.MethodDelete, "/portforward", bytes.NewReader(payload))
 	r = mux.SetURLVars(r, map[string]string{"clusterName": "cluster"})
 
-	StopOrDeletePortForward(c, "clusteruser999", w, r)
+	StopOrDeletePortForward(c, nil, "clusteruser999", w, r)
 
 	res := w.Result()
 
@@ -762,7 +762,7 @@ func TestStartPortForward_DuplicateIDConflict(t *testing.T) {
 	r.Header.Set("X-HEADLAMP-USER-ID", "user")
 	r = mux.SetURLVars(r, map[string]string{"clusterName": clusterName})
 
-	StartPortForward(kubeConfigStore, c, false, contextKey, w, r)
+	StartPortForward(kubeConfigStore, c, nil, nil, false, contextKey, w, r)
 
 	res := w.Result()
 
@@ -816,7 +816,7 @@ func TestStartPortForward_ConcurrentRequests(t *testing.T) {
 		r := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/portforward", bytes.NewReader(body))
 		r = mux.SetURLVars(r, map[string]string{"clusterName": "test-cluster"})
 
-		StartPortForward(store, c, false, "test-cluster", w, r)
+		StartPortForward(store, c, nil, nil, false, "test-cluster", w, r)
 
 		return w
 	}
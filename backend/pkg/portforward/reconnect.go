@@ -0,0 +1,410 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	reconnectInitialBackoff = time.Second
+	reconnectMaxBackoff     = 30 * time.Second
+	// reconnectMaxAttempts is how many times a port-forward tries to reconnect
+	// before it stops, about 4 minutes with the backoff.
+	reconnectMaxAttempts = 12
+)
+
+var errPortForwardClosed = errors.New("portforward was stopped")
+
+// podConnection is the connection of a port-forward to one pod.
+type podConnection struct {
+	stopChan chan struct{}
+	// done receives the result of ForwardPorts once it returns.
+	done chan error
+}
+
+// close stops forwarding to the pod and waits until the local port is released.
+func (c *podConnection) close() {
+	safeCloseChan(c.stopChan)
+
+	for range c.done { //nolint:revive // Drain until ForwardPorts returns.
+	}
+}
+
+// podReconnector finds the pod a port-forward targets and connects to it,
+// so that it can move to another pod when its pod goes away.
+type podReconnector struct {
+	// resolvePod returns the name of a ready pod to forward to.
+	resolvePod func(ctx context.Context) (string, error)
+	// connect forwards the local port to the pod, once it's ready.
+	connect func(pod string) (*podConnection, error)
+	// checkPod returns an error if the pod isn't running anymore.
+	checkPod func(pod string) error
+	// backoff is the initial delay between reconnection attempts.
+	backoff time.Duration
+}
+
+// newServiceReconnector returns the reconnector of a port-forward to a
+// service, which moves to another ready pod of the service.
+func newServiceReconnector(
+	clientset kubernetes.Interface,
+	rConf *rest.Config,
+	pfDetails *portForward,
+) *podReconnector {
+	namespace := pfDetails.ServiceNamespace
+	if namespace == "" {
+		namespace = pfDetails.Namespace
+	}
+
+	return newPodReconnector(clientset, rConf, pfDetails, func(ctx context.Context) (string, error) {
+		return resolveServicePod(ctx, clientset, namespace, pfDetails.Service)
+	})
+}
+
+// newPodReconnector returns the reconnector of a port-forward whose pods are
+// found by resolvePod.
+func newPodReconnector(
+	clientset kubernetes.Interface,
+	rConf *rest.Config,
+	pfDetails *portForward,
+	resolvePod func(ctx context.Context) (string, error),
+) *podReconnector {
+	return &podReconnector{
+		resolvePod: resolvePod,
+		connect: func(pod string) (*podConnection, error) {
+			if err := checkPortForwardPermission(clientset, pfDetails.Namespace, pod); err != nil {
+				return nil, fmt.Errorf("permission check failed: %w", err)
+			}
+
+			return connectPod(rConf, pfDetails, pod)
+		},
+		checkPod: func(pod string) error {
+			return checkIfPodIsRunning(clientset, pfDetails.Namespace, pod)
+		},
+		backoff: reconnectInitialBackoff,
+	}
+}
+
+// resolveServicePod returns a ready pod backing a service: one of the ready
+// endpoints of its EndpointSlices, or else a ready pod matching its selector.
+func resolveServicePod(ctx context.Context, clientset kubernetes.Interface, namespace, service string) (string, error) {
+	endpointSlices, err := clientset.DiscoveryV1().EndpointSlices(namespace).List(ctx, v1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + service,
+	})
+	if err != nil {
+		logger.Log(logger.LevelWarn, map[string]string{"namespace": namespace, "service": service},
+			err, "listing endpoint slices, falling back to the service selector")
+	} else if pod := readyEndpointPod(endpointSlices.Items); pod != "" {
+		return pod, nil
+	}
+
+	svc, err := clientset.CoreV1().Services(namespace).Get(ctx, service, v1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	if len(svc.Spec.Selector) == 0 {
+		return "", fmt.Errorf("service %s/%s has no ready endpoints", namespace, service)
+	}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, v1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	pod := readyPod(pods.Items)
+	if pod == "" {
+		return "", fmt.Errorf("service %s/%s has no ready pod", namespace, service)
+	}
+
+	return pod, nil
+}
+
+// readyEndpointPod returns the first, by name, of the pods that are ready
+// endpoints in the slices, or "" if there's none.
+func readyEndpointPod(endpointSlices []discoveryv1.EndpointSlice) string {
+	pods := []string{}
+
+	for _, slice := range endpointSlices {
+		for _, endpoint := range slice.Endpoints {
+			// A nil condition means the endpoint is ready and not terminating.
+			ready := endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+			terminating := endpoint.Conditions.Terminating != nil && *endpoint.Conditions.Terminating
+
+			if ready && !terminating && endpoint.TargetRef != nil && endpoint.TargetRef.Kind == "Pod" {
+				pods = append(pods, endpoint.TargetRef.Name)
+			}
+		}
+	}
+
+	if len(pods) == 0 {
+		return ""
+	}
+
+	return slices.Min(pods)
+}
+
+// readyPod returns the first, by name, of the pods that are running and
+// ready, or "" if there's none.
+func readyPod(pods []corev1.Pod) string {
+	names := []string{}
+
+	for i := range pods {
+		if isPodReady(&pods[i]) {
+			names = append(names, pods[i].Name)
+		}
+	}
+
+	if len(names) == 0 {
+		return ""
+	}
+
+	return slices.Min(names)
+}
+
+// isPodReady returns whether a pod is running, ready and not being deleted.
+func isPodReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+		return false
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// connectPod forwards the local port of a port-forward to a pod and waits
+// until the forward is ready.
+func connectPod(rConf *rest.Config, pfDetails *portForward, pod string) (*podConnection, error) {
+	forwarder, stopChan, readyChan, _, errOut, err := initPortForwarder(
+		rConf, pfDetails.Namespace, pod, pfDetails.Port+":"+pfDetails.TargetPort, pfDetails.TargetPort,
+		pfDetails.traffic,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize port forwarder: %w", err)
+	}
+
+	conn := &podConnection{stopChan: stopChan, done: make(chan error, 1)}
+
+	go func() {
+		conn.done <- forwarder.ForwardPorts()
+		close(conn.done)
+	}()
+
+	select {
+	case <-readyChan:
+		if errOut.String() != "" {
+			conn.close()
+
+			return nil, fmt.Errorf("portforward failed, stderr: %s", errOut.String())
+		}
+
+		return conn, nil
+	case err := <-conn.done:
+		if err == nil {
+			err = errors.New("portforward stopped before ready")
+		}
+
+		return nil, err
+	case <-time.After(PortForwardReadinessTimeout):
+		conn.close()
+
+		return nil, errors.New("timeout waiting for portforward to be ready")
+	case <-pfDetails.closeChan:
+		conn.close()
+
+		return nil, errPortForwardClosed
+	}
+}
+
+// startReconnectingPortForward connects a port-forward to its pod and keeps it
+// connected in the background. If it can't connect to its pod, e.g. the saved
+// pod of a restored port-forward that's gone, it connects to another pod.
+func startReconnectingPortForward(
+	cache cache.Cache[interface{}],
+	pfDetails *portForward,
+	reconnector *podReconnector,
+) error {
+	logParams := map[string]string{"id": pfDetails.ID, "target": pfDetails.target(), "port": pfDetails.Port}
+
+	conn, err := reconnector.connect(pfDetails.Pod)
+	if err != nil && !errors.Is(err, errPortForwardClosed) {
+		if pod, resolveErr := reconnector.resolvePod(context.Background()); resolveErr == nil && pod != pfDetails.Pod {
+			logger.Log(logger.LevelWarn, logParams, err, "connecting to pod, trying pod "+pod)
+
+			pfDetails.Pod = pod
+			conn, err = reconnector.connect(pod)
+		}
+	}
+
+	if err != nil {
+		return handlePortForwardError(cache, pfDetails, logParams, err.Error())
+	}
+
+	handlePortForwardSuccess(cache, pfDetails, logParams)
+
+	go superviseReconnectingPortForward(cache, pfDetails, reconnector, conn)
+
+	return nil
+}
+
+// superviseReconnectingPortForward runs in a goroutine while a port-forward is
+// running. When its connection is lost or its pod stops running, it resolves
+// a pod again and reconnects on the same local port, with backoff, until the
+// port-forward is stopped by closing its closeChan.
+func superviseReconnectingPortForward(
+	cache cache.Cache[interface{}],
+	pfDetails *portForward,
+	reconnector *podReconnector,
+	conn *podConnection,
+) {
+	logParams := map[string]string{"id": pfDetails.ID, "target": pfDetails.target(), "port": pfDetails.Port}
+
+	for {
+		err := waitForDisconnect(pfDetails, reconnector, conn)
+		conn.close()
+
+		if errors.Is(err, errPortForwardClosed) {
+			logger.Log(logger.LevelInfo, logParams, nil, "port forward closeChan was closed, stopping")
+			return
+		}
+
+		logger.Log(logger.LevelWarn, logParams, err, "lost connection to pod, reconnecting")
+		portforwardstore(cache, pfDetails.setStatusAndSnapshot(RECONNECTING, "reconnecting: "+err.Error()))
+
+		conn = reconnect(cache, pfDetails, reconnector, logParams)
+		if conn == nil {
+			return
+		}
+	}
+}
+
+// waitForDisconnect waits until the connection to the pod of a port-forward
+// is lost, its pod stops running, or the port-forward is stopped.
+func waitForDisconnect(pfDetails *portForward, reconnector *podReconnector, conn *podConnection) error {
+	ticker := time.NewTicker(PodAvailabilityCheckTimer * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-conn.done:
+			if err == nil {
+				err = errors.New("connection to pod closed")
+			}
+
+			return err
+		case <-ticker.C:
+			err := reconnector.checkPod(pfDetails.currentPod())
+			if err != nil && !errors.Is(err, syscall.ECONNREFUSED) {
+				return fmt.Errorf("pod check failed: %w", err)
+			}
+		case <-pfDetails.closeChan:
+			return errPortForwardClosed
+		}
+	}
+}
+
+// reconnect resolves a pod and connects to it until it succeeds, doubling
+// the delay between attempts up to reconnectMaxBackoff. It returns nil if
+// the port-forward is stopped meanwhile, or if it stops the port-forward
+// because the error is permanent or reconnectMaxAttempts attempts failed.
+func reconnect(
+	cache cache.Cache[interface{}],
+	pfDetails *portForward,
+	reconnector *podReconnector,
+	logParams map[string]string,
+) *podConnection {
+	backoff := reconnector.backoff
+
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-time.After(backoff):
+		case <-pfDetails.closeChan:
+			return nil
+		}
+
+		pod, err := reconnector.resolvePod(context.Background())
+		// The service or workload forwarded to is gone.
+		targetGone := apierrors.IsNotFound(err)
+
+		if err == nil {
+			var conn *podConnection
+
+			conn, err = reconnector.connect(pod)
+			if err == nil && pfDetails.closed() {
+				conn.close()
+
+				return nil
+			}
+
+			if err == nil {
+				portforwardstore(cache, pfDetails.setReconnected(pod))
+				logger.Log(logger.LevelInfo, map[string]string{"id": pfDetails.ID, "pod": pod}, nil, "port forward reconnected")
+
+				return conn
+			}
+		}
+
+		if errors.Is(err, errPortForwardClosed) || pfDetails.closed() {
+			return nil
+		}
+
+		if targetGone || isPermanentReconnectError(err) {
+			_ = handlePortForwardError(cache, pfDetails, logParams, err.Error())
+			return nil
+		}
+
+		if attempt >= reconnectMaxAttempts {
+			_ = handlePortForwardError(cache, pfDetails, logParams,
+				fmt.Sprintf("reconnecting failed after %d attempts: %v", attempt, err))
+
+			return nil
+		}
+
+		logger.Log(logger.LevelWarn, logParams, err, "reconnecting port forward")
+		portforwardstore(cache, pfDetails.setStatusAndSnapshot(RECONNECTING, "reconnecting: "+err.Error()))
+
+		backoff = min(backoff*2, reconnectMaxBackoff)
+	}
+}
+
+// isPermanentReconnectError returns whether reconnecting failed because the
+// user may not reach the target, which retrying doesn't fix.
+func isPermanentReconnectError(err error) bool {
+	return apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err) || errors.Is(err, errAccessDenied)
+}
@@ -0,0 +1,293 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestPod(name string, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: v1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "web"}},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+func newTestService() *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "web"}},
+	}
+}
+
+func TestResolveServicePod(t *testing.T) {
+	ready, notReady := true, false
+
+	t.Run("endpoint_slices", func(t *testing.T) {
+		slice := &discoveryv1.EndpointSlice{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "web-abc",
+				Namespace: "default",
+				Labels:    map[string]string{discoveryv1.LabelServiceName: "web"},
+			},
+			Endpoints: []discoveryv1.Endpoint{
+				{
+					Conditions: discoveryv1.EndpointConditions{Ready: &notReady},
+					TargetRef:  &corev1.ObjectReference{Kind: "Pod", Name: "web-a"},
+				},
+				{
+					Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+					TargetRef:  &corev1.ObjectReference{Kind: "Pod", Name: "web-b"},
+				},
+			},
+		}
+
+		clientset := fake.NewSimpleClientset(newTestService(), slice)
+
+		pod, err := resolveServicePod(context.Background(), clientset, "default", "web")
+		require.NoError(t, err)
+		assert.Equal(t, "web-b", pod)
+	})
+
+	t.Run("selector", func(t *testing.T) {
+		clientset := fake.NewSimpleClientset(newTestService(), newTestPod("web-a", false), newTestPod("web-b", true))
+
+		pod, err := resolveServicePod(context.Background(), clientset, "default", "web")
+		require.NoError(t, err)
+		assert.Equal(t, "web-b", pod)
+	})
+
+	t.Run("no_ready_pod", func(t *testing.T) {
+		clientset := fake.NewSimpleClientset(newTestService(), newTestPod("web-a", false))
+
+		_, err := resolveServicePod(context.Background(), clientset, "default", "web")
+		assert.ErrorContains(t, err, "has no ready pod")
+	})
+
+	t.Run("missing_service", func(t *testing.T) {
+		_, err := resolveServicePod(context.Background(), fake.NewSimpleClientset(), "default", "web")
+		assert.Error(t, err)
+	})
+}
+
+// newTestConnection returns a connection that is done once it's stopped, or
+// once it's lost by sending an error to done.
+func newTestConnection() *podConnection {
+	conn := &podConnection{stopChan: make(chan struct{}), done: make(chan error, 1)}
+
+	go func() {
+		<-conn.stopChan
+		close(conn.done)
+	}()
+
+	return conn
+}
+
+func TestSuperviseReconnectingPortForward(t *testing.T) {
+	c := cache.New[interface{}]()
+
+	pfDetails := &portForward{
+		mu:        &sync.Mutex{},
+		ID:        "id",
+		closeChan: make(chan struct{}),
+		Pod:       "web-a",
+		Service:   "web",
+		Namespace: "default",
+		cacheKey:  "cluster",
+		Status:    RUNNING,
+	}
+
+	var (
+		resolves    atomic.Int32
+		connections = make(chan *podConnection, 1)
+	)
+
+	reconnector := &podReconnector{
+		resolvePod: func(ctx context.Context) (string, error) {
+			// The first attempt finds no ready pod, as the pod is replaced.
+			if resolves.Add(1) == 1 {
+				return "", errors.New("service default/web has no ready pod")
+			}
+
+			return "web-b", nil
+		},
+		connect: func(pod string) (*podConnection, error) {
+			conn := newTestConnection()
+			connections <- conn
+
+			return conn, nil
+		},
+		checkPod: func(pod string) error { return nil },
+		backoff:  time.Millisecond,
+	}
+
+	first := newTestConnection()
+	done := make(chan struct{})
+
+	go func() {
+		superviseReconnectingPortForward(c, pfDetails, reconnector, first)
+		close(done)
+	}()
+
+	first.done <- errors.New("lost connection to pod")
+
+	var second *podConnection
+
+	select {
+	case second = <-connections:
+	case <-time.After(5 * time.Second):
+		t.Fatal("port forward didn't reconnect")
+	}
+
+	require.Eventually(t, func() bool {
+		stored, err := getPortForwardByID(c, "cluster", "id")
+		return err == nil && stored.Reconnects == 1
+	}, 5*time.Second, time.Millisecond)
+
+	stored, err := getPortForwardByID(c, "cluster", "id")
+	require.NoError(t, err)
+	assert.Equal(t, "web-b", stored.Pod)
+	assert.Equal(t, RUNNING, stored.Status)
+	assert.Empty(t, stored.Error)
+	assert.Equal(t, int32(2), resolves.Load())
+
+	safeCloseChan(pfDetails.closeChan)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor didn't stop with the port forward")
+	}
+
+	_, open := <-second.stopChan
+	assert.False(t, open, "the connection is stopped with the port forward")
+}
+
+func TestReconnectStops(t *testing.T) {
+	podsResource := schema.GroupResource{Resource: "pods"}
+	servicesResource := schema.GroupResource{Resource: "services"}
+
+	tests := []struct {
+		name       string
+		resolveErr error
+		connectErr error
+		attempts   int32
+		wantError  string
+	}{
+		{
+			name:       "target_gone",
+			resolveErr: apierrors.NewNotFound(servicesResource, "web"),
+			attempts:   1,
+			wantError:  `services "web" not found`,
+		},
+		{
+			name:       "forbidden",
+			resolveErr: apierrors.NewForbidden(podsResource, "", errors.New("no access")),
+			attempts:   1,
+			wantError:  "forbidden",
+		},
+		{
+			name:       "unauthorized",
+			resolveErr: apierrors.NewUnauthorized("expired token"),
+			attempts:   1,
+			wantError:  "expired token",
+		},
+		{
+			name:       "access_denied",
+			connectErr: fmt.Errorf("permission check failed: %w: no port-forward", errAccessDenied),
+			attempts:   1,
+			wantError:  "access denied: no port-forward",
+		},
+		{
+			name:       "too_many_attempts",
+			resolveErr: errors.New("service default/web has no ready pod"),
+			attempts:   reconnectMaxAttempts,
+			wantError:  "reconnecting failed after 12 attempts: service default/web has no ready pod",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cache.New[interface{}]()
+			pfDetails := &portForward{
+				mu:        &sync.Mutex{},
+				ID:        "id",
+				closeChan: make(chan struct{}),
+				Pod:       "web-a",
+				Service:   "web",
+				Namespace: "default",
+				cacheKey:  "cluster",
+				Status:    RECONNECTING,
+			}
+
+			var attempts atomic.Int32
+
+			reconnector := &podReconnector{
+				resolvePod: func(ctx context.Context) (string, error) {
+					attempts.Add(1)
+					return "web-b", tt.resolveErr
+				},
+				connect: func(pod string) (*podConnection, error) {
+					return nil, tt.connectErr
+				},
+				backoff: time.Microsecond,
+			}
+
+			conn := reconnect(c, pfDetails, reconnector, map[string]string{})
+			assert.Nil(t, conn)
+			assert.Equal(t, tt.attempts, attempts.Load())
+			assert.True(t, pfDetails.closed(), "the port forward is stopped")
+
+			stored, err := getPortForwardByID(c, "cluster", "id")
+			require.NoError(t, err)
+			assert.Equal(t, STOPPED, stored.Status)
+			assert.Contains(t, stored.Error, tt.wantError)
+		})
+	}
+}
+
+func TestPortForwardRequestValidateService(t *testing.T) {
+	req := portForwardRequest{Namespace: "default", Service: "web", TargetPort: "80"}
+	assert.NoError(t, req.Validate(), "a service port forward doesn't need a pod")
+
+	req.ServiceNamespace = "other"
+	assert.ErrorContains(t, req.Validate(), "serviceNamespace must be the namespace of the pod")
+}
@@ -0,0 +1,255 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream" //nolint:staticcheck // SA1019: client-go/tools/portforward still uses this; migrate when upstream does.
+)
+
+// trafficMeter counts the connections and bytes of a port-forward, across
+// its reconnections, and records them in the telemetry metrics if enabled.
+type trafficMeter struct {
+	activeConnections atomic.Int64
+	bytesIn           atomic.Int64
+	bytesOut          atomic.Int64
+	// lastActivity is the time, in Unix nanoseconds, a connection was opened,
+	// closed, or carried data. It starts when the port-forward starts.
+	lastActivity atomic.Int64
+
+	metrics *telemetry.Metrics
+	attrs   metric.MeasurementOption
+}
+
+// trafficStats is a snapshot of the traffic of a port-forward.
+type trafficStats struct {
+	ActiveConnections int64     `json:"activeConnections"`
+	BytesIn           int64     `json:"bytesIn"`
+	BytesOut          int64     `json:"bytesOut"`
+	LastActivity      time.Time `json:"lastActivity"`
+}
+
+// newTrafficMeter returns the meter of a port-forward. Its metrics are only
+// attributed to the cluster and namespace, as port-forward IDs are unbounded;
+// the traffic of one port-forward is returned by GetPortForwardByID.
+func newTrafficMeter(metrics *telemetry.Metrics, pfDetails *portForward) *trafficMeter {
+	m := &trafficMeter{
+		metrics: metrics,
+		attrs: metric.WithAttributes(
+			attribute.String("cluster", pfDetails.Cluster),
+			attribute.String("namespace", pfDetails.Namespace),
+		),
+	}
+	m.touch()
+
+	return m
+}
+
+func (m *trafficMeter) touch() {
+	m.lastActivity.Store(time.Now().UnixNano())
+}
+
+func (m *trafficMeter) connectionOpened() {
+	m.activeConnections.Add(1)
+	m.touch()
+
+	if m.metrics != nil {
+		m.metrics.PortForwardConnections.Add(context.Background(), 1, m.attrs)
+		m.metrics.PortForwardActiveConnections.Add(context.Background(), 1, m.attrs)
+	}
+}
+
+func (m *trafficMeter) connectionClosed() {
+	m.activeConnections.Add(-1)
+	m.touch()
+
+	if m.metrics != nil {
+		m.metrics.PortForwardActiveConnections.Add(context.Background(), -1, m.attrs)
+	}
+}
+
+// transferred records n bytes received from the pod (in) or sent to it (out).
+func (m *trafficMeter) transferred(n int, in bool) {
+	if n <= 0 {
+		return
+	}
+
+	direction := "out"
+	counter := &m.bytesOut
+
+	if in {
+		direction = "in"
+		counter = &m.bytesIn
+	}
+
+	counter.Add(int64(n))
+	m.touch()
+
+	if m.metrics != nil {
+		m.metrics.PortForwardBytes.Add(context.Background(), int64(n), m.attrs,
+			metric.WithAttributes(attribute.String("direction", direction)))
+	}
+}
+
+// stats returns the traffic so far. A nil meter, e.g. of a port-forward that
+// was never started, has had no traffic.
+func (m *trafficMeter) stats() trafficStats {
+	if m == nil {
+		return trafficStats{}
+	}
+
+	return trafficStats{
+		ActiveConnections: m.activeConnections.Load(),
+		BytesIn:           m.bytesIn.Load(),
+		BytesOut:          m.bytesOut.Load(),
+		LastActivity:      time.Unix(0, m.lastActivity.Load()),
+	}
+}
+
+// idleFor returns how long the port-forward has had no connection and no
+// traffic, or 0 while a connection is open.
+func (m *trafficMeter) idleFor(now time.Time) time.Duration {
+	if m.activeConnections.Load() > 0 {
+		return 0
+	}
+
+	return now.Sub(time.Unix(0, m.lastActivity.Load()))
+}
+
+// meteredDialer dials connections whose data streams are counted by a meter.
+// Each connection forwarded to the local port gets its own data stream.
+type meteredDialer struct {
+	httpstream.Dialer
+	meter *trafficMeter
+}
+
+func (d *meteredDialer) Dial(protocols ...string) (httpstream.Connection, string, error) {
+	conn, protocol, err := d.Dialer.Dial(protocols...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &meteredConnection{Connection: conn, meter: d.meter}, protocol, nil
+}
+
+type meteredConnection struct {
+	httpstream.Connection
+	meter *trafficMeter
+}
+
+func (c *meteredConnection) CreateStream(headers http.Header) (httpstream.Stream, error) {
+	stream, err := c.Connection.CreateStream(headers)
+	if err != nil || headers.Get(corev1.StreamType) != corev1.StreamTypeData {
+		return stream, err
+	}
+
+	c.meter.connectionOpened()
+
+	return &meteredStream{Stream: stream, meter: c.meter}, nil
+}
+
+// RemoveStreams removes the streams from the connection, which ends the
+// forwarded connections of the data streams.
+func (c *meteredConnection) RemoveStreams(streams ...httpstream.Stream) {
+	unwrapped := make([]httpstream.Stream, 0, len(streams))
+
+	for _, stream := range streams {
+		if s, ok := stream.(*meteredStream); ok {
+			s.remove()
+			stream = s.Stream
+		}
+
+		unwrapped = append(unwrapped, stream)
+	}
+
+	c.Connection.RemoveStreams(unwrapped...)
+}
+
+type meteredStream struct {
+	httpstream.Stream
+	meter   *trafficMeter
+	removed atomic.Bool
+}
+
+func (s *meteredStream) Read(p []byte) (int, error) {
+	n, err := s.Stream.Read(p)
+	s.meter.transferred(n, true)
+
+	return n, err
+}
+
+func (s *meteredStream) Write(p []byte) (int, error) {
+	n, err := s.Stream.Write(p)
+	s.meter.transferred(n, false)
+
+	return n, err
+}
+
+func (s *meteredStream) remove() {
+	if s.removed.CompareAndSwap(false, true) {
+		s.meter.connectionClosed()
+	}
+}
+
+// watchIdleTimeout runs in a goroutine while a port-forward with an idle
+// timeout is running, and stops it once it has had no connection and no
+// traffic for that long.
+func watchIdleTimeout(cache cache.Cache[interface{}], pfDetails *portForward) {
+	timer := time.NewTimer(pfDetails.idleTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case now := <-timer.C:
+			idle := pfDetails.traffic.idleFor(now)
+			if idle < pfDetails.idleTimeout {
+				timer.Reset(pfDetails.idleTimeout - idle)
+				continue
+			}
+
+			stopIdlePortForward(cache, pfDetails)
+
+			return
+		case <-pfDetails.closeChan:
+			return
+		}
+	}
+}
+
+// stopIdlePortForward stops a port-forward that reached its idle timeout.
+func stopIdlePortForward(cache cache.Cache[interface{}], pfDetails *portForward) {
+	errMsg := fmt.Sprintf("stopped after being idle for %s", pfDetails.idleTimeout)
+	logger.Log(logger.LevelInfo, map[string]string{"id": pfDetails.ID, "port": pfDetails.Port}, nil, errMsg)
+
+	portforwardstore(cache, pfDetails.setStatusAndSnapshot(STOPPED, errMsg))
+	safeCloseChan(pfDetails.closeChan)
+
+	if pfDetails.traffic.metrics != nil {
+		pfDetails.traffic.metrics.PortForwardIdleStops.Add(context.Background(), 1, pfDetails.traffic.attrs)
+	}
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream" //nolint:staticcheck // The dialer of client-go/tools/portforward.
+)
+
+type fakeStream struct {
+	httpstream.Stream
+	buf bytes.Buffer
+}
+
+func (s *fakeStream) Read(p []byte) (int, error)  { return s.buf.Read(p) }
+func (s *fakeStream) Write(p []byte) (int, error) { return s.buf.Write(p) }
+
+type fakeConnection struct {
+	httpstream.Connection
+	mu      sync.Mutex
+	removed []httpstream.Stream
+}
+
+func (c *fakeConnection) CreateStream(http.Header) (httpstream.Stream, error) {
+	return &fakeStream{}, nil
+}
+
+func (c *fakeConnection) RemoveStreams(streams ...httpstream.Stream) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removed = append(c.removed, streams...)
+}
+
+func createTestStream(t *testing.T, conn httpstream.Connection, streamType string) httpstream.Stream {
+	t.Helper()
+
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, streamType)
+
+	stream, err := conn.CreateStream(headers)
+	require.NoError(t, err)
+
+	return stream
+}
+
+func TestMeteredConnection(t *testing.T) {
+	meter := newTrafficMeter(nil, &portForward{ID: "id"})
+	fake := &fakeConnection{}
+	conn := &meteredConnection{Connection: fake, meter: meter}
+
+	errorStream := createTestStream(t, conn, corev1.StreamTypeError)
+	dataStream := createTestStream(t, conn, corev1.StreamTypeData)
+
+	assert.Equal(t, int64(1), meter.stats().ActiveConnections)
+
+	_, err := dataStream.Write([]byte("request"))
+	require.NoError(t, err)
+
+	_, err = dataStream.Read(make([]byte, 4))
+	require.NoError(t, err)
+
+	// The client removes both streams once the connection is done.
+	conn.RemoveStreams(errorStream)
+	conn.RemoveStreams(dataStream)
+	conn.RemoveStreams(dataStream)
+
+	stats := meter.stats()
+	assert.Equal(t, int64(0), stats.ActiveConnections)
+	assert.Equal(t, int64(4), stats.BytesIn)
+	assert.Equal(t, int64(7), stats.BytesOut)
+
+	// The connection gets its own streams back.
+	require.Len(t, fake.removed, 3)
+	assert.IsType(t, &fakeStream{}, fake.removed[1])
+}
+
+func TestTrafficMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	originalProvider := otel.GetMeterProvider()
+
+	otel.SetMeterProvider(provider)
+	t.Cleanup(func() { otel.SetMeterProvider(originalProvider) })
+
+	metrics, err := telemetry.NewMetrics()
+	require.NoError(t, err)
+
+	meter := newTrafficMeter(metrics, &portForward{ID: "id", Cluster: "cluster"})
+	meter.connectionOpened()
+	meter.transferred(10, true)
+	meter.transferred(3, false)
+
+	var data metricdata.ResourceMetrics
+
+	require.NoError(t, reader.Collect(context.Background(), &data))
+
+	sums := map[string]int64{}
+
+	for _, scopeMetrics := range data.ScopeMetrics {
+		for _, m := range scopeMetrics.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok {
+				for _, point := range sum.DataPoints {
+					sums[m.Name] += point.Value
+
+					_, hasID := point.Attributes.Value("portforward.id")
+					assert.False(t, hasID, "%s is attributed to a port forward ID", m.Name)
+				}
+			}
+		}
+	}
+
+	assert.Equal(t, int64(1), sums["headlamp.portforward.connections"])
+	assert.Equal(t, int64(1), sums["headlamp.portforward.active_connections"])
+	assert.Equal(t, int64(13), sums["headlamp.portforward.bytes"])
+}
+
+func newIdleTestPortForward(idleTimeout time.Duration) *portForward {
+	pfDetails := &portForward{
+		mu:          &sync.Mutex{},
+		ID:          "idle",
+		Cluster:     "cluster",
+		cacheKey:    "cluster",
+		Status:      RUNNING,
+		closeChan:   make(chan struct{}),
+		idleTimeout: idleTimeout,
+	}
+	pfDetails.traffic = newTrafficMeter(nil, pfDetails)
+
+	return pfDetails
+}
+
+func TestWatchIdleTimeout(t *testing.T) {
+	c := cache.New[interface{}]()
+	pfDetails := newIdleTestPortForward(50 * time.Millisecond)
+
+	done := make(chan struct{})
+
+	go func() {
+		watchIdleTimeout(c, pfDetails)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle port forward wasn't stopped")
+	}
+
+	assert.True(t, pfDetails.closed())
+
+	stored, err := getPortForwardByID(c, "cluster", "idle")
+	require.NoError(t, err)
+	assert.Equal(t, STOPPED, stored.Status)
+	assert.Contains(t, stored.Error, "idle")
+}
+
+func TestWatchIdleTimeoutKeepsActivePortForward(t *testing.T) {
+	c := cache.New[interface{}]()
+	pfDetails := newIdleTestPortForward(20 * time.Millisecond)
+	pfDetails.traffic.connectionOpened()
+
+	go watchIdleTimeout(c, pfDetails)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, pfDetails.closed())
+
+	safeCloseChan(pfDetails.closeChan)
+}
+
+func TestPortForwardRequestIdleTimeout(t *testing.T) {
+	p := portForwardRequest{Namespace: "default", Pod: "web", TargetPort: "80", IdleTimeout: "30m"}
+	require.NoError(t, p.Validate())
+
+	timeout, err := p.idleTimeout()
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, timeout)
+
+	for _, invalid := range []string{"30", "-1m", "0s"} {
+		p.IdleTimeout = invalid
+		assert.ErrorContains(t, p.Validate(), "idleTimeout", invalid)
+	}
+}
+
+func TestGetPortForwardByIDTraffic(t *testing.T) {
+	c := cache.New[interface{}]()
+	pfDetails := newIdleTestPortForward(time.Hour)
+	pfDetails.IdleTimeout = "1h"
+	pfDetails.traffic.connectionOpened()
+	pfDetails.traffic.transferred(5, true)
+	portforwardstore(c, *pfDetails)
+
+	r := httptest.NewRequest(http.MethodGet, "/clusters/cluster/portforward?id=idle", nil)
+	r = mux.SetURLVars(r, map[string]string{"clusterName": "cluster"})
+	w := httptest.NewRecorder()
+
+	GetPortForwardByID(c, "cluster", w, r)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var got map[string]interface{}
+
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, "1h", got["idleTimeout"])
+	assert.InDelta(t, 1, got["activeConnections"], 0)
+	assert.InDelta(t, 5, got["bytesIn"], 0)
+	assert.InDelta(t, 0, got["bytesOut"], 0)
+	assert.NotEmpty(t, got["lastActivity"])
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	kindDeployment  = "Deployment"
+	kindStatefulSet = "StatefulSet"
+	kindDaemonSet   = "DaemonSet"
+	kindReplicaSet  = "ReplicaSet"
+
+	// deploymentRevisionAnnotation is the revision the deployment controller
+	// sets on the ReplicaSets of a Deployment.
+	deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+)
+
+// workloadKind returns the kind of workload a port-forward can target, in
+// any case, e.g. "deployment" for "Deployment".
+func workloadKind(kind string) (string, bool) {
+	for _, k := range []string{kindDeployment, kindStatefulSet, kindDaemonSet, kindReplicaSet} {
+		if strings.EqualFold(kind, k) {
+			return k, true
+		}
+	}
+
+	return "", false
+}
+
+// newWorkloadReconnector returns the reconnector of a port-forward to a
+// workload or to a label selector, which moves to another ready pod of them.
+func newWorkloadReconnector(
+	clientset kubernetes.Interface,
+	rConf *rest.Config,
+	pfDetails *portForward,
+) *podReconnector {
+	return newPodReconnector(clientset, rConf, pfDetails, func(ctx context.Context) (string, error) {
+		if pfDetails.Selector != "" {
+			return resolveSelectorPod(ctx, clientset, pfDetails.Namespace, pfDetails.Selector)
+		}
+
+		return resolveWorkloadPod(ctx, clientset, pfDetails.Namespace, pfDetails.WorkloadKind, pfDetails.WorkloadName)
+	})
+}
+
+// resolveSelectorPod returns a ready pod matching a label selector.
+func resolveSelectorPod(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	namespace, selector string,
+) (string, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, v1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return "", err
+	}
+
+	pod := readyPod(pods.Items)
+	if pod == "" {
+		return "", fmt.Errorf("no ready pod in %s matches %s", namespace, selector)
+	}
+
+	return pod, nil
+}
+
+// resolveWorkloadPod returns a ready pod of a workload. The pods of a
+// Deployment's newest ReplicaSet are preferred, like kubectl port-forward does.
+func resolveWorkloadPod(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	namespace, kind, name string,
+) (string, error) {
+	var (
+		uid      types.UID
+		selector *v1.LabelSelector
+		err      error
+	)
+
+	apps := clientset.AppsV1()
+
+	switch kind {
+	case kindDeployment:
+		return resolveDeploymentPod(ctx, clientset, namespace, name)
+	case kindStatefulSet:
+		var sts *appsv1.StatefulSet
+
+		if sts, err = apps.StatefulSets(namespace).Get(ctx, name, v1.GetOptions{}); err == nil {
+			uid, selector = sts.UID, sts.Spec.Selector
+		}
+	case kindDaemonSet:
+		var ds *appsv1.DaemonSet
+
+		if ds, err = apps.DaemonSets(namespace).Get(ctx, name, v1.GetOptions{}); err == nil {
+			uid, selector = ds.UID, ds.Spec.Selector
+		}
+	case kindReplicaSet:
+		var rs *appsv1.ReplicaSet
+
+		if rs, err = apps.ReplicaSets(namespace).Get(ctx, name, v1.GetOptions{}); err == nil {
+			uid, selector = rs.UID, rs.Spec.Selector
+		}
+	default:
+		return "", fmt.Errorf("unsupported workload kind %q", kind)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	pods, err := listSelectedPods(ctx, clientset, namespace, selector)
+	if err != nil {
+		return "", err
+	}
+
+	pod := readyPod(slices.DeleteFunc(pods, func(p corev1.Pod) bool { return !isControlledBy(&p, uid) }))
+	if pod == "" {
+		return "", fmt.Errorf("%s %s/%s has no ready pod", kind, namespace, name)
+	}
+
+	return pod, nil
+}
+
+// resolveDeploymentPod returns a ready pod of the newest ReplicaSet of a
+// Deployment that has one, e.g. of the previous ReplicaSet during a rollout
+// whose new pods aren't ready yet.
+func resolveDeploymentPod(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (string, error) {
+	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, v1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	selector, err := v1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return "", err
+	}
+
+	replicaSets, err := clientset.AppsV1().ReplicaSets(namespace).List(ctx, v1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	owned := slices.DeleteFunc(replicaSets.Items, func(rs appsv1.ReplicaSet) bool {
+		return !isControlledBy(&rs, deployment.UID)
+	})
+	slices.SortFunc(owned, compareReplicaSetsNewestFirst)
+
+	pods, err := listSelectedPods(ctx, clientset, namespace, deployment.Spec.Selector)
+	if err != nil {
+		return "", err
+	}
+
+	for _, rs := range owned {
+		podsOfReplicaSet := slices.DeleteFunc(slices.Clone(pods), func(p corev1.Pod) bool {
+			return !isControlledBy(&p, rs.UID)
+		})
+
+		if pod := readyPod(podsOfReplicaSet); pod != "" {
+			return pod, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s %s/%s has no ready pod", kindDeployment, namespace, name)
+}
+
+// compareReplicaSetsNewestFirst orders ReplicaSets by decreasing revision,
+// then creation time.
+func compareReplicaSetsNewestFirst(a, b appsv1.ReplicaSet) int {
+	revision := func(rs appsv1.ReplicaSet) int64 {
+		r, _ := strconv.ParseInt(rs.Annotations[deploymentRevisionAnnotation], 10, 64)
+		return r
+	}
+
+	if c := cmp.Compare(revision(b), revision(a)); c != 0 {
+		return c
+	}
+
+	return b.CreationTimestamp.Compare(a.CreationTimestamp.Time)
+}
+
+func listSelectedPods(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	namespace string,
+	labelSelector *v1.LabelSelector,
+) ([]corev1.Pod, error) {
+	selector, err := v1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	if selector.Empty() {
+		return nil, fmt.Errorf("workload has an empty selector")
+	}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, v1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	return pods.Items, nil
+}
+
+// isControlledBy returns whether the controller of obj has the UID uid.
+func isControlledBy(obj v1.Object, uid types.UID) bool {
+	controller := v1.GetControllerOf(obj)
+
+	return controller != nil && controller.UID == uid
+}
+
+// validateSelector checks that a label selector parses and selects something.
+func validateSelector(selector string) error {
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return fmt.Errorf("invalid selector: %w", err)
+	}
+
+	if parsed.Empty() {
+		return fmt.Errorf("selector must not be empty")
+	}
+
+	return nil
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var testSelector = &v1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+func controlledBy(kind, name string, uid types.UID) []v1.OwnerReference {
+	controller := true
+
+	return []v1.OwnerReference{{Kind: kind, Name: name, UID: uid, Controller: &controller}}
+}
+
+func newOwnedTestPod(name string, ready bool, ownerKind, owner string, ownerUID types.UID) *corev1.Pod {
+	pod := newTestPod(name, ready)
+	pod.OwnerReferences = controlledBy(ownerKind, owner, ownerUID)
+
+	return pod
+}
+
+func newTestReplicaSet(name, revision string, uid types.UID, deploymentUID types.UID) *appsv1.ReplicaSet {
+	return &appsv1.ReplicaSet{
+		ObjectMeta: v1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			UID:             uid,
+			Labels:          map[string]string{"app": "web"},
+			Annotations:     map[string]string{deploymentRevisionAnnotation: revision},
+			OwnerReferences: controlledBy(kindDeployment, "web", deploymentUID),
+		},
+		Spec: appsv1.ReplicaSetSpec{Selector: testSelector},
+	}
+}
+
+func newTestDeploymentObjects(newPodsReady bool) []runtime.Object {
+	return []runtime.Object{
+		&appsv1.Deployment{
+			ObjectMeta: v1.ObjectMeta{Name: "web", Namespace: "default", UID: "deploy"},
+			Spec:       appsv1.DeploymentSpec{Selector: testSelector},
+		},
+		newTestReplicaSet("web-old", "1", "rs-old", "deploy"),
+		newTestReplicaSet("web-new", "2", "rs-new", "deploy"),
+		// A ReplicaSet with the same labels but another owner isn't the deployment's.
+		newTestReplicaSet("other", "9", "rs-other", "other"),
+		newOwnedTestPod("web-old-a", true, kindReplicaSet, "web-old", "rs-old"),
+		newOwnedTestPod("web-new-b", newPodsReady, kindReplicaSet, "web-new", "rs-new"),
+		newOwnedTestPod("web-new-c", newPodsReady, kindReplicaSet, "web-new", "rs-new"),
+		newOwnedTestPod("other-a", true, kindReplicaSet, "other", "rs-other"),
+	}
+}
+
+func TestResolveWorkloadPod(t *testing.T) {
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		kind    string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "deployment_newest_replicaset",
+			objects: newTestDeploymentObjects(true),
+			kind:    kindDeployment,
+			want:    "web-new-b",
+		},
+		{
+			name:    "deployment_rollout",
+			objects: newTestDeploymentObjects(false),
+			kind:    kindDeployment,
+			want:    "web-old-a",
+		},
+		{
+			name: "statefulset",
+			objects: []runtime.Object{
+				&appsv1.StatefulSet{
+					ObjectMeta: v1.ObjectMeta{Name: "web", Namespace: "default", UID: "sts"},
+					Spec:       appsv1.StatefulSetSpec{Selector: testSelector},
+				},
+				newOwnedTestPod("web-0", false, kindStatefulSet, "web", "sts"),
+				newOwnedTestPod("web-1", true, kindStatefulSet, "web", "sts"),
+				// A ready pod with the same labels but no owner isn't the statefulset's.
+				newTestPod("a-stray", true),
+			},
+			kind: kindStatefulSet,
+			want: "web-1",
+		},
+		{
+			name: "daemonset_no_ready_pod",
+			objects: []runtime.Object{
+				&appsv1.DaemonSet{
+					ObjectMeta: v1.ObjectMeta{Name: "web", Namespace: "default", UID: "ds"},
+					Spec:       appsv1.DaemonSetSpec{Selector: testSelector},
+				},
+				newOwnedTestPod("web-x", false, kindDaemonSet, "web", "ds"),
+			},
+			kind:    kindDaemonSet,
+			wantErr: "DaemonSet default/web has no ready pod",
+		},
+		{
+			name:    "missing_workload",
+			kind:    kindReplicaSet,
+			wantErr: "not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(tt.objects...)
+
+			pod, err := resolveWorkloadPod(context.Background(), clientset, "default", tt.kind, "web")
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, pod)
+		})
+	}
+}
+
+func TestResolveSelectorPod(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newTestPod("web-a", false), newTestPod("web-b", true))
+
+	pod, err := resolveSelectorPod(context.Background(), clientset, "default", "app=web")
+	require.NoError(t, err)
+	assert.Equal(t, "web-b", pod)
+
+	_, err = resolveSelectorPod(context.Background(), clientset, "default", "app=api")
+	assert.ErrorContains(t, err, "no ready pod in default matches app=api")
+}
+
+func TestPortForwardRequestValidateWorkload(t *testing.T) {
+	tests := []struct {
+		name    string
+		request portForwardRequest
+		wantErr string
+	}{
+		{name: "workload", request: portForwardRequest{WorkloadKind: "deployment", WorkloadName: "web"}},
+		{name: "selector", request: portForwardRequest{Selector: "app=web,tier in (frontend)"}},
+		{
+			name:    "unknown kind",
+			request: portForwardRequest{WorkloadKind: "Job", WorkloadName: "web"},
+			wantErr: "workloadKind must be",
+		},
+		{name: "no name", request: portForwardRequest{WorkloadKind: "StatefulSet"}, wantErr: "workloadName is required"},
+		{name: "invalid selector", request: portForwardRequest{Selector: "app in (web"}, wantErr: "invalid selector"},
+		{
+			name:    "service and workload",
+			request: portForwardRequest{Service: "web", WorkloadKind: "Deployment", WorkloadName: "web"},
+			wantErr: "only one of service, workload and selector",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.request.Namespace = "default"
+			tt.request.TargetPort = "80"
+
+			err := tt.request.Validate()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+
+	req := portForwardRequest{Namespace: "default", TargetPort: "80", WorkloadKind: "daemonset", WorkloadName: "web"}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, kindDaemonSet, req.WorkloadKind)
+}
+
+func TestStartReconnectingPortForwardRepicksPod(t *testing.T) {
+	c := cache.New[interface{}]()
+
+	pfDetails := &portForward{
+		mu:           &sync.Mutex{},
+		ID:           "id",
+		closeChan:    make(chan struct{}),
+		Pod:          "web-gone",
+		WorkloadKind: kindDeployment,
+		WorkloadName: "web",
+		Namespace:    "default",
+		cacheKey:     "cluster",
+		Status:       RUNNING,
+	}
+
+	reconnector := &podReconnector{
+		resolvePod: func(ctx context.Context) (string, error) { return "web-new-b", nil },
+		connect: func(pod string) (*podConnection, error) {
+			if pod == "web-gone" {
+				return nil, errors.New(`pods "web-gone" not found`)
+			}
+
+			return newTestConnection(), nil
+		},
+		checkPod: func(pod string) error { return nil },
+		backoff:  reconnectInitialBackoff,
+	}
+
+	require.NoError(t, startReconnectingPortForward(c, pfDetails, reconnector))
+
+	t.Cleanup(func() { safeCloseChan(pfDetails.closeChan) })
+
+	stored, err := getPortForwardByID(c, "cluster", "id")
+	require.NoError(t, err)
+	assert.Equal(t, RUNNING, stored.Status)
+	assert.Equal(t, "web-new-b", stored.Pod)
+	assert.Equal(t, "deployment web", stored.target())
+}
@@ -31,6 +31,14 @@ type Metrics struct {
 	ErrorCounter metric.Int64Counter
 	// KubeconfigRefreshCounter tracks the number of kubeconfig refresh operations
 	KubeconfigRefreshCounter metric.Int64Counter
+	// PortForwardConnections counts the connections made through port forwards
+	PortForwardConnections metric.Int64Counter
+	// PortForwardActiveConnections tracks the number of open port forward connections
+	PortForwardActiveConnections metric.Int64UpDownCounter
+	// PortForwardBytes counts the bytes sent through port forwards, by direction
+	PortForwardBytes metric.Int64Counter
+	// PortForwardIdleStops counts the port forwards stopped by their idle timeout
+	PortForwardIdleStops metric.Int64Counter
 }
 
 // NewMetrics creates and registers a set of common application metrics.
@@ -50,6 +58,10 @@ func NewMetrics() (*Metrics, error) {
 		return nil, err
 	}
 
+	if err := initPortForwardMetrics(meter, metrics); err != nil {
+		return nil, err
+	}
+
 	return metrics, nil
 }
 
@@ -124,6 +136,46 @@ func initApplicationMetrics(meter metric.Meter, metrics *Metrics) error {
 	return nil
 }
 
+// initPortForwardMetrics initializes port forward traffic metrics.
+func initPortForwardMetrics(meter metric.Meter, metrics *Metrics) error {
+	var err error
+
+	metrics.PortForwardConnections, err = meter.Int64Counter(
+		"headlamp.portforward.connections",
+		metric.WithDescription("Number of connections made through port forwards"),
+	)
+	if err != nil {
+		return err
+	}
+
+	metrics.PortForwardActiveConnections, err = meter.Int64UpDownCounter(
+		"headlamp.portforward.active_connections",
+		metric.WithDescription("Number of open port forward connections"),
+	)
+	if err != nil {
+		return err
+	}
+
+	metrics.PortForwardBytes, err = meter.Int64Counter(
+		"headlamp.portforward.bytes",
+		metric.WithDescription("Bytes sent through port forwards, in from the pod or out to it"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return err
+	}
+
+	metrics.PortForwardIdleStops, err = meter.Int64Counter(
+		"headlamp.portforward.idle_stops",
+		metric.WithDescription("Number of port forwards stopped by their idle timeout"),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // RequestCounterMiddleware creates HTTP middleware that tracks request metrics.
 func (m *Metrics) RequestCounterMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
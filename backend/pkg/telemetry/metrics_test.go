@@ -94,6 +94,10 @@ func TestNewMetrics(t *testing.T) {
 	assert.NotNil(t, metrics.ClusterProxyRequests)
 	assert.NotNil(t, metrics.PluginLoadCount)
 	assert.NotNil(t, metrics.ErrorCounter)
+	assert.NotNil(t, metrics.PortForwardConnections)
+	assert.NotNil(t, metrics.PortForwardActiveConnections)
+	assert.NotNil(t, metrics.PortForwardBytes)
+	assert.NotNil(t, metrics.PortForwardIdleStops)
 
 	ctx := context.Background()
 	metrics.RequestCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("test", "value")))